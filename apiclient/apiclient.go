@@ -0,0 +1,263 @@
+// Package apiclient contains the runtime used by Go clients generated by
+// apiclientgen. Generated clients are thin wrappers that build a Request for
+// each endpoint and send it with Do, which takes care of encoding, decoding,
+// and turning error responses back into apierror types.
+package apiclient
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+// Client holds configuration shared by every request made by a generated
+// client.
+type Client struct {
+	// BaseURL is the URL that request paths are resolved against, like
+	// `https://example.com`.
+	BaseURL string
+
+	// HTTPClient is the HTTP client used to make requests. Defaults to
+	// http.DefaultClient if not set.
+	HTTPClient *http.Client
+}
+
+// Request is a single request to an API endpoint.
+type Request struct {
+	// Body is a value to be marshaled to JSON and sent as the request body.
+	// No body is sent if nil.
+	Body any
+
+	// Cookies are cookies to send with the request.
+	Cookies []*http.Cookie
+
+	// Header are headers to send with the request.
+	Header http.Header
+
+	// Method is the request's HTTP method, like `GET`.
+	Method string
+
+	// Path is the request's path, with any path parameters already
+	// substituted in.
+	Path string
+
+	// Query are query string parameters to send with the request.
+	Query url.Values
+}
+
+// Do sends a request and decodes a successful response into a new TResp. A
+// response with a non-2xx status code is decoded as an API error and returned
+// as the apierror type corresponding to the status code, like a
+// *apierror.NotFound for a 404.
+func Do[TResp any](ctx context.Context, client *Client, req *Request) (*TResp, error) {
+	var body io.Reader
+	if req.Body != nil {
+		data, err := json.Marshal(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("apiclient: error marshaling request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	reqURL := strings.TrimSuffix(client.BaseURL, "/") + req.Path
+	if len(req.Query) > 0 {
+		reqURL += "?" + req.Query.Encode()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, reqURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("apiclient: error building request: %w", err)
+	}
+
+	for name, values := range req.Header {
+		for _, value := range values {
+			httpReq.Header.Add(name, value)
+		}
+	}
+
+	for _, cookie := range req.Cookies {
+		httpReq.AddCookie(cookie)
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := client.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("apiclient: error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("apiclient: error reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, DecodeError(resp.StatusCode, respData)
+	}
+
+	var respVal TResp
+	if len(bytes.TrimSpace(respData)) > 0 {
+		if err := json.Unmarshal(respData, &respVal); err != nil {
+			return nil, fmt.Errorf("apiclient: error unmarshaling response body: %w", err)
+		}
+	}
+
+	return &respVal, nil
+}
+
+// DecodeError decodes the body of an error response into an API error of the
+// type corresponding to its status code. Both the message-only format and RFC
+// 9457 problem details are understood. If the body isn't an API error, the
+// body itself (or the status text if there's no body) is used as the message.
+func DecodeError(statusCode int, data []byte) error {
+	var errorBody struct {
+		Detail  string `json:"detail"`
+		Message string `json:"message"`
+	}
+
+	message := strings.TrimSpace(string(data))
+	isJSON := json.Unmarshal(data, &errorBody) == nil
+	if isJSON {
+		message = cmp.Or(errorBody.Message, errorBody.Detail, message)
+	}
+
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+
+	apiErr := apierror.FromStatusCode(statusCode, message)
+
+	// Fill in other properties like code and field errors, which are named
+	// the same in both formats.
+	if isJSON {
+		_ = json.Unmarshal(data, apiErr)
+	}
+
+	return apiErr
+}
+
+// AddCookie adds a cookie for a value bound with a `cookie` tag, unless the
+// value is zero.
+func AddCookie(cookies []*http.Cookie, name string, value any) []*http.Cookie {
+	if isZero(value) {
+		return cookies
+	}
+
+	return append(cookies, &http.Cookie{Name: name, Value: FormatValue(value)})
+}
+
+// AddHeader adds a header for a value bound with a `header` tag, unless the
+// value is zero. Slice values are added as multiple headers.
+func AddHeader(header http.Header, name string, value any) {
+	for _, formatted := range formatValues(value) {
+		header.Add(name, formatted)
+	}
+}
+
+// AddQuery adds a query parameter for a value bound with a `query` tag, unless
+// the value is zero. Slice values are added as repeated parameters.
+func AddQuery(query url.Values, name string, value any) {
+	for _, formatted := range formatValues(value) {
+		query.Add(name, formatted)
+	}
+}
+
+// PathValue formats a value bound with a `path` tag for inclusion in a request
+// path. Slashes are escaped unless multiSegment is true, which is the case for
+// wildcards like `{path...}` that match multiple segments.
+func PathValue(value any, multiSegment bool) string {
+	escaped := url.PathEscape(FormatValue(value))
+	if multiSegment {
+		escaped = strings.ReplaceAll(escaped, "%2F", "/")
+	}
+
+	return escaped
+}
+
+// FormatValue formats a single value as a string in the format expected by
+// apiendpoint's request binding, which is the inverse of how it's parsed.
+func FormatValue(value any) string {
+	switch value := value.(type) {
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	case time.Duration:
+		return value.String()
+	}
+
+	reflectVal := reflect.ValueOf(value)
+	if reflectVal.Kind() == reflect.Pointer {
+		if reflectVal.IsNil() {
+			return ""
+		}
+		return FormatValue(reflectVal.Elem().Interface())
+	}
+
+	switch reflectVal.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		return strconv.FormatBool(reflectVal.Bool())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(reflectVal.Float(), 'f', -1, reflectVal.Type().Bits())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(reflectVal.Int(), 10)
+	case reflect.String:
+		return reflectVal.String()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(reflectVal.Uint(), 10)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// formatValues formats a value as zero or more strings. Zero values produce no
+// strings, and slices produce one string per element.
+func formatValues(value any) []string {
+	if isZero(value) {
+		return nil
+	}
+
+	reflectVal := reflect.ValueOf(value)
+	if reflectVal.Kind() == reflect.Slice {
+		if _, ok := value.(encoding.TextMarshaler); !ok {
+			formatted := make([]string, reflectVal.Len())
+			for i := range reflectVal.Len() {
+				formatted[i] = FormatValue(reflectVal.Index(i).Interface())
+			}
+			return formatted
+		}
+	}
+
+	return []string{FormatValue(value)}
+}
+
+func isZero(value any) bool {
+	if value == nil {
+		return true
+	}
+
+	return reflect.ValueOf(value).IsZero()
+}
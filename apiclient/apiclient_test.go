@@ -0,0 +1,147 @@
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	type response struct {
+		Message string `json:"message"`
+	}
+
+	setup := func(t *testing.T, handler http.HandlerFunc) *Client {
+		t.Helper()
+
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		return &Client{BaseURL: server.URL + "/", HTTPClient: server.Client()}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		client := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "/api/echo", r.URL.Path)
+			require.Equal(t, "a=1&a=2", r.URL.RawQuery)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.Equal(t, "value", r.Header.Get("X-Test"))
+
+			cookie, err := r.Cookie("session")
+			require.NoError(t, err)
+			require.Equal(t, "sess", cookie.Value)
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"message":"hello"}`))
+		})
+
+		resp, err := Do[response](ctx, client, &Request{
+			Body:    map[string]string{"message": "hello"},
+			Cookies: []*http.Cookie{{Name: "session", Value: "sess"}},
+			Header:  http.Header{"X-Test": []string{"value"}},
+			Method:  http.MethodPost,
+			Path:    "/api/echo",
+			Query:   url.Values{"a": []string{"1", "2"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, &response{Message: "hello"}, resp)
+	})
+
+	t.Run("EmptyResponse", func(t *testing.T) {
+		t.Parallel()
+
+		client := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		resp, err := Do[response](ctx, client, &Request{Method: http.MethodDelete, Path: "/api/thing"})
+		require.NoError(t, err)
+		require.Equal(t, &response{}, resp)
+	})
+
+	t.Run("APIError", func(t *testing.T) {
+		t.Parallel()
+
+		client := setup(t, func(w http.ResponseWriter, r *http.Request) {
+			apierror.NewNotFound("Thing not found.").Write(r.Context(), nil, w)
+		})
+
+		_, err := Do[response](ctx, client, &Request{Method: http.MethodGet, Path: "/api/thing"})
+		require.Equal(t, apierror.NewNotFound("Thing not found."), err)
+	})
+}
+
+func TestDecodeError(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, apierror.NewBadRequest("Bad."), DecodeError(http.StatusBadRequest, []byte(`{"message":"Bad."}`)))
+	require.Equal(t, apierror.WithCode(apierror.NewBadRequest("Bad."), "validation_failed"), DecodeError(http.StatusBadRequest, []byte(`{"code":"validation_failed","message":"Bad."}`)))
+	require.Equal(t, &apierror.BadRequest{APIError: apierror.APIError{
+		Code: "validation_failed",
+		Errors: []*apierror.FieldError{
+			{Field: "name", JSONPath: "items[3].name", Message: "Field `name` is required.", Tag: "required"},
+		},
+		Message:    "Field `name` is required.",
+		StatusCode: http.StatusBadRequest,
+	}}, DecodeError(http.StatusBadRequest, []byte(`{
+		"code": "validation_failed",
+		"errors": [{"field": "name", "json_path": "items[3].name", "message": "Field `+"`name`"+` is required.", "param": "", "tag": "required"}],
+		"message": "Field `+"`name`"+` is required."
+	}`)))
+	require.Equal(t, apierror.NewNotFound("Job not found."), DecodeError(http.StatusNotFound, []byte(`{"detail":"Job not found.","status":404,"title":"Not Found","type":"about:blank"}`)))
+	require.Equal(t, apierror.NewServiceUnavailable("upstream down"), DecodeError(http.StatusServiceUnavailable, []byte("upstream down\n")))
+	require.Equal(t, apierror.NewInternalServerError("Internal Server Error"), DecodeError(http.StatusInternalServerError, nil))
+}
+
+func TestFormatValue(t *testing.T) {
+	t.Parallel()
+
+	type namedString string
+
+	require.Equal(t, "true", FormatValue(true))
+	require.Equal(t, "1.5", FormatValue(1.5))
+	require.Equal(t, "-3", FormatValue(int64(-3)))
+	require.Equal(t, "3", FormatValue(uint8(3)))
+	require.Equal(t, "str", FormatValue(namedString("str")))
+	require.Equal(t, "5s", FormatValue(5*time.Second))
+	require.Equal(t, "2025-01-02T03:04:05Z", FormatValue(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)))
+	require.Equal(t, "7", FormatValue(ptr(7)))
+	require.Empty(t, FormatValue((*int)(nil)))
+}
+
+func TestAddQuery(t *testing.T) {
+	t.Parallel()
+
+	query := url.Values{}
+	AddQuery(query, "zero", 0)
+	AddQuery(query, "nil", (*string)(nil))
+	AddQuery(query, "int", 5)
+	AddQuery(query, "slice", []string{"a", "b"})
+	require.Equal(t, url.Values{"int": []string{"5"}, "slice": []string{"a", "b"}}, query)
+}
+
+func TestPathValue(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "a%20b%2Fc", PathValue("a b/c", false))
+	require.Equal(t, "a%20b/c", PathValue("a b/c", true))
+	require.Equal(t, "123", PathValue(int64(123), false))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
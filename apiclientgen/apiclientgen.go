@@ -0,0 +1,453 @@
+// Package apiclientgen generates typed Go clients for endpoints mounted with
+// apiendpoint.Mount. Endpoints are collected by assigning an
+// apiendpoint.Registry to MountOpts.Registry, then a client is generated from
+// the registry's routes with one method per endpoint. The client gets its own
+// copy of every request and response struct so that consumers don't need to
+// import the server's packages.
+//
+// Generation is meant to be invoked with go:generate through a small program
+// that mounts the API's endpoints:
+//
+//	//go:generate go run ./internal/cmd/generateclient
+//
+//	func main() {
+//		registry := apiendpoint.NewRegistry()
+//		api.MountEndpoints(http.NewServeMux(), &apiendpoint.MountOpts{Registry: registry})
+//
+//		if err := apiclientgen.WriteFile("client/client.go", registry, &apiclientgen.GenerateOpts{
+//			PackageName: "client",
+//		}); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// Generated clients use the apiclient package at runtime, which returns error
+// responses as the apierror type matching their status code.
+package apiclientgen
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/internal/apireflect"
+)
+
+// GenerateOpts are options for Generate.
+type GenerateOpts struct {
+	// ClientName is the name of the generated client struct. Defaults to
+	// "Client". Its constructor is named with a "New" prefix, like NewClient.
+	ClientName string
+
+	// PackageName is the package name of the generated file. Defaults to
+	// "client".
+	PackageName string
+}
+
+// Generate generates the Go source of a client for the routes in the given
+// registry. Routes of endpoints that don't both accept and produce JSON are
+// skipped.
+func Generate(registry *apiendpoint.Registry, opts *GenerateOpts) ([]byte, error) {
+	if opts == nil {
+		opts = &GenerateOpts{}
+	}
+
+	gen := &generator{
+		clientName:  cmp.Or(opts.ClientName, "Client"),
+		imports:     make(map[string]string),
+		methodNames: make(map[string]struct{}),
+		packageName: cmp.Or(opts.PackageName, "client"),
+		typeNames:   make(map[reflect.Type]string),
+		usedNames:   make(map[string]struct{}),
+	}
+
+	for _, route := range registry.Routes() {
+		// Endpoints that only consume something like a form, or only produce
+		// something like an event stream, can't be called like a regular
+		// JSON endpoint.
+		if !route.ConsumesJSON() || !route.ProducesJSON() {
+			continue
+		}
+
+		if err := gen.addRoute(route); err != nil {
+			return nil, err
+		}
+	}
+
+	return gen.source()
+}
+
+// WriteFile generates a client with Generate and writes it to the given path.
+func WriteFile(path string, registry *apiendpoint.Registry, opts *GenerateOpts) error {
+	src, err := Generate(registry, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, src, 0o600); err != nil {
+		return fmt.Errorf("error writing client: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()         //nolint:gochecknoglobals
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]() //nolint:gochecknoglobals
+)
+
+type generator struct {
+	clientName  string
+	imports     map[string]string // import path -> package name
+	methods     strings.Builder
+	methodNames map[string]struct{}
+	packageName string
+	typeDecls   strings.Builder
+	typeNames   map[reflect.Type]string // generated struct types -> names
+	usedNames   map[string]struct{}
+}
+
+func (g *generator) addRoute(route *apiendpoint.Route) error {
+	method, routePath := apireflect.SplitPattern(route.Meta.Pattern)
+	if method == "" {
+		return fmt.Errorf("pattern %q must start with a method to generate a client method", route.Meta.Pattern)
+	}
+
+	methodName := apireflect.UniqueName(g.methodNames, methodName(route))
+
+	reqTypeExpr := g.typeExpr(route.RequestType)
+	respTypeExpr := g.typeExpr(route.ResponseType)
+
+	var (
+		cookies []*apiendpoint.RouteParameter
+		headers []*apiendpoint.RouteParameter
+		queries []*apiendpoint.RouteParameter
+		paths   = make(map[string]*apiendpoint.RouteParameter)
+	)
+	for _, param := range route.Parameters {
+		switch param.In {
+		case "cookie":
+			cookies = append(cookies, param)
+		case "header":
+			headers = append(headers, param)
+		case "path":
+			paths[param.Name] = param
+		case "query":
+			queries = append(queries, param)
+		}
+	}
+
+	pathExpr, err := pathExpr(routePath, paths)
+	if err != nil {
+		return fmt.Errorf("error generating path for %q: %w", route.Meta.Pattern, err)
+	}
+
+	m := &g.methods
+	fmt.Fprintf(m, "\n// %s invokes `%s`.\n", methodName, route.Meta.Pattern)
+	fmt.Fprintf(m, "func (c *%s) %s(ctx context.Context, req *%s) (*%s, error) {\n", g.clientName, methodName, reqTypeExpr, respTypeExpr)
+
+	if len(queries) > 0 {
+		g.imports["net/url"] = "url"
+		m.WriteString("query := url.Values{}\n")
+		for _, param := range queries {
+			fmt.Fprintf(m, "apiclient.AddQuery(query, %q, req.%s)\n", param.Name, param.Field.Name)
+		}
+		m.WriteString("\n")
+	}
+
+	if len(headers) > 0 {
+		m.WriteString("header := http.Header{}\n")
+		for _, param := range headers {
+			fmt.Fprintf(m, "apiclient.AddHeader(header, %q, req.%s)\n", param.Name, param.Field.Name)
+		}
+		m.WriteString("\n")
+	}
+
+	if len(cookies) > 0 {
+		m.WriteString("var cookies []*http.Cookie\n")
+		for _, param := range cookies {
+			fmt.Fprintf(m, "cookies = apiclient.AddCookie(cookies, %q, req.%s)\n", param.Name, param.Field.Name)
+		}
+		m.WriteString("\n")
+	}
+
+	fmt.Fprintf(m, "return apiclient.Do[%s](ctx, c.client, &apiclient.Request{\n", respTypeExpr)
+
+	// Mirrors apiendpoint, which only reads request bodies for methods other
+	// than GET.
+	if method != http.MethodGet && route.RequestType.Kind() == reflect.Struct && len(apireflect.JSONFields(route.RequestType)) > 0 {
+		m.WriteString("Body: req,\n")
+	}
+	if len(cookies) > 0 {
+		m.WriteString("Cookies: cookies,\n")
+	}
+	if len(headers) > 0 {
+		m.WriteString("Header: header,\n")
+	}
+	fmt.Fprintf(m, "Method: %q,\n", method)
+	fmt.Fprintf(m, "Path: %s,\n", pathExpr)
+	if len(queries) > 0 {
+		m.WriteString("Query: query,\n")
+	}
+	m.WriteString("})\n}\n")
+
+	return nil
+}
+
+// pathExpr produces a Go expression that builds a request path from a pattern
+// path like `/jobs/{id}` by substituting in path parameters from the request.
+func pathExpr(routePath string, params map[string]*apiendpoint.RouteParameter) (string, error) {
+	var parts []string
+	for _, segment := range apireflect.PathSegments(routePath) {
+		if segment.Wildcard == "" {
+			parts = append(parts, strconv.Quote(segment.Literal))
+			continue
+		}
+
+		param, ok := params[segment.Wildcard]
+		if !ok {
+			return "", fmt.Errorf("wildcard {%s} isn't bound to a request struct field with a `path` tag", segment.Wildcard)
+		}
+
+		parts = append(parts, fmt.Sprintf("apiclient.PathValue(req.%s, %t)", param.Field.Name, segment.MultiSegment))
+	}
+
+	if len(parts) < 1 {
+		parts = append(parts, `""`)
+	}
+
+	return strings.Join(parts, " + "), nil
+}
+
+// typeExpr returns a Go type expression for the given type in the generated
+// client, generating a copy of any struct types it references along the way.
+func (g *generator) typeExpr(typ reflect.Type) string {
+	if valueType, ok := apireflect.ExplicitNullableValueType(typ); ok {
+		g.imports["github.com/riverqueue/apiframe/apitype"] = "apitype"
+		return "apitype.ExplicitNullable[" + g.typeExpr(valueType) + "]"
+	}
+
+	// Named types that aren't structs and don't customize their marshaling
+	// are rendered as their underlying type, which is what they look like in
+	// JSON anyway, and which avoids depending on the server's packages.
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		// Standard library types like time.Duration are always safe to
+		// reference, and their exact type often matters for how they're
+		// formatted in parameters.
+		if isStandardLibrary(typ.PkgPath()) {
+			if qualified, ok := g.qualifiedName(typ); ok {
+				return qualified
+			}
+		}
+
+		if typ.Implements(jsonMarshalerType) || reflect.PointerTo(typ).Implements(jsonMarshalerType) ||
+			typ.Implements(textMarshalerType) || reflect.PointerTo(typ).Implements(textMarshalerType) {
+			if qualified, ok := g.qualifiedName(typ); ok {
+				return qualified
+			}
+		}
+
+		if typ.Kind() == reflect.Struct {
+			return g.structName(typ)
+		}
+	}
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", typ.Len(), g.typeExpr(typ.Elem()))
+	case reflect.Interface:
+		return "any"
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", g.typeExpr(typ.Key()), g.typeExpr(typ.Elem()))
+	case reflect.Pointer:
+		return "*" + g.typeExpr(typ.Elem())
+	case reflect.Slice:
+		return "[]" + g.typeExpr(typ.Elem())
+	case reflect.Struct:
+		return g.structBody(typ, nil)
+	}
+
+	// Basic kinds, like a named string type becoming `string`.
+	return typ.Kind().String()
+}
+
+// qualifiedName returns a package-qualified reference to a named type, like
+// `time.Time`, adding an import for its package. Returns false if the type
+// can't be referenced from outside its package.
+func (g *generator) qualifiedName(typ reflect.Type) (string, bool) {
+	pkgPath := typ.PkgPath()
+
+	if !token.IsExported(typ.Name()) || strings.Contains(typ.Name(), "[") || pkgPath == "main" || strings.Contains(pkgPath, "/internal/") || strings.HasSuffix(pkgPath, "/internal") {
+		return "", false
+	}
+
+	// The package's actual name (which isn't always the last element of its
+	// path) is available as the prefix of the type's string representation.
+	pkgName, _, _ := strings.Cut(typ.String(), ".")
+
+	if existingName, ok := g.imports[pkgPath]; ok {
+		pkgName = existingName
+	} else {
+		// Alias the import if another package already uses the same name.
+		alias := pkgName
+		for i := 2; slices.Contains(slices.Collect(maps.Values(g.imports)), alias); i++ {
+			alias = pkgName + strconv.Itoa(i)
+		}
+
+		pkgName = alias
+		g.imports[pkgPath] = pkgName
+	}
+
+	return pkgName + "." + typ.Name(), true
+}
+
+// structName returns the name of the generated copy of a named struct type,
+// generating it if it hasn't been already.
+func (g *generator) structName(typ reflect.Type) string {
+	if name, ok := g.typeNames[typ]; ok {
+		return name
+	}
+
+	name := apireflect.UniqueName(g.usedNames, apireflect.ExportedTypeName(typ))
+
+	// Register the name before generating the struct so that recursive types
+	// can reference themselves.
+	g.typeNames[typ] = name
+
+	body := g.structBody(typ, boundFields(typ))
+	fmt.Fprintf(&g.typeDecls, "\ntype %s %s\n", name, body)
+
+	return name
+}
+
+// structBody generates the body of a struct type containing the JSON fields of
+// typ, and any fields bound from other parts of a request.
+func (g *generator) structBody(typ reflect.Type, bound []reflect.StructField) string {
+	var sb strings.Builder
+	sb.WriteString("struct {\n")
+
+	for _, field := range bound {
+		for _, tag := range apireflect.BindingTags {
+			if name := field.Tag.Get(tag); name != "" && name != "-" {
+				fmt.Fprintf(&sb, "%s %s `%s:%q json:\"-\"`\n", field.Name, g.typeExpr(field.Type), tag, name)
+				break
+			}
+		}
+	}
+
+	for _, field := range apireflect.JSONFields(typ) {
+		jsonTag := field.Name
+		if field.OmitEmpty {
+			jsonTag += ",omitempty"
+		}
+
+		// Unset ExplicitNullable fields must be omitted entirely so that
+		// they're distinguishable from an explicit null.
+		if _, ok := apireflect.ExplicitNullableValueType(field.StructField.Type); ok {
+			jsonTag = field.Name + ",omitzero"
+		}
+
+		fmt.Fprintf(&sb, "%s %s `json:%q`\n", field.StructField.Name, g.typeExpr(field.StructField.Type), jsonTag)
+	}
+
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (g *generator) source() ([]byte, error) {
+	var sb strings.Builder
+
+	sb.WriteString("// Code generated by apiclientgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&sb, "package %s\n\n", g.packageName)
+
+	imports := map[string]string{
+		"context":  "context",
+		"net/http": "http",
+		"github.com/riverqueue/apiframe/apiclient": "apiclient",
+	}
+	for pkgPath, pkgName := range g.imports {
+		imports[pkgPath] = pkgName
+	}
+
+	// Standard library imports go in their own group ahead of others.
+	sb.WriteString("import (\n")
+	for _, standardLibrary := range []bool{true, false} {
+		for _, pkgPath := range slices.Sorted(maps.Keys(imports)) {
+			if isStandardLibrary(pkgPath) != standardLibrary {
+				continue
+			}
+
+			if pkgName := imports[pkgPath]; pkgName != path.Base(pkgPath) {
+				fmt.Fprintf(&sb, "%s %q\n", pkgName, pkgPath)
+			} else {
+				fmt.Fprintf(&sb, "%q\n", pkgPath)
+			}
+		}
+
+		if standardLibrary {
+			sb.WriteString("\n")
+		}
+	}
+	sb.WriteString(")\n\n")
+
+	fmt.Fprintf(&sb, "// %s is a client for the API.\n", g.clientName)
+	fmt.Fprintf(&sb, "type %s struct {\nclient *apiclient.Client\n}\n\n", g.clientName)
+	fmt.Fprintf(&sb, "// New%s returns a new client that sends requests to the given base URL, like\n", g.clientName)
+	sb.WriteString("// `https://example.com`. If httpClient is nil, http.DefaultClient is used.\n")
+	fmt.Fprintf(&sb, "func New%s(baseURL string, httpClient *http.Client) *%s {\n", g.clientName, g.clientName)
+	fmt.Fprintf(&sb, "return &%s{client: &apiclient.Client{BaseURL: baseURL, HTTPClient: httpClient}}\n}\n", g.clientName)
+
+	sb.WriteString(g.methods.String())
+	sb.WriteString(g.typeDecls.String())
+
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated client: %w", err)
+	}
+
+	return src, nil
+}
+
+// boundFields returns the fields of a struct type bound from parts of a
+// request other than its body, including those promoted from embedded structs.
+func boundFields(typ reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+
+	for _, field := range reflect.VisibleFields(typ) {
+		if field.IsExported() && apireflect.IsBound(field) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// isStandardLibrary returns true if the given package path is for a package in
+// the standard library, which don't have a dot in their first path element.
+func isStandardLibrary(pkgPath string) bool {
+	firstElem, _, _ := strings.Cut(pkgPath, "/")
+	return !strings.Contains(firstElem, ".")
+}
+
+// methodName produces a client method name from the Go type of a route's
+// endpoint, like `JobGet` for *jobGetEndpoint.
+func methodName(route *apiendpoint.Route) string {
+	endpointType := reflect.TypeOf(route.Endpoint)
+	for endpointType.Kind() == reflect.Pointer {
+		endpointType = endpointType.Elem()
+	}
+
+	return cmp.Or(apireflect.UpperFirst(strings.TrimSuffix(endpointType.Name(), "Endpoint")), "Invoke")
+}
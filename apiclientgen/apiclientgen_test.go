@@ -0,0 +1,123 @@
+package apiclientgen
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/internal/testapi"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("TestClientUpToDate", func(t *testing.T) {
+		t.Parallel()
+
+		registry := apiendpoint.NewRegistry()
+		testapi.MountEndpoints(http.NewServeMux(), &apiendpoint.MountOpts{Registry: registry})
+
+		src, err := Generate(registry, &GenerateOpts{PackageName: "testclient"})
+		require.NoError(t, err)
+
+		existingSrc, err := os.ReadFile(filepath.Join("internal", "testclient", "client.go"))
+		require.NoError(t, err)
+
+		require.Equal(t, string(existingSrc), string(src), "Generated test client is out of date; run `go generate ./...`")
+	})
+
+	t.Run("PatternWithoutMethod", func(t *testing.T) {
+		t.Parallel()
+
+		registry := apiendpoint.NewRegistry()
+		apiendpoint.Mount(http.NewServeMux(), &noMethodEndpoint{}, &apiendpoint.MountOpts{Registry: registry})
+
+		_, err := Generate(registry, nil)
+		require.EqualError(t, err, `pattern "/api/no-method" must start with a method to generate a client method`)
+	})
+
+	t.Run("SkipsFormOnlyEndpoints", func(t *testing.T) {
+		t.Parallel()
+
+		registry := apiendpoint.NewRegistry()
+		apiendpoint.Mount(http.NewServeMux(), &formEndpoint{}, &apiendpoint.MountOpts{Registry: registry})
+
+		src, err := Generate(registry, nil)
+		require.NoError(t, err)
+		require.NotContains(t, string(src), "POST /api/form")
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		t.Parallel()
+
+		src, err := Generate(apiendpoint.NewRegistry(), &GenerateOpts{ClientName: "RiverClient", PackageName: "river"})
+		require.NoError(t, err)
+		require.Contains(t, string(src), "package river\n")
+		require.Contains(t, string(src), "func NewRiverClient(baseURL string, httpClient *http.Client) *RiverClient {")
+	})
+}
+
+func TestPathExpr(t *testing.T) {
+	t.Parallel()
+
+	params := map[string]*apiendpoint.RouteParameter{
+		"id":   {Field: reflect.StructField{Name: "ID"}, In: "path", Name: "id"},
+		"path": {Field: reflect.StructField{Name: "Path"}, In: "path", Name: "path"},
+	}
+
+	expr, err := pathExpr("/api/jobs", params)
+	require.NoError(t, err)
+	require.Equal(t, `"/api/jobs"`, expr)
+
+	expr, err = pathExpr("/api/jobs/{id}/files/{path...}", params)
+	require.NoError(t, err)
+	require.Equal(t, `"/api/jobs/" + apiclient.PathValue(req.ID, false) + "/files/" + apiclient.PathValue(req.Path, true)`, expr)
+
+	expr, err = pathExpr("/api/jobs/{id}/{$}", params)
+	require.NoError(t, err)
+	require.Equal(t, `"/api/jobs/" + apiclient.PathValue(req.ID, false) + "/"`, expr)
+
+	_, err = pathExpr("/api/queues/{queue}", params)
+	require.EqualError(t, err, "wildcard {queue} isn't bound to a request struct field with a `path` tag")
+}
+
+type formEndpoint struct {
+	apiendpoint.Endpoint[formRequest, struct{}]
+}
+
+func (*formEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:           "POST /api/form",
+		RequestMediaTypes: []string{apiendpoint.MediaTypeForm},
+		StatusCode:        http.StatusOK,
+	}
+}
+
+type formRequest struct {
+	Name string `form:"name" json:"-"`
+}
+
+func (*formEndpoint) Execute(_ context.Context, _ *formRequest) (*struct{}, error) {
+	return &struct{}{}, nil
+}
+
+type noMethodEndpoint struct {
+	apiendpoint.Endpoint[struct{}, struct{}]
+}
+
+func (*noMethodEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:    "/api/no-method",
+		StatusCode: http.StatusOK,
+	}
+}
+
+func (*noMethodEndpoint) Execute(_ context.Context, _ *struct{}) (*struct{}, error) {
+	return &struct{}{}, nil
+}
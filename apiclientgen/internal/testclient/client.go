@@ -0,0 +1,101 @@
+// Code generated by apiclientgen. DO NOT EDIT.
+
+package testclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/riverqueue/apiframe/apiclient"
+	"github.com/riverqueue/apiframe/apitype"
+)
+
+// Client is a client for the API.
+type Client struct {
+	client *apiclient.Client
+}
+
+// NewClient returns a new client that sends requests to the given base URL, like
+// `https://example.com`. If httpClient is nil, http.DefaultClient is used.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	return &Client{client: &apiclient.Client{BaseURL: baseURL, HTTPClient: httpClient}}
+}
+
+// JobCreate invokes `POST /api/jobs`.
+func (c *Client) JobCreate(ctx context.Context, req *JobCreateRequest) (*Job, error) {
+	header := http.Header{}
+	apiclient.AddHeader(header, "Idempotency-Key", req.IdempotencyKey)
+
+	return apiclient.Do[Job](ctx, c.client, &apiclient.Request{
+		Body:   req,
+		Header: header,
+		Method: "POST",
+		Path:   "/api/jobs",
+	})
+}
+
+// JobDelete invokes `DELETE /api/jobs/{id}`.
+func (c *Client) JobDelete(ctx context.Context, req *JobDeleteRequest) (*JobDeleteResponse, error) {
+	return apiclient.Do[JobDeleteResponse](ctx, c.client, &apiclient.Request{
+		Method: "DELETE",
+		Path:   "/api/jobs/" + apiclient.PathValue(req.ID, false),
+	})
+}
+
+// JobGet invokes `GET /api/queues/{queue}/jobs/{id}`.
+func (c *Client) JobGet(ctx context.Context, req *JobGetRequest) (*Job, error) {
+	query := url.Values{}
+	apiclient.AddQuery(query, "attempt", req.Attempt)
+	apiclient.AddQuery(query, "wait", req.Wait)
+
+	var cookies []*http.Cookie
+	cookies = apiclient.AddCookie(cookies, "session", req.Session)
+
+	return apiclient.Do[Job](ctx, c.client, &apiclient.Request{
+		Cookies: cookies,
+		Method:  "GET",
+		Path:    "/api/queues/" + apiclient.PathValue(req.Queue, false) + "/jobs/" + apiclient.PathValue(req.ID, false),
+		Query:   query,
+	})
+}
+
+type JobCreateRequest struct {
+	IdempotencyKey string                           `header:"Idempotency-Key" json:"-"`
+	Args           map[string]any                   `json:"args"`
+	Label          apitype.ExplicitNullable[string] `json:"label,omitzero"`
+	Queue          string                           `json:"queue"`
+	ScheduledAt    time.Time                        `json:"scheduled_at"`
+}
+
+type JobAttempt struct {
+	Error string `json:"error"`
+	Num   int    `json:"num"`
+}
+
+type Job struct {
+	ID          int64          `json:"id"`
+	Args        map[string]any `json:"args"`
+	Attempts    []JobAttempt   `json:"attempts"`
+	Label       *string        `json:"label,omitempty"`
+	Queue       string         `json:"queue"`
+	ScheduledAt time.Time      `json:"scheduled_at"`
+	State       string         `json:"state"`
+}
+
+type JobDeleteRequest struct {
+	ID int64 `path:"id" json:"-"`
+}
+
+type JobDeleteResponse struct {
+	Deleted bool `json:"deleted"`
+}
+
+type JobGetRequest struct {
+	ID      int64         `path:"id" json:"-"`
+	Queue   string        `path:"queue" json:"-"`
+	Attempt []int         `query:"attempt" json:"-"`
+	Session string        `cookie:"session" json:"-"`
+	Wait    time.Duration `query:"wait" json:"-"`
+}
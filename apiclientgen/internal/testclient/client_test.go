@@ -0,0 +1,124 @@
+package testclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/apitype"
+	"github.com/riverqueue/apiframe/internal/testapi"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *Client {
+		t.Helper()
+
+		mux := http.NewServeMux()
+		testapi.MountEndpoints(mux, &apiendpoint.MountOpts{Logger: riversharedtest.Logger(t)})
+
+		server := httptest.NewServer(mux)
+		t.Cleanup(server.Close)
+
+		return NewClient(server.URL, server.Client())
+	}
+
+	t.Run("BodyAndHeader", func(t *testing.T) {
+		t.Parallel()
+
+		client := setup(t)
+
+		scheduledAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		job, err := client.JobCreate(ctx, &JobCreateRequest{
+			IdempotencyKey: "key",
+			Args:           map[string]any{"foo": "bar"},
+			Label:          apitype.ExplicitNullable[string]{Set: true},
+			Queue:          "default",
+			ScheduledAt:    scheduledAt,
+		})
+		require.NoError(t, err)
+		require.Equal(t, &Job{
+			ID:          123,
+			Args:        map[string]any{"foo": "bar"},
+			Label:       ptr("<null>"),
+			Queue:       "default:key",
+			ScheduledAt: scheduledAt,
+			State:       "available",
+		}, job)
+	})
+
+	t.Run("ExplicitNullableOmitted", func(t *testing.T) {
+		t.Parallel()
+
+		client := setup(t)
+
+		job, err := client.JobCreate(ctx, &JobCreateRequest{Queue: "default"})
+		require.NoError(t, err)
+		require.Equal(t, ptr("<unset>"), job.Label)
+	})
+
+	t.Run("PathQueryAndCookie", func(t *testing.T) {
+		t.Parallel()
+
+		client := setup(t)
+
+		job, err := client.JobGet(ctx, &JobGetRequest{
+			ID:      123,
+			Queue:   "a queue/with slash",
+			Attempt: []int{1, 2},
+			Session: "sess",
+			Wait:    5 * time.Second,
+		})
+		require.NoError(t, err)
+		require.Equal(t, &Job{
+			ID: 123,
+			Attempts: []JobAttempt{
+				{Error: "sess:5s", Num: 1},
+				{Error: "sess:5s", Num: 2},
+			},
+			Queue: "a queue/with slash",
+			State: "running",
+		}, job)
+	})
+
+	t.Run("APIError", func(t *testing.T) {
+		t.Parallel()
+
+		client := setup(t)
+
+		resp, err := client.JobDelete(ctx, &JobDeleteRequest{ID: 123})
+		require.NoError(t, err)
+		require.Equal(t, &JobDeleteResponse{Deleted: true}, resp)
+
+		_, err = client.JobDelete(ctx, &JobDeleteRequest{ID: 404})
+		require.Equal(t, apierror.NewNotFound("Job not found: 404."), err)
+	})
+
+	t.Run("ValidationError", func(t *testing.T) {
+		t.Parallel()
+
+		client := setup(t)
+
+		_, err := client.JobCreate(ctx, &JobCreateRequest{})
+		expectedErr := apierror.WithCode(apierror.NewBadRequest("Field `queue` is required."), apierror.CodeValidationFailed)
+		expectedErr.Errors = []*apierror.FieldError{
+			{Field: "queue", JSONPath: "queue", Message: "Field `queue` is required.", Tag: "required"},
+		}
+		require.Equal(t, expectedErr, err)
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,20 @@
+// Command gen generates the test client from the endpoints in testapi.
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/riverqueue/apiframe/apiclientgen"
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/internal/testapi"
+)
+
+func main() {
+	registry := apiendpoint.NewRegistry()
+	testapi.MountEndpoints(http.NewServeMux(), &apiendpoint.MountOpts{Registry: registry})
+
+	if err := apiclientgen.WriteFile("client.go", registry, &apiclientgen.GenerateOpts{PackageName: "testclient"}); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,5 @@
+// Package testclient contains a client generated from the endpoints in testapi
+// that's used to verify that generated clients work end to end.
+package testclient
+
+//go:generate go run ./gen
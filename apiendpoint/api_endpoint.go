@@ -1,22 +1,35 @@
 // Package apiendpoint provides a lightweight API framework extracted from its
 // original use in River projects.  It lets API endpoints be defined, then
 // mounted into an http.ServeMux.
+//
+// An endpoint's request struct is populated from the request's JSON body, and
+// fields can also be bound from other parts of the request using struct tags:
+//
+//	type getJobRequest struct {
+//		ID             int64    `json:"-" path:"id"`
+//		IdempotencyKey string   `json:"-" header:"Idempotency-Key"`
+//		Kinds          []string `json:"-" query:"kinds"`
+//		Session        string   `json:"-" cookie:"session" validate:"required"`
+//	}
+//
+// Bound values are converted to the field's type (strings, integers, floats,
+// booleans, durations, time.Time, any encoding.TextUnmarshaler, and pointers or
+// slices of these), and a BadRequest naming the parameter is returned if one
+// can't be parsed. Binding happens before validation, so `validate` tags apply
+// to bound fields like any others.
 package apiendpoint
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
+	"reflect"
+	"runtime/debug"
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/riverqueue/apiframe/apierror"
 	"github.com/riverqueue/apiframe/apimiddleware"
@@ -70,15 +83,73 @@ type EndpointExecuteInterface[TReq any, TResp any] interface {
 
 // EndpointMeta is metadata about an API endpoint.
 type EndpointMeta struct {
+	// Errors are API errors that the endpoint is known to return, like a
+	// NotFound when a requested resource doesn't exist. They're not used when
+	// executing the endpoint, but are included in generated documentation.
+	// Errors emitted by the framework itself, like a BadRequest for a request
+	// that fails validation, don't need to be listed.
+	Errors []apierror.Interface
+
+	// MaxRequestBodyBytes is the maximum size of the endpoint's request body
+	// in bytes. A request with a larger body is rejected with a
+	// RequestEntityTooLarge, before the body is read if its Content-Length
+	// says it's too large. If zero, the limit from MountOpts is used. Set to
+	// a negative value like MaxRequestBodyBytesNone to disable the limit for
+	// this endpoint entirely.
+	MaxRequestBodyBytes int64
+
 	// Pattern is the API endpoint's HTTP method and path where it should be
 	// mounted, which is passed to http.ServeMux by Mount. It should start with
 	// a verb like `GET` or `POST`, and may contain Go 1.22 path variables like
-	// `{name}`, whose values should be extracted by an endpoint request
-	// struct's custom ExtractRaw implementation.
+	// `{name}`. When mounted through a Router, the path is relative to the
+	// router's prefix, and Pattern is updated to include it.
+	//
+	// Path variables are bound into request struct fields with a `path` tag
+	// like `path:"name"`, and converted to the field's type. Mount panics if a
+	// `path` tag names a variable that's not in the pattern, or if a variable
+	// in the pattern isn't bound to any field (unless the request struct
+	// implements RawExtractor, in which case it's expected that the variable is
+	// extracted in ExtractRaw).
 	Pattern string
 
+	// RequestMediaTypes are the media types of request bodies that the
+	// endpoint accepts, like `application/json`, each of which must have a
+	// decoder registered in MountOpts.Decoders. A request whose Content-Type
+	// isn't one of them is rejected with an UnsupportedMediaType. If empty,
+	// only MediaTypeJSON is accepted.
+	RequestMediaTypes []string
+
+	// ResourceName is a human-friendly name of the resource the endpoint
+	// operates on, like `job`. It's used by error interpreters to make
+	// messages more specific, like "Job not found." instead of "Resource not
+	// found." Optional.
+	ResourceName string
+
+	// ResponseMediaTypes are the media types that the endpoint can respond
+	// with, like `application/json` or `text/csv`, in order of preference,
+	// each of which must have an encoder registered in MountOpts.Encoders.
+	// The media type is negotiated using the request's Accept header, and a
+	// request that doesn't accept any of them is rejected with a
+	// NotAcceptable. If empty, only MediaTypeJSON is produced. Not used for
+	// response structs that implement RawResponder.
+	ResponseMediaTypes []string
+
 	// StatusCode is the status code to be set on a successful response.
 	StatusCode int
+
+	// StrictJSON enables strict decoding of the endpoint's JSON request
+	// body. See MountOpts.StrictJSON, which enables it for every endpoint
+	// mounted with the same options.
+	StrictJSON bool
+
+	// Timeout is the maximum amount of time the endpoint is given to execute
+	// before its context is cancelled and a 503 is returned. If zero, the
+	// timeout from MountOpts is used. Set to a negative value like
+	// TimeoutNone to disable the timeout for this endpoint entirely.
+	//
+	// Long running endpoints that make incremental progress can push the
+	// deadline back using ExtendTimeout or ResetTimeout.
+	Timeout time.Duration
 }
 
 func (m *EndpointMeta) validate() {
@@ -92,22 +163,140 @@ func (m *EndpointMeta) validate() {
 }
 
 type MountOpts struct {
-	Logger *slog.Logger
+	// Decoders is a registry of decoders for request bodies, keyed by media
+	// type, that endpoints opt into with EndpointMeta.RequestMediaTypes. If
+	// not specified, a registry from NewDecoderRegistry is used, which only
+	// decodes JSON.
+	Decoders *DecoderRegistry
+	// Encoders is a registry of encoders for responses, keyed by media type,
+	// that endpoints opt into with EndpointMeta.ResponseMediaTypes. If not
+	// specified, a registry from NewEncoderRegistry is used, which only
+	// encodes JSON.
+	Encoders *EncoderRegistry
+	// ErrorFormat is the wire format of error responses. If not specified,
+	// errors are written in the message-only apierror.FormatMessage. Clients
+	// may ask for RFC 9457 problem details regardless of this setting by
+	// sending an Accept header including `application/problem+json`.
+	ErrorFormat apierror.Format
+	// ErrorInterpreters is a chain of interpreters that convert errors
+	// returned by endpoints into API errors, like a database error into a
+	// user-friendly message. They're tried in order, and the first to return
+	// an API error wins. Errors that no interpreter recognizes produce an
+	// internal server error. If not specified, no errors are interpreted.
+	//
+	// Postgres errors can be interpreted by including an
+	// apipgx.ErrorInterpreter.
+	//
+	// When mounting through a Router, these are appended to the router's
+	// interpreters rather than replacing them.
+	ErrorInterpreters []ErrorInterpreter
+	// HeartbeatInterval is the interval between heartbeats sent on streaming
+	// responses like those of endpoints mounted with MountSSE, or pings sent
+	// on connections of endpoints mounted with MountWebSocket, which keep
+	// proxies from closing connections that are otherwise idle. If not
+	// specified, DefaultHeartbeatInterval is used. Set to a negative value to
+	// disable heartbeats.
+	HeartbeatInterval time.Duration
+	Logger            *slog.Logger
+	// MaxRequestBodyBytes is the default maximum size of request bodies in
+	// bytes for endpoints that don't specify their own with
+	// EndpointMeta.MaxRequestBodyBytes. If not specified,
+	// DefaultMaxRequestBodyBytes is used. Set to a negative value like
+	// MaxRequestBodyBytesNone to disable the limit by default.
+	MaxRequestBodyBytes int64
 	// MiddlewareStack is a stack of middleware that will be mounted in front of
 	// the API endpoint handler. If not specified, no middleware will be used.
 	MiddlewareStack *apimiddleware.MiddlewareStack
+	// OnPanic is invoked when an endpoint panics, after the panic has been
+	// recovered and logged, and before an internal server error is returned
+	// to the client. It's useful for forwarding panics to an error tracker.
+	// Optional.
+	OnPanic func(ctx context.Context, info *PanicInfo)
+	// Registry is a registry that mounted endpoints are added to, which makes
+	// them available for generating artifacts like API documentation. If not
+	// specified, endpoints aren't registered anywhere.
+	Registry *Registry
+	// StrictJSON enables strict decoding of JSON request bodies. A request is
+	// rejected with a BadRequest naming the offending JSON path if its body
+	// contains a field that doesn't exist on the request struct (or that's
+	// bound from elsewhere in the request, like with a `path` tag), the same
+	// field more than once, or data after its JSON value. By default these
+	// are ignored like they are by encoding/json, so a client that misspells
+	// a field gets a silent default. Strict decoding is enabled for an
+	// endpoint if either this or EndpointMeta.StrictJSON is set.
+	//
+	// Strictness only accumulates, so when set on a Router, it can't be
+	// turned back off for a group or endpoint mounted under it.
+	StrictJSON bool
+	// Timeout is the default timeout for endpoints that don't specify their
+	// own with EndpointMeta.Timeout. If not specified, DefaultTimeout is used.
+	// Set to a negative value like TimeoutNone to disable timeouts by default.
+	Timeout time.Duration
 	// Validator is the validator to use for this endpoint. If not specified,
 	// the default validator will be used.
 	Validator *validator.Validate
+	// WebSocket are options for endpoints mounted with MountWebSocket. If not
+	// specified, defaults are used.
+	WebSocket *WebSocketOpts
+}
+
+// mountConfig is the fully resolved configuration for a mounted endpoint,
+// derived from its EndpointMeta and MountOpts with defaults applied.
+type mountConfig struct {
+	bindings            []fieldBinding
+	decoders            map[string]Decoder
+	encoders            *responseEncoders // nil for RawResponder responses
+	errorFormat         apierror.Format
+	errorInterpreters   []ErrorInterpreter
+	heartbeatInterval   time.Duration
+	logger              *slog.Logger
+	maxRequestBodyBytes int64
+	meta                *EndpointMeta
+	onPanic             func(ctx context.Context, info *PanicInfo)
+	timeout             time.Duration
+	validator           *validator.Validate
 }
 
-// Mount mounts an endpoint to a Go http.ServeMux. The logger is used to log
-// information about endpoint execution.
-func Mount[TReq any, TResp any](mux *http.ServeMux, apiEndpoint EndpointExecuteInterface[TReq, TResp], opts *MountOpts) EndpointInterface {
-	if opts == nil {
-		opts = &MountOpts{}
+// Mount mounts an endpoint to a Go http.ServeMux, or to a Router that mounts
+// it to one. The logger is used to log information about endpoint execution.
+func Mount[TReq any, TResp any](mux Mux, apiEndpoint EndpointExecuteInterface[TReq, TResp], opts *MountOpts) EndpointInterface {
+	router, opts := resolveRouter(mux, opts)
+
+	config := resolveMountConfig[TReq](apiEndpoint, router, opts)
+	meta := config.meta
+
+	if _, hasRawResponder := any(new(TResp)).(RawResponder); !hasRawResponder {
+		encoderRegistry := opts.Encoders
+		if encoderRegistry == nil {
+			encoderRegistry = defaultEncoders
+		}
+
+		var err error
+		if config.encoders, err = resolveEncoders(encoderRegistry, meta); err != nil {
+			panic(fmt.Sprintf("error resolving response encoders for %q: %s", meta.Pattern, err))
+		}
 	}
 
+	config.timeout = meta.Timeout
+	if config.timeout == 0 {
+		config.timeout = opts.Timeout
+	}
+	if config.timeout == 0 {
+		config.timeout = DefaultTimeout
+	}
+
+	mountHandler(router, apiEndpoint, opts, config, reflect.TypeFor[TReq](), reflect.TypeFor[TResp](), func(w http.ResponseWriter, r *http.Request) {
+		executeAPIEndpoint(w, r, config, apiEndpoint.Execute)
+	})
+
+	return apiEndpoint
+}
+
+// resolveMountConfig resolves the configuration for mounting an endpoint with
+// a request struct of type TReq through a router that's shared by every kind
+// of endpoint, panicking if the endpoint is misconfigured. Settings specific
+// to a kind of endpoint, like its timeout, are left for the caller.
+func resolveMountConfig[TReq any](apiEndpoint EndpointInterface, router *Router, opts *MountOpts) *mountConfig {
 	logger := opts.Logger
 	if logger == nil {
 		logger = slog.Default()
@@ -122,59 +311,106 @@ func Mount[TReq any, TResp any](mux *http.ServeMux, apiEndpoint EndpointExecuteI
 
 	meta := apiEndpoint.Meta()
 	meta.validate() // panic on problem
+	meta.Pattern = router.prefixPattern(meta.Pattern)
 	apiEndpoint.SetMeta(meta)
 
-	innerHandler := func(w http.ResponseWriter, r *http.Request) {
-		executeAPIEndpoint(w, r, opts.Logger, meta, validator, apiEndpoint.Execute)
+	bindings, err := structBindings(reflect.TypeFor[TReq]())
+	if err != nil {
+		panic(fmt.Sprintf("error binding request struct for %q: %s", meta.Pattern, err))
 	}
 
-	if opts.MiddlewareStack != nil {
-		mux.Handle(meta.Pattern, opts.MiddlewareStack.Mount(http.HandlerFunc(innerHandler)))
-	} else {
-		mux.HandleFunc(meta.Pattern, innerHandler)
+	_, hasRawExtractor := any(new(TReq)).(RawExtractor)
+	if err := checkPathBindings(meta.Pattern, bindings, hasRawExtractor); err != nil {
+		panic(fmt.Sprintf("error binding request struct for %q: %s", meta.Pattern, err))
 	}
 
-	return apiEndpoint
+	if _, err := formBindings(reflect.TypeFor[TReq]()); err != nil {
+		panic(fmt.Sprintf("error binding request struct for %q: %s", meta.Pattern, err))
+	}
+
+	decoderRegistry := opts.Decoders
+	if decoderRegistry == nil {
+		decoderRegistry = defaultDecoders
+	}
+
+	decoders, err := resolveDecoders(decoderRegistry, meta, meta.StrictJSON || opts.StrictJSON)
+	if err != nil {
+		panic(fmt.Sprintf("error resolving request decoders for %q: %s", meta.Pattern, err))
+	}
+
+	maxRequestBodyBytes := meta.MaxRequestBodyBytes
+	if maxRequestBodyBytes == 0 {
+		maxRequestBodyBytes = opts.MaxRequestBodyBytes
+	}
+	if maxRequestBodyBytes == 0 {
+		maxRequestBodyBytes = DefaultMaxRequestBodyBytes
+	}
+
+	return &mountConfig{
+		bindings:            bindings,
+		decoders:            decoders,
+		errorFormat:         opts.ErrorFormat,
+		errorInterpreters:   opts.ErrorInterpreters,
+		logger:              logger,
+		maxRequestBodyBytes: maxRequestBodyBytes,
+		meta:                meta,
+		onPanic:             opts.OnPanic,
+		validator:           validator,
+	}
 }
 
-func executeAPIEndpoint[TReq any, TResp any](w http.ResponseWriter, r *http.Request, logger *slog.Logger, meta *EndpointMeta, validator *validator.Validate, execute func(ctx context.Context, req *TReq) (*TResp, error)) {
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
+// mountHandler adds an endpoint to its router's route table and to the
+// registry from MountOpts (if there is one), and mounts its handler behind any
+// middleware.
+func mountHandler(router *Router, apiEndpoint EndpointInterface, opts *MountOpts, config *mountConfig, reqType, respType reflect.Type, innerHandler http.HandlerFunc) {
+	route := &Route{
+		Endpoint:     apiEndpoint,
+		Meta:         config.meta,
+		Parameters:   routeParameters(reqType, config.bindings),
+		RequestType:  reqType,
+		ResponseType: respType,
+	}
 
-	err := func() error {
-		var req TReq
+	router.addRoute(route)
 
-		if r.Method != http.MethodGet {
-			reqData, err := io.ReadAll(r.Body)
-			if err != nil {
-				var maxBytesErr *http.MaxBytesError
-				if errors.As(err, &maxBytesErr) {
-					return apierror.NewRequestEntityTooLarge("Request entity too large.")
-				}
+	if opts.Registry != nil {
+		opts.Registry.add(route)
+	}
 
-				return fmt.Errorf("error reading request body: %w", err)
-			}
+	var handler http.Handler = innerHandler
+	if opts.MiddlewareStack != nil {
+		handler = opts.MiddlewareStack.Mount(handler)
+	}
 
-			if len(reqData) > 0 {
-				if err := json.Unmarshal(reqData, &req); err != nil {
-					return apierror.NewBadRequestf("Error unmarshaling request body: %s.", err)
-				}
-			}
+	router.handle(config.meta.Pattern, handler)
+}
 
-			r.Body = io.NopCloser(bytes.NewReader(reqData))
-		}
+func executeAPIEndpoint[TReq any, TResp any](w http.ResponseWriter, r *http.Request, config *mountConfig, execute func(ctx context.Context, req *TReq) (*TResp, error)) {
+	ctx := endpointContext(w, r, config)
 
-		if rawExtractor, ok := any(&req).(RawExtractor); ok {
-			if err := rawExtractor.ExtractRaw(r); err != nil {
+	ctx, cancel := withTimeout(ctx, config.timeout)
+	defer cancel()
+
+	defer removeMultipartFiles(r)
+
+	err := func() (err error) {
+		defer recoverPanic(&err)
+
+		// Negotiate the response's media type up front so that a request
+		// whose response can't be encoded isn't executed for nothing.
+		var encoder Encoder
+		if config.encoders != nil {
+			if encoder, err = config.encoders.negotiate(r); err != nil {
 				return err
 			}
 		}
 
-		if err := validator.StructCtx(ctx, &req); err != nil {
-			return apierror.NewBadRequest(validate.PublicFacingMessage(validator, err))
+		req, err := parseRequest[TReq](ctx, w, r, config)
+		if err != nil {
+			return err
 		}
 
-		resp, err := execute(ctx, &req)
+		resp, err := execute(ctx, req)
 		if err != nil {
 			return err
 		}
@@ -183,13 +419,13 @@ func executeAPIEndpoint[TReq any, TResp any](w http.ResponseWriter, r *http.Requ
 			return rawExtractor.RespondRaw(w)
 		}
 
-		respData, err := json.Marshal(resp)
+		respData, err := encoder.Encode(resp)
 		if err != nil {
-			return fmt.Errorf("error marshaling response JSON: %w", err)
+			return fmt.Errorf("error encoding response: %w", err)
 		}
 
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		w.WriteHeader(meta.StatusCode)
+		w.Header().Set("Content-Type", encoder.ContentType())
+		w.WriteHeader(config.meta.StatusCode)
 
 		if _, err := w.Write(respData); err != nil {
 			return fmt.Errorf("error writing response: %w", err)
@@ -198,79 +434,163 @@ func executeAPIEndpoint[TReq any, TResp any](w http.ResponseWriter, r *http.Requ
 		return nil
 	}()
 	if err != nil {
-		// Convert certain types of Postgres errors into something more
-		// user-friendly than an internal server error.
-		err = maybeInterpretInternalError(err)
-
-		var apiErr apierror.Interface
-		if errors.As(err, &apiErr) {
-			logAttrs := []any{
-				slog.String("error", apiErr.Error()),
-			}
+		resolveError(ctx, r, config, err).Write(ctx, config.logger, w)
+	}
+}
 
-			if internalErr := apiErr.GetInternalError(); internalErr != nil {
-				logAttrs = append(logAttrs, slog.String("internal_error", internalErr.Error()))
-			}
+// endpointContext returns the context that an endpoint is executed with,
+// which carries its metadata and determines how API errors are written.
+func endpointContext(w http.ResponseWriter, r *http.Request, config *mountConfig) context.Context {
+	ctx := apierror.WithFormat(r.Context(), apierror.NegotiateFormat(r.Header.Get("Accept"), config.errorFormat))
+	ctx = context.WithValue(ctx, metaContextKey{}, config.meta)
 
-			// Logged at info level because API errors are normal.
-			logger.InfoContext(ctx, "API error response", logAttrs...)
+	if config.encoders != nil {
+		ctx = config.encoders.withErrorEncoder(ctx, r)
 
-			apiErr.Write(ctx, logger, w)
+		if len(config.encoders.mediaTypes) > 1 {
+			w.Header().Add("Vary", "Accept")
+		}
+	}
 
-			return
+	return ctx
+}
+
+// parseRequest builds an endpoint's request struct from a request by decoding
+// its body, binding values from elsewhere in the request, running
+// ExtractRaw, and validating the result.
+func parseRequest[TReq any](ctx context.Context, w http.ResponseWriter, r *http.Request, config *mountConfig) (*TReq, error) {
+	var req TReq
+
+	if r.Method != http.MethodGet {
+		if err := decodeRequestBody(w, r, config.decoders, config.maxRequestBodyBytes, &req); err != nil {
+			return nil, err
 		}
+	}
 
-		if errors.Is(err, context.DeadlineExceeded) {
-			logger.ErrorContext(ctx, "request timeout", slog.String("error", err.Error()))
-			apierror.NewServiceUnavailable("Request timed out. Retrying the request might work.").Write(ctx, logger, w)
+	if err := bindRequest(r, config.bindings, &req); err != nil {
+		return nil, err
+	}
 
-			return
+	if rawExtractor, ok := any(&req).(RawExtractor); ok {
+		if err := rawExtractor.ExtractRaw(r); err != nil {
+			return nil, err
 		}
+	}
 
-		// Internal server error. The error goes to logs but should not be
-		// included in the response in case there's something sensitive in
-		// the error string.
-		logger.ErrorContext(ctx, "error running API route", slog.String("error", err.Error()))
-		apierror.NewInternalServerError("Internal server error. Check logs for more information.").Write(ctx, logger, w)
+	if err := config.validator.StructCtx(ctx, &req); err != nil {
+		return nil, validate.PublicFacingError(config.validator, err)
 	}
+
+	return &req, nil
 }
 
-// RawExtractor is an interface that can be implemented by request structs that
-// allows them to extract information from a raw request, like path values.
-type RawExtractor interface {
-	ExtractRaw(r *http.Request) error
+// recoverPanic recovers a panic in an endpoint and stores it to err as a
+// *panicError so that it's logged with the structured logger and the client
+// gets a normal internal server error instead of a dropped connection. It
+// must be deferred directly. http.ErrAbortHandler is used to deliberately
+// abort a response, so it's left for net/http to handle.
+func recoverPanic(err *error) {
+	if recovered := recover(); recovered != nil {
+		if recovered == http.ErrAbortHandler { //nolint:errorlint
+			panic(recovered)
+		}
+
+		*err = &panicError{recovered: recovered, stack: debug.Stack()}
+	}
 }
 
-// RawResponder is an interface that can be implemented by response structs that
-// allow them to respond directly to a ResponseWriter instead of emitting the
-// normal JSON format.
-type RawResponder interface {
-	RespondRaw(w http.ResponseWriter) error
+// removeMultipartFiles removes any temporary files left by a multipart request
+// body once the endpoint is done with them.
+func removeMultipartFiles(r *http.Request) {
+	if r.MultipartForm != nil {
+		_ = r.MultipartForm.RemoveAll()
+	}
 }
 
-// Make some broad categories of internal error back into something public
-// facing because in some cases they can be a vast help for debugging.
-func maybeInterpretInternalError(err error) error {
+// resolveError logs an error returned while executing an endpoint and
+// converts it into the API error that should be sent to the client. Errors
+// that aren't API errors and that no error interpreter recognizes become an
+// internal server error, with the original only going to logs.
+func resolveError(ctx context.Context, r *http.Request, config *mountConfig, err error) apierror.Interface {
 	var (
-		apiErr     apierror.Interface
-		connectErr *pgconn.ConnectError
-		pgErr      *pgconn.PgError
+		logger = config.logger
+		meta   = config.meta
 	)
 
-	switch {
-	case errors.As(err, &connectErr):
-		apiErr = apierror.NewBadRequest("There was a problem connecting to the configured database. Check logs for details.")
+	var panicErr *panicError
+	if errors.As(err, &panicErr) {
+		handlePanic(ctx, r, config, panicErr)
+		return apierror.WithCode(apierror.NewInternalServerError("Internal server error. Check logs for more information."), apierror.CodeInternalError)
+	}
 
-	case errors.As(err, &pgErr):
-		if pgErr.Code == pgerrcode.InsufficientPrivilege {
-			apiErr = apierror.NewBadRequest("Insufficient database privilege to perform this operation.")
-		} else {
-			return err
+	// Convert errors that an interpreter recognizes, like certain types of
+	// Postgres errors, into something more user-friendly than an internal
+	// server error.
+	err = interpretError(ctx, config.errorInterpreters, err)
+
+	var apiErr apierror.Interface
+	if errors.As(err, &apiErr) {
+		logAttrs := []any{
+			slog.String("error", apiErr.Error()),
 		}
 
-	default:
-		return err
+		if internalErr := apiErr.GetInternalError(); internalErr != nil {
+			logAttrs = append(logAttrs, slog.String("internal_error", internalErr.Error()))
+		}
+
+		// Logged at info level because API errors are normal.
+		logger.InfoContext(ctx, "API error response", logAttrs...)
+
+		return apiErr
 	}
 
-	return apierror.WithInternalError(apiErr, err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// If the deadline came from this endpoint's own timeout (as opposed
+		// to one set on the incoming request's context), say which limit was
+		// hit so it's obvious what needs tuning.
+		if limit, ok := timeoutExceeded(ctx); ok {
+			logger.ErrorContext(ctx, "request timeout",
+				slog.String("error", err.Error()),
+				slog.String("pattern", meta.Pattern),
+				slog.Duration("timeout", limit),
+			)
+			return apierror.WithCode(apierror.NewServiceUnavailablef("Request timed out after %s. Retrying the request might work.", limit), apierror.CodeTimeout)
+		}
+
+		logger.ErrorContext(ctx, "request timeout",
+			slog.String("error", err.Error()),
+			slog.String("pattern", meta.Pattern),
+		)
+		return apierror.WithCode(apierror.NewServiceUnavailable("Request timed out. Retrying the request might work."), apierror.CodeTimeout)
+	}
+
+	// Internal server error. The error goes to logs but should not be
+	// included in the response in case there's something sensitive in the
+	// error string.
+	logger.ErrorContext(ctx, "error running API route", slog.String("error", err.Error()))
+	return apierror.WithCode(apierror.NewInternalServerError("Internal server error. Check logs for more information."), apierror.CodeInternalError)
+}
+
+type metaContextKey struct{}
+
+// MetaFromContext returns the metadata of the endpoint being executed, which
+// is useful to error interpreters and middleware-like helpers that want to
+// tailor their behavior to an endpoint. Returns nil if the context isn't one
+// created for an endpoint.
+func MetaFromContext(ctx context.Context) *EndpointMeta {
+	meta, _ := ctx.Value(metaContextKey{}).(*EndpointMeta)
+	return meta
+}
+
+// RawExtractor is an interface that can be implemented by request structs that
+// allows them to extract information from a raw request, like path values.
+type RawExtractor interface {
+	ExtractRaw(r *http.Request) error
+}
+
+// RawResponder is an interface that can be implemented by response structs that
+// allow them to respond directly to a ResponseWriter instead of emitting the
+// normal JSON format.
+type RawResponder interface {
+	RespondRaw(w http.ResponseWriter) error
 }
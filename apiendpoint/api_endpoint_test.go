@@ -10,11 +10,10 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/jackc/pgerrcode"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/require"
 
 	"github.com/riverqueue/apiframe/apierror"
@@ -37,11 +36,24 @@ func TestMountAndServe(t *testing.T) {
 		var (
 			logger = riversharedtest.Logger(t)
 			mux    = http.NewServeMux()
-			opts   = &MountOpts{Logger: logger}
+			opts   = &MountOpts{
+				ErrorInterpreters: []ErrorInterpreter{
+					ErrorInterpreterFunc(func(_ context.Context, err error) apierror.Interface {
+						if errors.Is(err, errPaymentDeclined) {
+							return apierror.NewUnprocessableEntity("Payment was declined.")
+						}
+						return nil
+					}),
+				},
+				Logger: logger,
+			}
 		)
 
 		Mount(mux, &getEndpoint{}, opts)
 		Mount(mux, &postEndpoint{}, opts)
+		Mount(mux, &headerEndpoint{}, opts)
+		Mount(mux, &pathEndpoint{}, opts)
+		Mount(mux, &queryEndpoint{}, opts)
 
 		return mux, &testBundle{
 			logger:   logger,
@@ -82,7 +94,68 @@ func TestMountAndServe(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBuffer(payload))
 		req.Body = http.MaxBytesReader(bundle.recorder, io.NopCloser(bytes.NewReader(payload)), int64(len(payload)-1))
 		mux.ServeHTTP(bundle.recorder, req)
-		requireStatusAndJSONResponse(t, http.StatusRequestEntityTooLarge, &apierror.APIError{Message: "Request entity too large."}, bundle.recorder)
+		requireStatusAndJSONResponse(t, http.StatusRequestEntityTooLarge, &apierror.APIError{Code: apierror.CodeRequestEntityTooLarge, Message: fmt.Sprintf("Request entity too large. The maximum request body size is %d bytes.", len(payload)-1)}, bundle.recorder)
+	})
+
+	t.Run("MaxRequestBodyBytes", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mux      = http.NewServeMux()
+			payload  = mustMarshalJSON(t, &postRequest{Message: "Hello."})
+			recorder = httptest.NewRecorder()
+		)
+
+		Mount(mux, &postEndpoint{MaxBodyBytes: 10}, &MountOpts{Logger: riversharedtest.Logger(t)})
+
+		// Rejected based on Content-Length, before the body is read.
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBuffer(payload))
+		mux.ServeHTTP(recorder, req)
+		requireStatusAndJSONResponse(t, http.StatusRequestEntityTooLarge, &apierror.APIError{Code: apierror.CodeRequestEntityTooLarge, Message: "Request entity too large. The maximum request body size is 10 bytes."}, recorder)
+
+		// Rejected while reading a body of unknown length.
+		recorder = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", io.NopCloser(bytes.NewBuffer(payload)))
+		req.ContentLength = -1
+		mux.ServeHTTP(recorder, req)
+		requireStatusAndJSONResponse(t, http.StatusRequestEntityTooLarge, &apierror.APIError{Code: apierror.CodeRequestEntityTooLarge, Message: "Request entity too large. The maximum request body size is 10 bytes."}, recorder)
+	})
+
+	t.Run("MaxRequestBodyBytesFromMountOpts", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mux      = http.NewServeMux()
+			payload  = mustMarshalJSON(t, &postRequest{Message: "Hello."})
+			recorder = httptest.NewRecorder()
+		)
+
+		Mount(mux, &postEndpoint{}, &MountOpts{Logger: riversharedtest.Logger(t), MaxRequestBodyBytes: 1 << 10})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBuffer(bytes.Repeat([]byte(" "), 2<<10)))
+		mux.ServeHTTP(recorder, req)
+		requireStatusAndJSONResponse(t, http.StatusRequestEntityTooLarge, &apierror.APIError{Code: apierror.CodeRequestEntityTooLarge, Message: "Request entity too large. The maximum request body size is 1 KB."}, recorder)
+
+		recorder = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBuffer(payload))
+		mux.ServeHTTP(recorder, req)
+		requireStatusAndJSONResponse(t, http.StatusCreated, &postResponse{ID: "123", Message: "Hello.", RawPayload: payload}, recorder)
+	})
+
+	t.Run("MaxRequestBodyBytesNone", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mux      = http.NewServeMux()
+			payload  = mustMarshalJSON(t, &postRequest{Message: "Hello." + strings.Repeat(" ", int(DefaultMaxRequestBodyBytes))})
+			recorder = httptest.NewRecorder()
+		)
+
+		Mount(mux, &postEndpoint{MaxBodyBytes: MaxRequestBodyBytesNone}, &MountOpts{Logger: riversharedtest.Logger(t)})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBuffer(payload))
+		mux.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusCreated, recorder.Code)
 	})
 
 	t.Run("MethodNotAllowed", func(t *testing.T) {
@@ -138,6 +211,142 @@ func TestMountAndServe(t *testing.T) {
 		requireStatusAndJSONResponse(t, http.StatusCreated, &postResponse{ID: "123", Message: "Hello.", RawPayload: reqPayload}, bundle.recorder)
 	})
 
+	t.Run("HeaderAndCookieBinding", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/header-endpoint", nil)
+		req.Header.Set("Idempotency-Key", "key_123")
+		req.AddCookie(&http.Cookie{Name: "session", Value: "sess_123"})
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusOK, &headerResponse{IdempotencyKey: "key_123", Session: "sess_123"}, bundle.recorder)
+	})
+
+	t.Run("HeaderAndCookieBindingValidationError", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/header-endpoint", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{
+			Code: apierror.CodeValidationFailed,
+			Errors: []*apierror.FieldError{
+				{Field: "Idempotency-Key", Message: "Header `Idempotency-Key` is required.", Tag: "required"},
+				{Field: "session", Message: "Cookie `session` is required.", Tag: "required"},
+			},
+			Message: "Header `Idempotency-Key` is required. Cookie `session` is required.",
+		}, bundle.recorder)
+	})
+
+	t.Run("PathBinding", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/path-endpoint/123/queue-default", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusOK, &pathResponse{ID: 123, Queue: "default"}, bundle.recorder)
+	})
+
+	t.Run("PathBindingParseError", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/path-endpoint/abc/queue-default", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidParameter, Message: "Path parameter `id` must be an integer."}, bundle.recorder)
+	})
+
+	t.Run("PathBindingTextUnmarshalerError", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/path-endpoint/123/default", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidParameter, Message: "Path parameter `queue` has an invalid value."}, bundle.recorder)
+	})
+
+	t.Run("PathBindingValidationError", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/path-endpoint/0/queue-default", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{
+			Code: apierror.CodeValidationFailed,
+			Errors: []*apierror.FieldError{
+				{Field: "id", Message: "Field `id` must be greater or equal to 1.", Param: "1", Tag: "min"},
+			},
+			Message: "Field `id` must be greater or equal to 1.",
+		}, bundle.recorder)
+	})
+
+	t.Run("PathBindingMismatchPanics", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t,
+			"error binding request struct for \"GET /api/path-endpoint/{id}\": `path` tag \"queue\" doesn't match any wildcard in pattern \"GET /api/path-endpoint/{id}\"",
+			func() {
+				Mount(http.NewServeMux(), &pathEndpoint{pattern: "GET /api/path-endpoint/{id}"}, nil)
+			})
+
+		require.PanicsWithValue(t,
+			"error binding request struct for \"GET /api/path-endpoint/{id}/{queue}/{kind}\": wildcard {kind} in pattern \"GET /api/path-endpoint/{id}/{queue}/{kind}\" isn't bound to a request struct field with a `path` tag",
+			func() {
+				Mount(http.NewServeMux(), &pathEndpoint{pattern: "GET /api/path-endpoint/{id}/{queue}/{kind}"}, nil)
+			})
+	})
+
+	t.Run("QueryBinding", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/query-endpoint?ids=1,2&ids=3&limit=10", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusOK, &queryResponse{IDs: []int64{1, 2, 3}, Limit: 10}, bundle.recorder)
+	})
+
+	t.Run("QueryBindingParseError", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/query-endpoint?limit=ten", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidParameter, Message: "Query parameter `limit` must be an integer."}, bundle.recorder)
+	})
+
+	t.Run("QueryBindingValidationError", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/query-endpoint?limit=1000", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{
+			Code: apierror.CodeValidationFailed,
+			Errors: []*apierror.FieldError{
+				{Field: "limit", Message: "Field `limit` must be less than or equal to 100.", Param: "100", Tag: "max"},
+			},
+			Message: "Field `limit` must be less than or equal to 100.",
+		}, bundle.recorder)
+	})
+
 	t.Run("ValidationError", func(t *testing.T) {
 		t.Parallel()
 
@@ -146,7 +355,13 @@ func TestMountAndServe(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", nil)
 		mux.ServeHTTP(bundle.recorder, req)
 
-		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Message: "Field `message` is required."}, bundle.recorder)
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{
+			Code: apierror.CodeValidationFailed,
+			Errors: []*apierror.FieldError{
+				{Field: "message", JSONPath: "message", Message: "Field `message` is required.", Tag: "required"},
+			},
+			Message: "Field `message` is required.",
+		}, bundle.recorder)
 	})
 
 	t.Run("APIError", func(t *testing.T) {
@@ -161,16 +376,152 @@ func TestMountAndServe(t *testing.T) {
 		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Message: "Bad request."}, bundle.recorder)
 	})
 
+	t.Run("ErrorFormatFromAccept", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123",
+			bytes.NewBuffer(mustMarshalJSON(t, &postRequest{MakeAPIError: true, Message: "Hello."})))
+		req.Header.Set("Accept", "application/problem+json, application/json;q=0.9")
+		mux.ServeHTTP(bundle.recorder, req)
+
+		require.Equal(t, http.StatusBadRequest, bundle.recorder.Result().StatusCode)
+		require.Equal(t, apierror.ContentTypeProblemDetails, bundle.recorder.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"detail":"Bad request.","status":400,"title":"Bad Request","type":"about:blank"}`, bundle.recorder.Body.String())
+	})
+
+	t.Run("ErrorFormatFromMountOpts", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		mux := http.NewServeMux()
+		Mount(mux, &postEndpoint{}, &MountOpts{ErrorFormat: apierror.FormatProblemDetails, Logger: bundle.logger})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123",
+			bytes.NewBuffer(mustMarshalJSON(t, &postRequest{})))
+		mux.ServeHTTP(bundle.recorder, req)
+
+		require.Equal(t, http.StatusBadRequest, bundle.recorder.Result().StatusCode)
+		require.Equal(t, apierror.ContentTypeProblemDetails, bundle.recorder.Header().Get("Content-Type"))
+		require.JSONEq(t, `{
+			"code": "validation_failed",
+			"detail": "Field `+"`message`"+` is required.",
+			"errors": [{"field": "message", "json_path": "message", "message": "Field `+"`message`"+` is required.", "param": "", "tag": "required"}],
+			"status": 400,
+			"title": "Bad Request",
+			"type": "about:blank"
+		}`, bundle.recorder.Body.String())
+	})
+
+	t.Run("UnmarshalTypeError", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBufferString(`{"message":123}`))
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{
+			Code: apierror.CodeInvalidBody,
+			Errors: []*apierror.FieldError{
+				{Field: "message", JSONPath: "message", Message: "Field `message` must be a string, but got a number.", Tag: "type"},
+			},
+			Message: "Field `message` must be a string, but got a number.",
+		}, bundle.recorder)
+	})
+
+	t.Run("UnmarshalSyntaxError", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBufferString(`{"message":}`))
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidBody, Message: "Request body contains invalid JSON at byte 12: invalid character '}' looking for beginning of value."}, bundle.recorder)
+	})
+
+	t.Run("UnknownFieldIgnoredByDefault", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBufferString(`{"message":"Hello.","mesage":"Typo."}`))
+		mux.ServeHTTP(bundle.recorder, req)
+
+		require.Equal(t, http.StatusCreated, bundle.recorder.Code)
+	})
+
+	t.Run("StrictJSONMountOpts", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		mux := http.NewServeMux()
+		Mount(mux, &postEndpoint{}, &MountOpts{Logger: bundle.logger, StrictJSON: true})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBufferString(`{"message":"Hello.","mesage":"Typo."}`))
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidBody, Message: "Unknown field `mesage` in request body."}, bundle.recorder)
+	})
+
+	t.Run("StrictJSONEndpointMeta", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		mux := http.NewServeMux()
+		Mount(mux, &postEndpoint{StrictJSON: true}, &MountOpts{Logger: bundle.logger})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBufferString(`{"message":"Hello.","message":"Again."}`))
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidBody, Message: "Duplicate field `message` in request body."}, bundle.recorder)
+	})
+
+	t.Run("StrictJSONValid", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		mux := http.NewServeMux()
+		Mount(mux, &postEndpoint{StrictJSON: true}, &MountOpts{Logger: bundle.logger})
+
+		reqPayload := mustMarshalJSON(t, &postRequest{Message: "Hello."})
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBuffer(reqPayload))
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusCreated, &postResponse{ID: "123", Message: "Hello.", RawPayload: reqPayload}, bundle.recorder)
+	})
+
 	t.Run("InterpretedError", func(t *testing.T) {
 		t.Parallel()
 
 		mux, bundle := setup(t)
 
 		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123",
-			bytes.NewBuffer(mustMarshalJSON(t, &postRequest{MakePostgresError: true, Message: "Hello."})))
+			bytes.NewBuffer(mustMarshalJSON(t, &postRequest{MakeDomainError: true, Message: "Hello."})))
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusUnprocessableEntity, &apierror.APIError{Message: "Payment was declined."}, bundle.recorder)
+	})
+
+	t.Run("UninterpretedError", func(t *testing.T) {
+		t.Parallel()
+
+		_, bundle := setup(t)
+
+		mux := http.NewServeMux()
+		Mount(mux, &postEndpoint{}, &MountOpts{Logger: bundle.logger})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123",
+			bytes.NewBuffer(mustMarshalJSON(t, &postRequest{MakeDomainError: true, Message: "Hello."})))
 		mux.ServeHTTP(bundle.recorder, req)
 
-		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Message: "Insufficient database privilege to perform this operation."}, bundle.recorder)
+		requireStatusAndJSONResponse(t, http.StatusInternalServerError, &apierror.APIError{Code: apierror.CodeInternalError, Message: "Internal server error. Check logs for more information."}, bundle.recorder)
 	})
 
 	t.Run("Timeout", func(t *testing.T) {
@@ -186,58 +537,88 @@ func TestMountAndServe(t *testing.T) {
 		require.NoError(t, err)
 		mux.ServeHTTP(bundle.recorder, req)
 
-		requireStatusAndJSONResponse(t, http.StatusServiceUnavailable, &apierror.APIError{Message: "Request timed out. Retrying the request might work."}, bundle.recorder)
+		requireStatusAndJSONResponse(t, http.StatusServiceUnavailable, &apierror.APIError{Code: apierror.CodeTimeout, Message: "Request timed out. Retrying the request might work."}, bundle.recorder)
 	})
 
-	t.Run("InternalServerError", func(t *testing.T) {
+	t.Run("TimeoutFromEndpointMeta", func(t *testing.T) {
 		t.Parallel()
 
-		mux, bundle := setup(t)
+		_, bundle := setup(t)
 
-		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123",
-			bytes.NewBuffer(mustMarshalJSON(t, &postRequest{MakeInternalError: true, Message: "Hello."})))
+		mux := http.NewServeMux()
+		Mount(mux, &slowEndpoint{timeout: 10 * time.Millisecond}, &MountOpts{Logger: bundle.logger, Timeout: time.Hour})
+
+		req := httptest.NewRequest(http.MethodGet, "/api/slow-endpoint", nil)
 		mux.ServeHTTP(bundle.recorder, req)
 
-		requireStatusAndJSONResponse(t, http.StatusInternalServerError, &apierror.APIError{Message: "Internal server error. Check logs for more information."}, bundle.recorder)
+		requireStatusAndJSONResponse(t, http.StatusServiceUnavailable, &apierror.APIError{Code: apierror.CodeTimeout, Message: "Request timed out after 10ms. Retrying the request might work."}, bundle.recorder)
 	})
-}
 
-func TestMaybeInterpretInternalError(t *testing.T) {
-	t.Parallel()
+	t.Run("TimeoutFromMountOpts", func(t *testing.T) {
+		t.Parallel()
 
-	ctx := context.Background()
+		_, bundle := setup(t)
 
-	t.Run("ConnectError", func(t *testing.T) {
-		t.Parallel()
+		mux := http.NewServeMux()
+		Mount(mux, &slowEndpoint{}, &MountOpts{Logger: bundle.logger, Timeout: 20 * time.Millisecond})
 
-		_, err := pgconn.Connect(ctx, "postgres://user@127.0.0.1:37283/does_not_exist")
+		req := httptest.NewRequest(http.MethodGet, "/api/slow-endpoint", nil)
+		mux.ServeHTTP(bundle.recorder, req)
 
-		require.Equal(t, apierror.WithInternalError(apierror.NewBadRequest("There was a problem connecting to the configured database. Check logs for details."), err), maybeInterpretInternalError(err))
+		requireStatusAndJSONResponse(t, http.StatusServiceUnavailable, &apierror.APIError{Code: apierror.CodeTimeout, Message: "Request timed out after 20ms. Retrying the request might work."}, bundle.recorder)
 	})
 
-	t.Run("ConnectError", func(t *testing.T) {
+	t.Run("TimeoutNone", func(t *testing.T) {
 		t.Parallel()
 
-		err := &pgconn.PgError{Code: pgerrcode.InsufficientPrivilege}
+		_, bundle := setup(t)
+
+		mux := http.NewServeMux()
+		Mount(mux, &slowEndpoint{sleep: 50 * time.Millisecond}, &MountOpts{Logger: bundle.logger, Timeout: TimeoutNone})
 
-		require.Equal(t, apierror.WithInternalError(apierror.NewBadRequest("Insufficient database privilege to perform this operation."), err), maybeInterpretInternalError(err))
+		req := httptest.NewRequest(http.MethodGet, "/api/slow-endpoint", nil)
+		mux.ServeHTTP(bundle.recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusOK, &slowResponse{HasDeadline: false}, bundle.recorder)
 	})
 
-	t.Run("OtherPGError", func(t *testing.T) {
+	t.Run("InternalServerError", func(t *testing.T) {
 		t.Parallel()
 
-		err := &pgconn.PgError{Code: pgerrcode.CardinalityViolation}
+		mux, bundle := setup(t)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123",
+			bytes.NewBuffer(mustMarshalJSON(t, &postRequest{MakeInternalError: true, Message: "Hello."})))
+		mux.ServeHTTP(bundle.recorder, req)
 
-		require.Equal(t, err, maybeInterpretInternalError(err))
+		requireStatusAndJSONResponse(t, http.StatusInternalServerError, &apierror.APIError{Code: apierror.CodeInternalError, Message: "Internal server error. Check logs for more information."}, bundle.recorder)
 	})
+}
 
-	t.Run("ConnectError", func(t *testing.T) {
-		t.Parallel()
+func TestMetaFromContext(t *testing.T) {
+	t.Parallel()
 
-		err := errors.New("other error")
+	require.Nil(t, MetaFromContext(context.Background()))
 
-		require.Equal(t, err, maybeInterpretInternalError(err))
+	var meta *EndpointMeta
+
+	mux := http.NewServeMux()
+	Mount(mux, &postEndpoint{}, &MountOpts{
+		ErrorInterpreters: []ErrorInterpreter{
+			ErrorInterpreterFunc(func(ctx context.Context, _ error) apierror.Interface {
+				meta = MetaFromContext(ctx)
+				return nil
+			}),
+		},
+		Logger: riversharedtest.Logger(t),
 	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123",
+		bytes.NewBuffer(mustMarshalJSON(t, &postRequest{MakeDomainError: true, Message: "Hello."})))
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NotNil(t, meta)
+	require.Equal(t, "POST /api/post-endpoint/{id}", meta.Pattern)
 }
 
 func mustMarshalJSON(t *testing.T, v any) []byte {
@@ -316,24 +697,33 @@ func (a *getEndpoint) Execute(_ context.Context, req *getRequest) (*getResponse,
 // postEndpoint
 //
 
+var errPaymentDeclined = errors.New("payment declined")
+
 type postEndpoint struct {
 	Endpoint[postRequest, postResponse]
 
-	MaxBodyBytes int64
+	MaxBodyBytes       int64
+	RequestMediaTypes  []string
+	ResponseMediaTypes []string
+	StrictJSON         bool
 }
 
 func (a *postEndpoint) Meta() *EndpointMeta {
 	return &EndpointMeta{
-		Pattern:    "POST /api/post-endpoint/{id}",
-		StatusCode: http.StatusCreated,
+		MaxRequestBodyBytes: a.MaxBodyBytes,
+		Pattern:             "POST /api/post-endpoint/{id}",
+		RequestMediaTypes:   a.RequestMediaTypes,
+		ResponseMediaTypes:  a.ResponseMediaTypes,
+		StatusCode:          http.StatusCreated,
+		StrictJSON:          a.StrictJSON,
 	}
 }
 
 type postRequest struct {
 	ID                string `json:"-"                   validate:"-"`
 	MakeAPIError      bool   `json:"make_api_error"      validate:"-"`
+	MakeDomainError   bool   `json:"make_domain_error"   validate:"-"`
 	MakeInternalError bool   `json:"make_internal_error" validate:"-"`
-	MakePostgresError bool   `json:"make_postgres_error" validate:"-"`
 	Message           string `json:"message"             validate:"required"`
 	RawPayload        []byte `json:"-"                   validate:"-"`
 }
@@ -368,10 +758,161 @@ func (a *postEndpoint) Execute(ctx context.Context, req *postRequest) (*postResp
 		return nil, errors.New("an internal error occurred")
 	}
 
-	if req.MakePostgresError {
+	if req.MakeDomainError {
 		// Wrap the error to make it more realistic.
-		return nil, fmt.Errorf("error running Postgres query: %w", &pgconn.PgError{Code: pgerrcode.InsufficientPrivilege})
+		return nil, fmt.Errorf("error charging card: %w", errPaymentDeclined)
 	}
 
 	return &postResponse{ID: req.ID, Message: req.Message, RawPayload: req.RawPayload}, nil
 }
+
+//
+// headerEndpoint
+//
+
+type headerEndpoint struct {
+	Endpoint[headerRequest, headerResponse]
+}
+
+func (*headerEndpoint) Meta() *EndpointMeta {
+	return &EndpointMeta{
+		Pattern:    "GET /api/header-endpoint",
+		StatusCode: http.StatusOK,
+	}
+}
+
+type headerRequest struct {
+	IdempotencyKey string `header:"Idempotency-Key" json:"-" validate:"required"`
+	Session        string `cookie:"session"         json:"-" validate:"required"`
+}
+
+type headerResponse struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	Session        string `json:"session"`
+}
+
+func (*headerEndpoint) Execute(_ context.Context, req *headerRequest) (*headerResponse, error) {
+	return &headerResponse{IdempotencyKey: req.IdempotencyKey, Session: req.Session}, nil
+}
+
+//
+// pathEndpoint
+//
+
+type pathEndpoint struct {
+	Endpoint[pathRequest, pathResponse]
+
+	pattern string
+}
+
+func (a *pathEndpoint) Meta() *EndpointMeta {
+	pattern := a.pattern
+	if pattern == "" {
+		pattern = "GET /api/path-endpoint/{id}/{queue}"
+	}
+
+	return &EndpointMeta{
+		Pattern:    pattern,
+		StatusCode: http.StatusOK,
+	}
+}
+
+type pathRequest struct {
+	ID    int64     `json:"-" path:"id"    validate:"min=1"`
+	Queue queueName `json:"-" path:"queue" validate:"-"`
+}
+
+// queueName is a custom encoding.TextUnmarshaler that expects a value prefixed
+// with `queue-`.
+type queueName string
+
+func (n *queueName) UnmarshalText(text []byte) error {
+	name, ok := strings.CutPrefix(string(text), "queue-")
+	if !ok {
+		return errors.New("queue name should be prefixed with `queue-`")
+	}
+
+	*n = queueName(name)
+	return nil
+}
+
+type pathResponse struct {
+	ID    int64  `json:"id"`
+	Queue string `json:"queue"`
+}
+
+func (*pathEndpoint) Execute(_ context.Context, req *pathRequest) (*pathResponse, error) {
+	return &pathResponse{ID: req.ID, Queue: string(req.Queue)}, nil
+}
+
+//
+// queryEndpoint
+//
+
+type queryEndpoint struct {
+	Endpoint[queryRequest, queryResponse]
+}
+
+func (*queryEndpoint) Meta() *EndpointMeta {
+	return &EndpointMeta{
+		Pattern:    "GET /api/query-endpoint",
+		StatusCode: http.StatusOK,
+	}
+}
+
+type queryRequest struct {
+	IDs   []int64 `json:"-" query:"ids"   validate:"-"`
+	Limit int     `json:"-" query:"limit" validate:"max=100"`
+}
+
+type queryResponse struct {
+	IDs   []int64 `json:"ids"`
+	Limit int     `json:"limit"`
+}
+
+func (*queryEndpoint) Execute(_ context.Context, req *queryRequest) (*queryResponse, error) {
+	return &queryResponse{IDs: req.IDs, Limit: req.Limit}, nil
+}
+
+//
+// slowEndpoint
+//
+
+type slowEndpoint struct {
+	Endpoint[slowRequest, slowResponse]
+
+	sleep   time.Duration
+	timeout time.Duration
+}
+
+func (a *slowEndpoint) Meta() *EndpointMeta {
+	return &EndpointMeta{
+		Pattern:    "GET /api/slow-endpoint",
+		StatusCode: http.StatusOK,
+		Timeout:    a.timeout,
+	}
+}
+
+type slowRequest struct{}
+
+type slowResponse struct {
+	HasDeadline bool `json:"has_deadline"`
+}
+
+// Execute sleeps for the configured duration, or blocks until the context is
+// cancelled if no sleep was configured.
+func (a *slowEndpoint) Execute(ctx context.Context, _ *slowRequest) (*slowResponse, error) {
+	sleepChan := make(<-chan time.Time)
+	if a.sleep > 0 {
+		sleepChan = time.After(a.sleep)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-sleepChan:
+	}
+
+	_, hasDeadline := ctx.Deadline()
+	return &slowResponse{HasDeadline: hasDeadline}, nil
+}
@@ -0,0 +1,398 @@
+package apiendpoint
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+// Struct tags that bind values from parts of an HTTP request into fields of an
+// endpoint's request struct. Binding happens after the request body has been
+// unmarshaled and before ExtractRaw and validation run, so bound values
+// overwrite anything set from the body.
+const (
+	// tagCookie binds a field from a request cookie, like `cookie:"session"`.
+	tagCookie = "cookie"
+
+	// tagHeader binds a field from a request header, like
+	// `header:"Idempotency-Key"`. Slice fields accept both repeated headers and
+	// comma-separated values.
+	tagHeader = "header"
+
+	// tagPath binds a field from a path wildcard in the endpoint's pattern,
+	// like `path:"id"` for a pattern of `GET /jobs/{id}`.
+	tagPath = "path"
+
+	// tagQuery binds a field from a query string parameter, like
+	// `query:"limit"`. Slice fields accept both repeated parameters
+	// (`?id=1&id=2`) and comma-separated values (`?id=1,2`).
+	tagQuery = "query"
+)
+
+// fieldBinding is a single field on a request struct that's bound from part of
+// an HTTP request.
+type fieldBinding struct {
+	// index is the field's index path, as accepted by reflect.Value's
+	// FieldByIndex. It's more than one element long for fields promoted from
+	// embedded structs.
+	index []int
+
+	// name is the name of the value being bound, like the name of a query
+	// parameter.
+	name string
+
+	// tag is the struct tag that defined the binding, like tagQuery.
+	tag string
+}
+
+// structBindingsCache caches the results of structBindings by type because
+// reflecting over a struct is relatively expensive, and it's done on every
+// request.
+var structBindingsCache sync.Map //nolint:gochecknoglobals
+
+// structBindings returns the bound fields of the given type, or an error if the
+// type's binding tags are misconfigured, like if they're on a field of a type
+// that can't be bound. Types that aren't structs have no bindings.
+func structBindings(typ reflect.Type) ([]fieldBinding, error) {
+	if cached, ok := structBindingsCache.Load(typ); ok {
+		return cached.([]fieldBinding), nil //nolint:forcetypeassert
+	}
+
+	var bindings []fieldBinding
+	if typ.Kind() == reflect.Struct {
+		var err error
+		if bindings, err = appendStructBindings(nil, typ, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	structBindingsCache.Store(typ, bindings)
+	return bindings, nil
+}
+
+func appendStructBindings(bindings []fieldBinding, typ reflect.Type, parentIndex []int) ([]fieldBinding, error) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		index := append(append([]int{}, parentIndex...), i)
+
+		var found bool
+		for _, tag := range []string{tagCookie, tagHeader, tagPath, tagQuery} {
+			name, ok := field.Tag.Lookup(tag)
+			if !ok || name == "-" {
+				continue
+			}
+
+			if found {
+				return nil, fmt.Errorf("field %s.%s has more than one binding tag", typ.Name(), field.Name)
+			}
+			found = true
+
+			if !field.IsExported() {
+				return nil, fmt.Errorf("field %s.%s has a `%s` tag, but isn't exported", typ.Name(), field.Name, tag)
+			}
+
+			if name == "" {
+				return nil, fmt.Errorf("field %s.%s has an empty `%s` tag", typ.Name(), field.Name, tag)
+			}
+
+			if !isBindableType(field.Type, tag == tagHeader || tag == tagQuery) {
+				return nil, fmt.Errorf("field %s.%s has a `%s` tag, but its type %s can't be bound", typ.Name(), field.Name, tag, field.Type)
+			}
+
+			bindings = append(bindings, fieldBinding{index: index, name: name, tag: tag})
+		}
+
+		// Look into embedded structs for promoted fields.
+		if !found && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			var err error
+			if bindings, err = appendStructBindings(bindings, field.Type, index); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return bindings, nil
+}
+
+// checkPathBindings checks that path bindings and the wildcards in an
+// endpoint's pattern agree with each other. Every `path` tag must name a
+// wildcard in the pattern, and every wildcard must be bound to a field, unless
+// the request struct implements RawExtractor, in which case it's assumed that
+// unbound wildcards are extracted there.
+func checkPathBindings(pattern string, bindings []fieldBinding, hasRawExtractor bool) error {
+	wildcards := patternWildcards(pattern)
+
+	bound := make(map[string]struct{}, len(bindings))
+	for _, binding := range bindings {
+		if binding.tag != tagPath {
+			continue
+		}
+
+		if !slices.Contains(wildcards, binding.name) {
+			return fmt.Errorf("`path` tag %q doesn't match any wildcard in pattern %q", binding.name, pattern)
+		}
+
+		bound[binding.name] = struct{}{}
+	}
+
+	if hasRawExtractor {
+		return nil
+	}
+
+	for _, wildcard := range wildcards {
+		if _, ok := bound[wildcard]; !ok {
+			return fmt.Errorf("wildcard {%s} in pattern %q isn't bound to a request struct field with a `path` tag", wildcard, pattern)
+		}
+	}
+
+	return nil
+}
+
+// patternWildcards extracts the names of wildcards from an http.ServeMux
+// pattern like `GET /jobs/{id}/{rest...}`. The special `{$}` wildcard that
+// matches the end of a path is not included.
+func patternWildcards(pattern string) []string {
+	var wildcards []string
+
+	for {
+		_, after, ok := strings.Cut(pattern, "{")
+		if !ok {
+			break
+		}
+
+		var wildcard string
+		wildcard, pattern, ok = strings.Cut(after, "}")
+		if !ok {
+			break
+		}
+
+		wildcard = strings.TrimSuffix(wildcard, "...")
+		if wildcard != "$" {
+			wildcards = append(wildcards, wildcard)
+		}
+	}
+
+	return wildcards
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()            //nolint:gochecknoglobals
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]() //nolint:gochecknoglobals
+	timeType            = reflect.TypeFor[time.Time]()                //nolint:gochecknoglobals
+)
+
+// isBindableType returns true if the given type can be parsed from a string
+// value by setFieldFromString. Slices of bindable types are also bindable if
+// allowSlice is true.
+func isBindableType(typ reflect.Type, allowSlice bool) bool {
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Bool,
+		reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.String,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+
+	case reflect.Pointer:
+		return isBindableType(typ.Elem(), false)
+
+	case reflect.Slice:
+		return allowSlice && isBindableType(typ.Elem(), false)
+	}
+
+	return false
+}
+
+// bindRequest binds values from the given HTTP request into the bound fields of
+// req, which should be a pointer to a struct.
+func bindRequest(r *http.Request, bindings []fieldBinding, req any) error {
+	if len(bindings) < 1 {
+		return nil
+	}
+
+	var (
+		query    = r.URL.Query()
+		reqValue = reflect.ValueOf(req).Elem()
+	)
+
+	for _, binding := range bindings {
+		var (
+			description string
+			values      []string
+		)
+
+		switch binding.tag {
+		case tagCookie:
+			description = "Cookie"
+			if cookie, err := r.Cookie(binding.name); err == nil {
+				values = []string{cookie.Value}
+			}
+
+		case tagHeader:
+			description = "Header"
+			values = r.Header.Values(binding.name)
+
+		case tagPath:
+			description = "Path parameter"
+			if value := r.PathValue(binding.name); value != "" {
+				values = []string{value}
+			}
+
+		case tagQuery:
+			description = "Query parameter"
+			values = query[binding.name]
+		}
+
+		if err := setFieldFromStrings(reqValue.FieldByIndex(binding.index), values); err != nil {
+			return apierror.WithCode(apierror.NewBadRequestf("%s `%s` %s.", description, binding.name, err), apierror.CodeInvalidParameter)
+		}
+	}
+
+	return nil
+}
+
+// setFieldFromStrings sets the given field from a set of string values. Slice
+// fields get every value, with comma-separated values split into separate
+// elements and surrounding whitespace trimmed. Other fields get the first value.
+//
+// Fields are left untouched if there are no values, or for fields other than
+// strings, if values are empty, so that something like `?limit=` is treated the
+// same as if the parameter had been omitted.
+func setFieldFromStrings(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && !reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+		var elems []string
+		for _, value := range values {
+			for elem := range strings.SplitSeq(value, ",") {
+				if elem = strings.TrimSpace(elem); elem != "" {
+					elems = append(elems, elem)
+				}
+			}
+		}
+
+		if len(elems) < 1 {
+			return nil
+		}
+
+		slice := reflect.MakeSlice(field.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err := setFieldFromString(slice.Index(i), elem); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+		return nil
+	}
+
+	if len(values) < 1 {
+		return nil
+	}
+
+	if values[0] == "" && indirectType(field.Type()).Kind() != reflect.String {
+		return nil
+	}
+
+	return setFieldFromString(field, values[0])
+}
+
+// errInvalidValue is a generic parse error for types where there's nothing more
+// specific to say about what went wrong.
+var errInvalidValue = errors.New("has an invalid value")
+
+// setFieldFromString parses a string value into the given field according to
+// its type. Returned errors describe what the value should have looked like
+// and are suitable for use in a public-facing message.
+func setFieldFromString(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setFieldFromString(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		field.Set(ptr)
+		return nil
+	}
+
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(value)); err != nil {
+			if field.Type() == timeType {
+				return errors.New("must be an RFC 3339 timestamp like `2006-01-02T15:04:05Z`")
+			}
+
+			return errInvalidValue
+		}
+
+		return nil
+	}
+
+	switch field.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		field.SetBool(boolVal)
+
+	case reflect.Float32, reflect.Float64:
+		floatVal, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		field.SetFloat(floatVal)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == durationType {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return errors.New("must be a duration like `30s` or `5m`")
+			}
+			field.SetInt(int64(duration))
+			return nil
+		}
+
+		intVal, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		field.SetInt(intVal)
+
+	case reflect.String:
+		field.SetString(value)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintVal, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		field.SetUint(uintVal)
+
+	default:
+		// Should be prevented by isBindableType when bindings are built.
+		return errInvalidValue
+	}
+
+	return nil
+}
+
+// indirectType returns the type pointed to by typ if it's a pointer, and typ
+// otherwise.
+func indirectType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Pointer {
+		return typ.Elem()
+	}
+
+	return typ
+}
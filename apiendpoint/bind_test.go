@@ -0,0 +1,299 @@
+package apiendpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+func TestStructBindings(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Fields", func(t *testing.T) {
+		t.Parallel()
+
+		type embedded struct {
+			Cursor string `query:"cursor"`
+		}
+
+		type request struct {
+			embedded
+
+			Limit   int    `query:"limit"`
+			Ignored string `query:"-"`
+			Message string `json:"message"`
+		}
+
+		bindings, err := structBindings(reflect.TypeFor[request]())
+		require.NoError(t, err)
+		require.Equal(t, []fieldBinding{
+			{index: []int{0, 0}, name: "cursor", tag: tagQuery},
+			{index: []int{1}, name: "limit", tag: tagQuery},
+		}, bindings)
+	})
+
+	t.Run("HeaderAndCookie", func(t *testing.T) {
+		t.Parallel()
+
+		type request struct {
+			IfMatch  []string `header:"If-Match"`
+			Session  string   `cookie:"session"`
+			Sessions []string `cookie:"sessions"`
+		}
+
+		_, err := structBindings(reflect.TypeFor[request]())
+		require.EqualError(t, err, "field request.Sessions has a `cookie` tag, but its type []string can't be bound")
+	})
+
+	t.Run("NotStruct", func(t *testing.T) {
+		t.Parallel()
+
+		bindings, err := structBindings(reflect.TypeFor[string]())
+		require.NoError(t, err)
+		require.Empty(t, bindings)
+	})
+
+	t.Run("MultipleTags", func(t *testing.T) {
+		t.Parallel()
+
+		type request struct {
+			ID int `path:"id" query:"id"`
+		}
+
+		_, err := structBindings(reflect.TypeFor[request]())
+		require.EqualError(t, err, "field request.ID has more than one binding tag")
+	})
+
+	t.Run("PathSlice", func(t *testing.T) {
+		t.Parallel()
+
+		type request struct {
+			IDs []int `path:"ids"`
+		}
+
+		_, err := structBindings(reflect.TypeFor[request]())
+		require.EqualError(t, err, "field request.IDs has a `path` tag, but its type []int can't be bound")
+	})
+
+	t.Run("EmptyName", func(t *testing.T) {
+		t.Parallel()
+
+		type request struct {
+			Limit int `query:""`
+		}
+
+		_, err := structBindings(reflect.TypeFor[request]())
+		require.EqualError(t, err, "field request.Limit has an empty `query` tag")
+	})
+
+	t.Run("Unexported", func(t *testing.T) {
+		t.Parallel()
+
+		type request struct {
+			limit int `query:"limit"`
+		}
+
+		_, err := structBindings(reflect.TypeFor[request]())
+		require.EqualError(t, err, "field request.limit has a `query` tag, but isn't exported")
+	})
+
+	t.Run("UnbindableType", func(t *testing.T) {
+		t.Parallel()
+
+		type request struct {
+			Filter map[string]string `query:"filter"`
+		}
+
+		_, err := structBindings(reflect.TypeFor[request]())
+		require.EqualError(t, err, "field request.Filter has a `query` tag, but its type map[string]string can't be bound")
+	})
+}
+
+func TestBindRequest(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		Bool     bool          `query:"bool"`
+		Duration time.Duration `query:"duration"`
+		Float    float64       `query:"float"`
+		Int      int64         `query:"int"`
+		IntPtr   *int          `query:"int_ptr"`
+		Strings  []string      `query:"strings"`
+		String   string        `query:"string"`
+		StrPtr   *string       `query:"str_ptr"`
+		Time     time.Time     `query:"time"`
+		TimePtr  *time.Time    `query:"time_ptr"`
+		Uint     uint          `query:"uint"`
+		Uints    []uint        `query:"uints"`
+	}
+
+	bindings, err := structBindings(reflect.TypeFor[request]())
+	require.NoError(t, err)
+
+	bind := func(t *testing.T, rawQuery string) (*request, error) {
+		t.Helper()
+
+		var req request
+		err := bindRequest(httptest.NewRequest(http.MethodGet, "/?"+rawQuery, nil), bindings, &req)
+		return &req, err
+	}
+
+	t.Run("AllTypes", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := bind(t, "bool=true&duration=5s&float=1.5&int=-3&int_ptr=7&strings=a&strings=b,c&string=hello&str_ptr=&time=2025-01-02T03:04:05Z&time_ptr=2025-01-02T03:04:05Z&uint=3&uints=1,2")
+		require.NoError(t, err)
+
+		expectedTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		require.Equal(t, &request{
+			Bool:     true,
+			Duration: 5 * time.Second,
+			Float:    1.5,
+			Int:      -3,
+			IntPtr:   ptr(7),
+			Strings:  []string{"a", "b", "c"},
+			String:   "hello",
+			StrPtr:   ptr(""),
+			Time:     expectedTime,
+			TimePtr:  &expectedTime,
+			Uint:     3,
+			Uints:    []uint{1, 2},
+		}, req)
+	})
+
+	t.Run("Omitted", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := bind(t, "int=&int_ptr=&strings=")
+		require.NoError(t, err)
+		require.Equal(t, &request{}, req)
+	})
+
+	t.Run("FirstValueUsed", func(t *testing.T) {
+		t.Parallel()
+
+		req, err := bind(t, "int=1&int=2")
+		require.NoError(t, err)
+		require.Equal(t, &request{Int: 1}, req)
+	})
+
+	t.Run("ParseErrors", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tt := range []struct {
+			rawQuery string
+			message  string
+		}{
+			{"bool=maybe", "Query parameter `bool` must be a boolean."},
+			{"duration=soon", "Query parameter `duration` must be a duration like `30s` or `5m`."},
+			{"float=abc", "Query parameter `float` must be a number."},
+			{"int=abc", "Query parameter `int` must be an integer."},
+			{"int_ptr=1.5", "Query parameter `int_ptr` must be an integer."},
+			{"time=yesterday", "Query parameter `time` must be an RFC 3339 timestamp like `2006-01-02T15:04:05Z`."},
+			{"uint=-1", "Query parameter `uint` must be a non-negative integer."},
+			{"uints=1,x", "Query parameter `uints` must be a non-negative integer."},
+		} {
+			_, err := bind(t, tt.rawQuery)
+			require.Equal(t, apierror.WithCode(apierror.NewBadRequest(tt.message), apierror.CodeInvalidParameter), err)
+		}
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestCheckPathBindings(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		ID   int64  `path:"id"`
+		Name string `path:"name"`
+	}
+
+	bindings, err := structBindings(reflect.TypeFor[request]())
+	require.NoError(t, err)
+
+	t.Run("AllBound", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, checkPathBindings("GET /jobs/{id}/{name}", bindings, false))
+	})
+
+	t.Run("TagWithoutWildcard", func(t *testing.T) {
+		t.Parallel()
+
+		require.EqualError(t, checkPathBindings("GET /jobs/{id}", bindings, false),
+			"`path` tag \"name\" doesn't match any wildcard in pattern \"GET /jobs/{id}\"")
+	})
+
+	t.Run("WildcardWithoutTag", func(t *testing.T) {
+		t.Parallel()
+
+		require.EqualError(t, checkPathBindings("GET /jobs/{id}/{name}/{kind}", bindings, false),
+			"wildcard {kind} in pattern \"GET /jobs/{id}/{name}/{kind}\" isn't bound to a request struct field with a `path` tag")
+	})
+
+	t.Run("WildcardWithoutTagRawExtractor", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, checkPathBindings("GET /jobs/{id}/{name}/{kind}", bindings, true))
+	})
+}
+
+func TestPatternWildcards(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, patternWildcards("GET /jobs"))
+	require.Equal(t, []string{"id"}, patternWildcards("GET /jobs/{id}"))
+	require.Equal(t, []string{"id", "rest"}, patternWildcards("GET example.com/jobs/{id}/{rest...}"))
+	require.Equal(t, []string{"id"}, patternWildcards("GET /jobs/{id}/{$}"))
+}
+
+func TestBindRequestHeaderAndCookie(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		IdempotencyKey string   `header:"Idempotency-Key"`
+		IfMatch        []string `header:"If-Match"`
+		Page           int      `cookie:"page"`
+		Session        string   `cookie:"session"`
+	}
+
+	bindings, err := structBindings(reflect.TypeFor[request]())
+	require.NoError(t, err)
+
+	t.Run("Bound", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("idempotency-key", "abc")
+		r.Header.Add("If-Match", `"v1", "v2"`)
+		r.Header.Add("If-Match", `"v3"`)
+		r.AddCookie(&http.Cookie{Name: "session", Value: "sess_123"})
+
+		var req request
+		require.NoError(t, bindRequest(r, bindings, &req))
+		require.Equal(t, request{
+			IdempotencyKey: "abc",
+			IfMatch:        []string{`"v1"`, `"v2"`, `"v3"`},
+			Session:        "sess_123",
+		}, req)
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		t.Parallel()
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: "page", Value: "first"})
+
+		var req request
+		require.Equal(t, apierror.WithCode(apierror.NewBadRequest("Cookie `page` must be an integer."), apierror.CodeInvalidParameter), bindRequest(r, bindings, &req))
+	})
+}
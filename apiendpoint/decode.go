@@ -0,0 +1,282 @@
+package apiendpoint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/internal/validate"
+)
+
+// MediaTypeJSON is the media type of JSON request bodies, which every
+// endpoint accepts unless it specifies otherwise with
+// EndpointMeta.RequestMediaTypes.
+const MediaTypeJSON = "application/json"
+
+// DefaultMaxRequestBodyBytes is the maximum size of request bodies when
+// neither EndpointMeta.MaxRequestBodyBytes nor MountOpts.MaxRequestBodyBytes
+// is set.
+const DefaultMaxRequestBodyBytes int64 = 10 << 20 // 10 MB
+
+// MaxRequestBodyBytesNone can be assigned to EndpointMeta.MaxRequestBodyBytes
+// or MountOpts.MaxRequestBodyBytes to disable the request body size limit
+// entirely. Any negative value has the same effect.
+const MaxRequestBodyBytesNone int64 = -1
+
+// Decoder decodes request bodies of a particular media type into an
+// endpoint's request struct.
+type Decoder interface {
+	// Decode decodes a request body into v, which is a pointer to the
+	// endpoint's request struct. Problems with the body should be returned as
+	// an API error (usually a BadRequest) so that they're reported to the
+	// client. Other errors produce an internal server error.
+	Decode(r *http.Request, data []byte, v any) error
+}
+
+// StreamDecoder is a Decoder that can also read request bodies itself instead
+// of being given them in full, like to stream large file uploads to disk
+// instead of holding them in memory. If an endpoint's decoder for a request
+// implements it, DecodeStream is used instead of Decode, and the request's
+// body isn't available to RawExtractor afterwards.
+type StreamDecoder interface {
+	Decoder
+
+	// DecodeStream decodes a request's body by reading from r.Body into v,
+	// which is a pointer to the endpoint's request struct.
+	DecodeStream(r *http.Request, v any) error
+}
+
+// DecoderFunc is a function that implements Decoder.
+type DecoderFunc func(r *http.Request, data []byte, v any) error
+
+func (f DecoderFunc) Decode(r *http.Request, data []byte, v any) error {
+	return f(r, data, v)
+}
+
+// DecoderRegistry is a set of request decoders keyed by media type, like
+// `application/json`. Endpoints opt into decoders by listing their media types
+// in EndpointMeta.RequestMediaTypes, and a registry is given to Mount with
+// MountOpts.Decoders.
+//
+//	decoders := apiendpoint.NewDecoderRegistry()
+//	decoders.Register("application/msgpack", apiendpoint.DecoderFunc(func(_ *http.Request, data []byte, v any) error {
+//		if err := msgpack.Unmarshal(data, v); err != nil {
+//			return apierror.NewBadRequestf("Error decoding MessagePack request body: %s.", err)
+//		}
+//		return nil
+//	}))
+type DecoderRegistry struct {
+	decoders map[string]Decoder
+	mu       sync.RWMutex
+}
+
+// NewDecoderRegistry initializes a new decoder registry containing decoders
+// for MediaTypeJSON, MediaTypeForm, and MediaTypeMultipartForm.
+func NewDecoderRegistry() *DecoderRegistry {
+	registry := &DecoderRegistry{decoders: make(map[string]Decoder)}
+	registry.Register(MediaTypeForm, &formDecoder{})
+	registry.Register(MediaTypeJSON, &jsonDecoder{})
+	registry.Register(MediaTypeMultipartForm, NewMultipartDecoder(nil))
+	return registry
+}
+
+// Lookup returns the decoder registered for the given media type, or false if
+// there isn't one.
+func (r *DecoderRegistry) Lookup(mediaType string) (Decoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	decoder, ok := r.decoders[strings.ToLower(mediaType)]
+	return decoder, ok
+}
+
+// MediaTypes returns the media types of every registered decoder, sorted.
+func (r *DecoderRegistry) MediaTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(r.decoders))
+}
+
+// Register registers a decoder for a media type like `application/msgpack`,
+// replacing any decoder already registered for it. Media types are matched
+// case-insensitively and without parameters like `charset`.
+func (r *DecoderRegistry) Register(mediaType string, decoder Decoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.decoders[strings.ToLower(mediaType)] = decoder
+}
+
+// defaultDecoders is the decoder registry used by endpoints that are mounted
+// without MountOpts.Decoders.
+var defaultDecoders = NewDecoderRegistry() //nolint:gochecknoglobals
+
+// jsonDecoder decodes JSON request bodies, rejecting bodies that don't match
+// the request struct if strict is set. See MountOpts.StrictJSON.
+type jsonDecoder struct {
+	strict bool
+}
+
+func (d *jsonDecoder) Decode(_ *http.Request, data []byte, v any) error {
+	typ := reflect.TypeOf(v).Elem()
+
+	if d.strict {
+		var strictErr *strictJSONError
+		if err := checkStrictJSON(data, typ); errors.As(err, &strictErr) {
+			return apierror.WithCode(apierror.NewBadRequest(strictErr.message), apierror.CodeInvalidBody)
+		}
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return validate.PublicFacingJSONError(err, typ)
+	}
+
+	return nil
+}
+
+// resolveDecoders returns the decoders for the media types that an endpoint
+// accepts, keyed by media type.
+func resolveDecoders(registry *DecoderRegistry, meta *EndpointMeta, strictJSON bool) (map[string]Decoder, error) {
+	mediaTypes := meta.RequestMediaTypes
+	if len(mediaTypes) < 1 {
+		mediaTypes = []string{MediaTypeJSON}
+	}
+
+	decoders := make(map[string]Decoder, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		decoder, ok := registry.Lookup(mediaType)
+		if !ok {
+			return nil, fmt.Errorf("no decoder registered for request media type %q", mediaType)
+		}
+
+		// The built-in JSON decoder is configured per endpoint because strict
+		// decoding may be enabled on only some of them.
+		if _, ok := decoder.(*jsonDecoder); ok {
+			decoder = &jsonDecoder{strict: strictJSON}
+		}
+
+		decoders[strings.ToLower(mediaType)] = decoder
+	}
+
+	return decoders, nil
+}
+
+// decodeRequestBody decodes a request's body into v, which is a pointer to the
+// endpoint's request struct, using the decoder for its Content-Type. Requests
+// without a body are left alone. Unless the decoder is a StreamDecoder, the
+// body is read in full and then restored so that it can be read again by a
+// RawExtractor.
+//
+// A body larger than maxBytes is rejected, without being read at all if its
+// Content-Length is known. maxBytes may be negative for no limit.
+func decodeRequestBody(w http.ResponseWriter, r *http.Request, decoders map[string]Decoder, maxBytes int64, v any) error {
+	if maxBytes > 0 {
+		if r.ContentLength > maxBytes {
+			return requestEntityTooLargeError(maxBytes)
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	}
+
+	// Peek at the body to find out whether there is one without reading it
+	// all, because a StreamDecoder should be given it unread.
+	body := bufio.NewReader(r.Body)
+	if _, err := body.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		return readBodyError(err)
+	}
+
+	decoder, err := requestDecoder(r, decoders)
+	if err != nil {
+		return err
+	}
+
+	if streamDecoder, ok := decoder.(StreamDecoder); ok {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{body, r.Body}
+
+		return streamDecoder.DecodeStream(r, v)
+	}
+
+	reqData, err := io.ReadAll(body)
+	if err != nil {
+		return readBodyError(err)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(reqData))
+
+	return decoder.Decode(r, reqData, v)
+}
+
+// readBodyError converts an error from reading a request body into an API
+// error if it was caused by the body being too large.
+func readBodyError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return requestEntityTooLargeError(maxBytesErr.Limit)
+	}
+
+	return fmt.Errorf("error reading request body: %w", err)
+}
+
+// requestEntityTooLargeError returns a RequestEntityTooLarge API error for a
+// request body larger than maxBytes. The limit is left out of the message if
+// it's not known, which is indicated by a maxBytes of zero or less.
+func requestEntityTooLargeError(maxBytes int64) error {
+	if maxBytes <= 0 {
+		return apierror.WithCode(apierror.NewRequestEntityTooLarge("Request entity too large."), apierror.CodeRequestEntityTooLarge)
+	}
+
+	return apierror.WithCode(apierror.NewRequestEntityTooLargef("Request entity too large. The maximum request body size is %s.", formatByteSize(maxBytes)), apierror.CodeRequestEntityTooLarge)
+}
+
+// requestDecoder returns the decoder for a request based on its Content-Type
+// header, or an UnsupportedMediaType API error if the endpoint doesn't accept
+// its media type. Requests without a Content-Type are assumed to be JSON for
+// compatibility with clients that don't send one.
+func requestDecoder(r *http.Request, decoders map[string]Decoder) (Decoder, error) {
+	contentType := r.Header.Get("Content-Type")
+
+	mediaType := MediaTypeJSON
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			mediaType = ""
+		}
+	}
+
+	if decoder, ok := decoders[strings.ToLower(mediaType)]; ok {
+		return decoder, nil
+	}
+
+	var (
+		supported = slices.Sorted(maps.Keys(decoders))
+		message   = fmt.Sprintf("Content-Type `%s` isn't supported.", contentType)
+	)
+
+	if contentType == "" {
+		message = "Request body is missing a Content-Type."
+	}
+
+	return nil, apierror.WithCode(
+		apierror.NewUnsupportedMediaTypef("%s Supported types: `%s`.", message, strings.Join(supported, "`, `")).WithAccept(supported...),
+		apierror.CodeUnsupportedMediaType,
+	)
+}
@@ -0,0 +1,148 @@
+package apiendpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestDecoderRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := NewDecoderRegistry()
+
+	_, ok := registry.Lookup(MediaTypeJSON)
+	require.True(t, ok)
+
+	_, ok = registry.Lookup("text/csv")
+	require.False(t, ok)
+
+	registry.Register("Text/CSV", DecoderFunc(func(_ *http.Request, _ []byte, _ any) error { return nil }))
+
+	_, ok = registry.Lookup("text/csv")
+	require.True(t, ok)
+
+	require.Equal(t, []string{MediaTypeJSON, MediaTypeForm, MediaTypeMultipartForm, "text/csv"}, registry.MediaTypes())
+}
+
+func TestRequestDecoder(t *testing.T) {
+	t.Parallel()
+
+	var (
+		jsonDecoder = &jsonDecoder{}
+		decoders    = map[string]Decoder{MediaTypeJSON: jsonDecoder, "application/msgpack": DecoderFunc(func(_ *http.Request, _ []byte, _ any) error { return nil })}
+	)
+
+	requestWithContentType := func(contentType string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		return r
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		decoder, err := requestDecoder(requestWithContentType("application/json"), decoders)
+		require.NoError(t, err)
+		require.Equal(t, jsonDecoder, decoder)
+	})
+
+	t.Run("ParametersAndCase", func(t *testing.T) {
+		t.Parallel()
+
+		decoder, err := requestDecoder(requestWithContentType("Application/JSON; charset=utf-8"), decoders)
+		require.NoError(t, err)
+		require.Equal(t, jsonDecoder, decoder)
+	})
+
+	t.Run("MissingContentTypeIsJSON", func(t *testing.T) {
+		t.Parallel()
+
+		decoder, err := requestDecoder(requestWithContentType(""), decoders)
+		require.NoError(t, err)
+		require.Equal(t, jsonDecoder, decoder)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := requestDecoder(requestWithContentType("text/plain"), decoders)
+		require.Equal(t,
+			apierror.WithCode(apierror.NewUnsupportedMediaType("Content-Type `text/plain` isn't supported. Supported types: `application/json`, `application/msgpack`.").WithAccept("application/json", "application/msgpack"), apierror.CodeUnsupportedMediaType),
+			err,
+		)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := requestDecoder(requestWithContentType("application/"), decoders)
+		require.IsType(t, &apierror.UnsupportedMediaType{}, err)
+	})
+
+	t.Run("MissingContentTypeWithoutJSON", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := requestDecoder(requestWithContentType(""), map[string]Decoder{"text/csv": jsonDecoder})
+		require.Equal(t,
+			apierror.WithCode(apierror.NewUnsupportedMediaType("Request body is missing a Content-Type. Supported types: `text/csv`.").WithAccept("text/csv"), apierror.CodeUnsupportedMediaType),
+			err,
+		)
+	})
+}
+
+func TestMountDecoders(t *testing.T) {
+	t.Parallel()
+
+	// Decodes a body containing only a JSON string into the request's message.
+	decoders := NewDecoderRegistry()
+	decoders.Register("application/vnd.message+json", DecoderFunc(func(_ *http.Request, data []byte, v any) error {
+		return json.Unmarshal(data, &v.(*postRequest).Message) //nolint:forcetypeassert
+	}))
+
+	t.Run("UnsupportedMediaType", func(t *testing.T) {
+		t.Parallel()
+
+		mux := http.NewServeMux()
+		Mount(mux, &postEndpoint{}, &MountOpts{Logger: riversharedtest.Logger(t)})
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBufferString("Hello."))
+		req.Header.Set("Content-Type", "text/plain")
+		mux.ServeHTTP(recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusUnsupportedMediaType, &apierror.APIError{Code: apierror.CodeUnsupportedMediaType, Message: "Content-Type `text/plain` isn't supported. Supported types: `application/json`."}, recorder)
+		require.Equal(t, "application/json", recorder.Header().Get("Accept"))
+	})
+
+	t.Run("CustomDecoder", func(t *testing.T) {
+		t.Parallel()
+
+		mux := http.NewServeMux()
+		Mount(mux, &postEndpoint{RequestMediaTypes: []string{MediaTypeJSON, "application/vnd.message+json"}}, &MountOpts{Decoders: decoders, Logger: riversharedtest.Logger(t)})
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBufferString(`"Hello."`))
+		req.Header.Set("Content-Type", "application/vnd.message+json; charset=utf-8")
+		mux.ServeHTTP(recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusCreated, &postResponse{ID: "123", Message: "Hello.", RawPayload: []byte(`"Hello."`)}, recorder)
+	})
+
+	t.Run("UnregisteredMediaTypePanics", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, `error resolving request decoders for "POST /api/post-endpoint/{id}": no decoder registered for request media type "application/msgpack"`, func() {
+			Mount(http.NewServeMux(), &postEndpoint{RequestMediaTypes: []string{"application/msgpack"}}, nil)
+		})
+	})
+}
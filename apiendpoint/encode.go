@@ -0,0 +1,178 @@
+package apiendpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/internal/mediatype"
+)
+
+// Encoder encodes response bodies in a particular media type.
+type Encoder interface {
+	// ContentType is the Content-Type header written along with encoded
+	// responses, like `application/json; charset=utf-8`.
+	ContentType() string
+
+	// Encode encodes a response, which is a pointer to the endpoint's response
+	// struct.
+	//
+	// Encoders are also used to encode API errors for clients that accept the
+	// encoder's media type but not JSON, in which case v is the API error (or
+	// a map of its members if it's being written as problem details). An
+	// encoder that can't represent errors may return an error, in which case
+	// the API error is written as JSON instead.
+	Encode(v any) ([]byte, error)
+}
+
+// NewEncoder returns an encoder that encodes values with the given function
+// and writes them with the given Content-Type. Functions like json.Marshal
+// can be used directly:
+//
+//	encoders.Register("application/msgpack", apiendpoint.NewEncoder("application/msgpack", msgpack.Marshal))
+func NewEncoder(contentType string, encode func(v any) ([]byte, error)) Encoder {
+	return &funcEncoder{contentType: contentType, encode: encode}
+}
+
+type funcEncoder struct {
+	contentType string
+	encode      func(v any) ([]byte, error)
+}
+
+func (e *funcEncoder) ContentType() string          { return e.contentType }
+func (e *funcEncoder) Encode(v any) ([]byte, error) { return e.encode(v) }
+
+// EncoderRegistry is a set of response encoders keyed by media type, like
+// `application/json`. Endpoints opt into encoders by listing their media types
+// in EndpointMeta.ResponseMediaTypes, and a registry is given to Mount with
+// MountOpts.Encoders.
+//
+//	encoders := apiendpoint.NewEncoderRegistry()
+//	encoders.Register("application/cbor", apiendpoint.NewEncoder("application/cbor", cbor.Marshal))
+type EncoderRegistry struct {
+	encoders map[string]Encoder
+	mu       sync.RWMutex
+}
+
+// NewEncoderRegistry initializes a new encoder registry containing an encoder
+// for MediaTypeJSON.
+func NewEncoderRegistry() *EncoderRegistry {
+	registry := &EncoderRegistry{encoders: make(map[string]Encoder)}
+	registry.Register(MediaTypeJSON, NewEncoder("application/json; charset=utf-8", json.Marshal))
+	return registry
+}
+
+// Lookup returns the encoder registered for the given media type, or false if
+// there isn't one.
+func (r *EncoderRegistry) Lookup(mediaType string) (Encoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	encoder, ok := r.encoders[strings.ToLower(mediaType)]
+	return encoder, ok
+}
+
+// MediaTypes returns the media types of every registered encoder, sorted.
+func (r *EncoderRegistry) MediaTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(r.encoders))
+}
+
+// Register registers an encoder for a media type like `text/csv`, replacing
+// any encoder already registered for it. Media types are matched against
+// Accept headers case-insensitively.
+func (r *EncoderRegistry) Register(mediaType string, encoder Encoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.encoders[strings.ToLower(mediaType)] = encoder
+}
+
+// defaultEncoders is the encoder registry used by endpoints that are mounted
+// without MountOpts.Encoders.
+var defaultEncoders = NewEncoderRegistry() //nolint:gochecknoglobals
+
+// responseEncoders is the set of encoders that an endpoint can respond with,
+// in order of the endpoint's preference.
+type responseEncoders struct {
+	encoders   map[string]Encoder
+	mediaTypes []string
+}
+
+// resolveEncoders returns the encoders for the media types that an endpoint
+// responds with.
+func resolveEncoders(registry *EncoderRegistry, meta *EndpointMeta) (*responseEncoders, error) {
+	mediaTypes := meta.ResponseMediaTypes
+	if len(mediaTypes) < 1 {
+		mediaTypes = []string{MediaTypeJSON}
+	}
+
+	resolved := &responseEncoders{
+		encoders:   make(map[string]Encoder, len(mediaTypes)),
+		mediaTypes: make([]string, 0, len(mediaTypes)),
+	}
+
+	for _, mediaType := range mediaTypes {
+		encoder, ok := registry.Lookup(mediaType)
+		if !ok {
+			return nil, fmt.Errorf("no encoder registered for response media type %q", mediaType)
+		}
+
+		mediaType = strings.ToLower(mediaType)
+		resolved.encoders[mediaType] = encoder
+		resolved.mediaTypes = append(resolved.mediaTypes, mediaType)
+	}
+
+	return resolved, nil
+}
+
+// negotiate returns the encoder for the media type that's most preferred by a
+// request's Accept header, or a NotAcceptable API error if the endpoint can't
+// respond with any media type that the request accepts.
+func (e *responseEncoders) negotiate(r *http.Request) (Encoder, error) {
+	accept := r.Header.Get("Accept")
+
+	if mediaType, ok := mediatype.Negotiate(accept, e.mediaTypes); ok {
+		return e.encoders[mediaType], nil
+	}
+
+	return nil, notAcceptableError(accept, e.mediaTypes)
+}
+
+// notAcceptableError returns the API error for a request whose Accept header
+// doesn't accept any of the media types that an endpoint can produce.
+func notAcceptableError(accept string, mediaTypes []string) error {
+	return apierror.WithCode(
+		apierror.NewNotAcceptablef("None of the media types in the Accept header (`%s`) can be produced. Available types: `%s`.", accept, strings.Join(mediaTypes, "`, `")),
+		apierror.CodeNotAcceptable,
+	)
+}
+
+// withErrorEncoder returns a context that makes API errors use one of the
+// endpoint's encoders if the request prefers its media type over JSON, so
+// that a client that only accepts something like MessagePack gets errors it
+// can decode. JSON wins ties, and is used if the request accepts nothing the
+// endpoint can produce.
+func (e *responseEncoders) withErrorEncoder(ctx context.Context, r *http.Request) context.Context {
+	offers := []string{MediaTypeJSON, apierror.ContentTypeProblemDetails}
+	for _, mediaType := range e.mediaTypes {
+		if mediaType != MediaTypeJSON {
+			offers = append(offers, mediaType)
+		}
+	}
+
+	mediaType, ok := mediatype.Negotiate(r.Header.Get("Accept"), offers)
+	if !ok || mediaType == MediaTypeJSON || mediaType == apierror.ContentTypeProblemDetails {
+		return ctx
+	}
+
+	return apierror.WithEncoder(ctx, e.encoders[mediaType])
+}
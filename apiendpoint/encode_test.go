@@ -0,0 +1,195 @@
+package apiendpoint
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestEncoderRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := NewEncoderRegistry()
+
+	encoder, ok := registry.Lookup(MediaTypeJSON)
+	require.True(t, ok)
+	require.Equal(t, "application/json; charset=utf-8", encoder.ContentType())
+
+	data, err := encoder.Encode(&getResponse{Message: "Hello."})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"message":"Hello."}`, string(data))
+
+	_, ok = registry.Lookup("text/csv")
+	require.False(t, ok)
+
+	registry.Register("Text/CSV", NewEncoder("text/csv", func(_ any) ([]byte, error) { return nil, nil }))
+
+	_, ok = registry.Lookup("text/csv")
+	require.True(t, ok)
+
+	require.Equal(t, []string{MediaTypeJSON, "text/csv"}, registry.MediaTypes())
+}
+
+func TestMountEncoders(t *testing.T) {
+	t.Parallel()
+
+	// Encodes a response as its plain text message, and API errors the same
+	// way with a prefix.
+	encoders := NewEncoderRegistry()
+	encoders.Register("text/plain", NewEncoder("text/plain; charset=utf-8", func(v any) ([]byte, error) {
+		switch v := v.(type) {
+		case *postResponse:
+			return []byte(v.Message), nil
+		case apierror.Interface:
+			return []byte("error: " + v.Error()), nil
+		}
+		return nil, errors.New("can't encode value as plain text")
+	}))
+
+	type testBundle struct {
+		mux *http.ServeMux
+	}
+
+	setup := func(t *testing.T) *testBundle {
+		t.Helper()
+
+		mux := http.NewServeMux()
+		Mount(mux, &postEndpoint{ResponseMediaTypes: []string{MediaTypeJSON, "text/plain"}}, &MountOpts{Encoders: encoders, Logger: riversharedtest.Logger(t)})
+
+		return &testBundle{mux: mux}
+	}
+
+	serve := func(bundle *testBundle, accept, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/post-endpoint/123", bytes.NewBufferString(body))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		bundle.mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("NegotiatesMediaType", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t)
+
+		recorder := serve(bundle, "application/json;q=0.5, text/plain", `{"message":"Hello."}`)
+		requireStatusAndResponse(t, http.StatusCreated, "Hello.", recorder)
+		require.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+		require.Equal(t, "Accept", recorder.Header().Get("Vary"))
+	})
+
+	t.Run("MissingAcceptUsesFirstMediaType", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t)
+
+		recorder := serve(bundle, "", `{"message":"Hello."}`)
+		requireStatusAndJSONResponse(t, http.StatusCreated, &postResponse{ID: "123", Message: "Hello.", RawPayload: []byte(`{"message":"Hello."}`)}, recorder)
+	})
+
+	t.Run("NotAcceptable", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t)
+
+		recorder := serve(bundle, "application/xml", `{"message":"Hello."}`)
+		requireStatusAndJSONResponse(t, http.StatusNotAcceptable, &apierror.APIError{Code: apierror.CodeNotAcceptable, Message: "None of the media types in the Accept header (`application/xml`) can be produced. Available types: `application/json`, `text/plain`."}, recorder)
+		require.Equal(t, "Accept", recorder.Header().Get("Vary"))
+	})
+
+	t.Run("NotAcceptableWithDefaultEncoders", func(t *testing.T) {
+		t.Parallel()
+
+		mux := http.NewServeMux()
+		Mount(mux, &getEndpoint{}, &MountOpts{Logger: riversharedtest.Logger(t)})
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/get-endpoint", nil)
+		req.Header.Set("Accept", "text/html")
+		mux.ServeHTTP(recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusNotAcceptable, &apierror.APIError{Code: apierror.CodeNotAcceptable, Message: "None of the media types in the Accept header (`text/html`) can be produced. Available types: `application/json`."}, recorder)
+		require.Empty(t, recorder.Header().Get("Vary"))
+	})
+
+	t.Run("ErrorInAcceptedMediaType", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t)
+
+		recorder := serve(bundle, "text/plain", `{"make_api_error":true,"message":"Hello."}`)
+		requireStatusAndResponse(t, http.StatusBadRequest, "error: Bad request.", recorder)
+		require.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("ErrorPrefersJSON", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t)
+
+		recorder := serve(bundle, "text/plain, application/json", `{"make_api_error":true,"message":"Hello."}`)
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Message: "Bad request."}, recorder)
+	})
+
+	t.Run("RawResponderNotNegotiated", func(t *testing.T) {
+		t.Parallel()
+
+		mux := http.NewServeMux()
+		Mount(mux, &rawResponseEndpoint{}, &MountOpts{Logger: riversharedtest.Logger(t)})
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/raw-response-endpoint", nil)
+		req.Header.Set("Accept", "text/html")
+		mux.ServeHTTP(recorder, req)
+
+		requireStatusAndResponse(t, http.StatusOK, "<p>Hello.</p>", recorder)
+	})
+
+	t.Run("UnregisteredMediaTypePanics", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, `error resolving response encoders for "POST /api/post-endpoint/{id}": no encoder registered for response media type "application/msgpack"`, func() {
+			Mount(http.NewServeMux(), &postEndpoint{ResponseMediaTypes: []string{"application/msgpack"}}, nil)
+		})
+	})
+}
+
+//
+// rawResponseEndpoint
+//
+
+type rawResponseEndpoint struct {
+	Endpoint[struct{}, rawResponse]
+}
+
+func (*rawResponseEndpoint) Meta() *EndpointMeta {
+	return &EndpointMeta{
+		Pattern:    "GET /api/raw-response-endpoint",
+		StatusCode: http.StatusOK,
+	}
+}
+
+type rawResponse struct {
+	HTML string
+}
+
+func (resp *rawResponse) RespondRaw(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte(resp.HTML))
+	return err
+}
+
+func (*rawResponseEndpoint) Execute(_ context.Context, _ *struct{}) (*rawResponse, error) {
+	return &rawResponse{HTML: "<p>Hello.</p>"}, nil
+}
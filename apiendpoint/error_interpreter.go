@@ -0,0 +1,67 @@
+package apiendpoint
+
+import (
+	"context"
+	"errors"
+
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+// ErrorInterpreter converts errors returned while executing an endpoint into
+// API errors so that they're reported to the user as something more useful
+// than an internal server error. Interpreters are configured with
+// MountOpts.ErrorInterpreters.
+//
+// Errors from libraries like database drivers usually don't know anything
+// about HTTP, and an interpreter is the place to map them, along with any
+// domain-specific errors that an application wants to give a particular API
+// error without having to convert them in every endpoint.
+type ErrorInterpreter interface {
+	// InterpretError returns an API error for the given error, or nil if the
+	// interpreter doesn't know anything about it, in which case it's passed to
+	// the next interpreter in the chain.
+	//
+	// If the returned API error doesn't have an internal error set, the
+	// original error is set as its internal error so that it's logged. A new
+	// API error should be returned for each invocation because of this.
+	InterpretError(ctx context.Context, err error) apierror.Interface
+}
+
+// ErrorInterpreterFunc is a function that implements ErrorInterpreter.
+//
+//	apiendpoint.ErrorInterpreterFunc(func(ctx context.Context, err error) apierror.Interface {
+//		if errors.Is(err, ErrAccountSuspended) {
+//			return apierror.NewForbidden("Account is suspended.")
+//		}
+//		return nil
+//	})
+type ErrorInterpreterFunc func(ctx context.Context, err error) apierror.Interface
+
+func (f ErrorInterpreterFunc) InterpretError(ctx context.Context, err error) apierror.Interface {
+	return f(ctx, err)
+}
+
+// interpretError runs an error through a chain of interpreters, returning the
+// API error from the first one that recognizes it. Errors that are already API
+// errors are returned as is, as are errors that no interpreter recognizes.
+func interpretError(ctx context.Context, interpreters []ErrorInterpreter, err error) error {
+	var apiErr apierror.Interface
+	if errors.As(err, &apiErr) {
+		return err
+	}
+
+	for _, interpreter := range interpreters {
+		apiErr := interpreter.InterpretError(ctx, err)
+		if apiErr == nil {
+			continue
+		}
+
+		if apiErr.GetInternalError() == nil {
+			apiErr.SetInternalError(err)
+		}
+
+		return apiErr
+	}
+
+	return err
+}
@@ -0,0 +1,74 @@
+package apiendpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+func TestInterpretError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var (
+		errFirst  = errors.New("first")
+		errSecond = errors.New("second")
+	)
+
+	interpreters := []ErrorInterpreter{
+		ErrorInterpreterFunc(func(_ context.Context, err error) apierror.Interface {
+			if errors.Is(err, errFirst) {
+				return apierror.NewConflict("First.")
+			}
+			return nil
+		}),
+		ErrorInterpreterFunc(func(_ context.Context, err error) apierror.Interface {
+			switch {
+			case errors.Is(err, errFirst):
+				return apierror.NewGone("Shadowed by first interpreter.")
+			case errors.Is(err, errSecond):
+				return apierror.WithInternalError(apierror.NewForbidden("Second."), errors.New("custom internal error"))
+			}
+			return nil
+		}),
+	}
+
+	t.Run("FirstMatchWins", func(t *testing.T) {
+		t.Parallel()
+
+		err := fmt.Errorf("wrapped: %w", errFirst)
+		require.Equal(t, apierror.WithInternalError(apierror.NewConflict("First."), err), interpretError(ctx, interpreters, err))
+	})
+
+	t.Run("InternalErrorPreserved", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, apierror.WithInternalError(apierror.NewForbidden("Second."), errors.New("custom internal error")), interpretError(ctx, interpreters, errSecond))
+	})
+
+	t.Run("APIErrorUnchanged", func(t *testing.T) {
+		t.Parallel()
+
+		err := apierror.NewNotFound("Not found.")
+		require.Equal(t, err, interpretError(ctx, interpreters, err))
+	})
+
+	t.Run("NotInterpreted", func(t *testing.T) {
+		t.Parallel()
+
+		err := errors.New("other error")
+		require.Equal(t, err, interpretError(ctx, interpreters, err))
+	})
+
+	t.Run("NoInterpreters", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, errFirst, interpretError(ctx, nil, errFirst))
+	})
+}
@@ -0,0 +1,519 @@
+package apiendpoint
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/internal/mediatype"
+)
+
+// Media types of HTML form request bodies. Decoders for both are registered
+// by NewDecoderRegistry, so endpoints only need to list them in
+// EndpointMeta.RequestMediaTypes to accept forms.
+const (
+	MediaTypeForm          = "application/x-www-form-urlencoded"
+	MediaTypeMultipartForm = "multipart/form-data"
+)
+
+// DefaultMultipartMaxMemory is the default maximum number of bytes of a
+// multipart request body's files that are kept in memory, with the rest being
+// stored in temporary files on disk. It's the same as net/http's default.
+const DefaultMultipartMaxMemory = 32 << 20 // 32 MB
+
+// tagForm binds a field from a form field in a request body that's either
+// `application/x-www-form-urlencoded` or `multipart/form-data`, like
+// `form:"name"`. Slice fields get every value of a repeated form field.
+//
+// In multipart bodies, files are bound to fields of type *File or []*File.
+// File fields accept options that limit the files they'll take:
+//
+//	Avatar *apiendpoint.File `form:"avatar,maxsize=5MB,accept=image/png|image/jpeg" json:"-"`
+//
+// `maxsize` is a number of bytes with an optional `KB`, `MB`, or `GB` suffix
+// (each 1024 times the last), and `accept` is a `|`-separated list of media
+// types that may include wildcards like `image/*`. A file that's too large is
+// rejected with a RequestEntityTooLarge, and one of a type that isn't accepted
+// with an UnsupportedMediaType.
+const tagForm = "form"
+
+// File is a file uploaded as part of a `multipart/form-data` request body,
+// bound to a request struct field with a `form` tag. Its contents are stored
+// in memory or in a temporary file depending on its size (see
+// MultipartDecoderOpts.MaxMemory), and are only available until the endpoint
+// finishes executing.
+type File struct {
+	// ContentType is the file's media type as sent by the client, like
+	// `image/png`, or `application/octet-stream` if it didn't send one.
+	ContentType string
+
+	// Filename is the file's name as sent by the client. It shouldn't be
+	// trusted as a path on disk.
+	Filename string
+
+	// Header is the MIME header of the file's part of the body.
+	Header textproto.MIMEHeader
+
+	// Size is the size of the file in bytes.
+	Size int64
+
+	fileHeader *multipart.FileHeader
+}
+
+// Open opens the file for reading. It should be closed when done.
+func (f *File) Open() (multipart.File, error) {
+	return f.fileHeader.Open()
+}
+
+var (
+	fileType      = reflect.TypeFor[*File]()   //nolint:gochecknoglobals
+	fileSliceType = reflect.TypeFor[[]*File]() //nolint:gochecknoglobals
+)
+
+// formBinding is a single field on a request struct that's bound from a form
+// field with a `form` tag.
+type formBinding struct {
+	// accept are the media types accepted for a file field, from its `accept`
+	// option. Empty accepts any media type.
+	accept []string
+
+	// index is the field's index path, as accepted by reflect.Value's
+	// FieldByIndex.
+	index []int
+
+	// isFile is true if the field is a *File or []*File.
+	isFile bool
+
+	// maxBytes is the maximum size of a file bound to a file field, from its
+	// `maxsize` option. Zero means that there's no limit other than the
+	// decoder's.
+	maxBytes int64
+
+	// name is the name of the form field.
+	name string
+}
+
+// formBindingsCache caches the results of formBindings by type.
+var formBindingsCache sync.Map //nolint:gochecknoglobals
+
+// formBindings returns the fields of the given type that are bound with a
+// `form` tag, or an error if a tag is misconfigured. Types that aren't structs
+// have no bindings.
+func formBindings(typ reflect.Type) ([]formBinding, error) {
+	if cached, ok := formBindingsCache.Load(typ); ok {
+		return cached.([]formBinding), nil //nolint:forcetypeassert
+	}
+
+	var bindings []formBinding
+	if typ.Kind() == reflect.Struct {
+		var err error
+		if bindings, err = appendFormBindings(nil, typ, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	formBindingsCache.Store(typ, bindings)
+	return bindings, nil
+}
+
+func appendFormBindings(bindings []formBinding, typ reflect.Type, parentIndex []int) ([]formBinding, error) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		index := append(append([]int{}, parentIndex...), i)
+
+		tag, ok := field.Tag.Lookup(tagForm)
+		if !ok || tag == "-" {
+			// Look into embedded structs for promoted fields.
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				var err error
+				if bindings, err = appendFormBindings(bindings, field.Type, index); err != nil {
+					return nil, err
+				}
+			}
+
+			continue
+		}
+
+		if !field.IsExported() {
+			return nil, fmt.Errorf("field %s.%s has a `form` tag, but isn't exported", typ.Name(), field.Name)
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			return nil, fmt.Errorf("field %s.%s has an empty `form` tag", typ.Name(), field.Name)
+		}
+
+		binding := formBinding{
+			index:  index,
+			isFile: field.Type == fileType || field.Type == fileSliceType,
+			name:   name,
+		}
+
+		if !binding.isFile && !isBindableType(field.Type, true) {
+			return nil, fmt.Errorf("field %s.%s has a `form` tag, but its type %s can't be bound", typ.Name(), field.Name, field.Type)
+		}
+
+		if opts != "" {
+			if !binding.isFile {
+				return nil, fmt.Errorf("field %s.%s has `form` tag options, but they're only allowed on *File and []*File fields", typ.Name(), field.Name)
+			}
+
+			for opt := range strings.SplitSeq(opts, ",") {
+				key, value, _ := strings.Cut(opt, "=")
+
+				switch key {
+				case "accept":
+					binding.accept = strings.Split(value, "|")
+
+				case "maxsize":
+					maxBytes, err := parseByteSize(value)
+					if err != nil {
+						return nil, fmt.Errorf("field %s.%s has an invalid `maxsize` option: %w", typ.Name(), field.Name, err)
+					}
+					binding.maxBytes = maxBytes
+
+				default:
+					return nil, fmt.Errorf("field %s.%s has an unknown `form` tag option %q", typ.Name(), field.Name, key)
+				}
+			}
+		}
+
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, nil
+}
+
+// bindForm binds form values and files into the form-bound fields of req,
+// which should be a pointer to a struct. files may be nil for bodies that
+// can't contain files.
+func bindForm(bindings []formBinding, values url.Values, files map[string][]*multipart.FileHeader, req any) error {
+	reqValue := reflect.ValueOf(req).Elem()
+
+	for _, binding := range bindings {
+		field := reqValue.FieldByIndex(binding.index)
+
+		if binding.isFile {
+			if err := bindFormFiles(binding, field, files[binding.name]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		formValues := values[binding.name]
+
+		// Unlike query parameters, repeated form fields aren't also split on
+		// commas because a single value like a text area may contain them.
+		if field.Kind() == reflect.Slice && !reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+			if len(formValues) < 1 {
+				continue
+			}
+
+			slice := reflect.MakeSlice(field.Type(), len(formValues), len(formValues))
+			for i, value := range formValues {
+				if err := setFieldFromString(slice.Index(i), value); err != nil {
+					return formFieldError(binding.name, err)
+				}
+			}
+
+			field.Set(slice)
+			continue
+		}
+
+		if err := setFieldFromStrings(field, formValues); err != nil {
+			return formFieldError(binding.name, err)
+		}
+	}
+
+	return nil
+}
+
+// bindFormFiles binds uploaded files into a *File or []*File field, checking
+// them against the field's accepted media types. Sizes are checked while the
+// body is read (see multipartDecoder.copyParts).
+func bindFormFiles(binding formBinding, field reflect.Value, fileHeaders []*multipart.FileHeader) error {
+	if len(fileHeaders) < 1 {
+		return nil
+	}
+
+	files := make([]*File, len(fileHeaders))
+	for i, fileHeader := range fileHeaders {
+		contentType := fileHeader.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		if len(binding.accept) > 0 {
+			mediaType, _, err := mime.ParseMediaType(contentType)
+			if _, ok := mediatype.Negotiate(strings.Join(binding.accept, ","), []string{mediaType}); err != nil || !ok {
+				return apierror.WithCode(
+					apierror.NewUnsupportedMediaTypef("File `%s` has unsupported content type `%s`. Supported types: `%s`.", binding.name, contentType, strings.Join(binding.accept, "`, `")).WithAccept(binding.accept...),
+					apierror.CodeUnsupportedMediaType,
+				)
+			}
+		}
+
+		files[i] = &File{
+			ContentType: contentType,
+			Filename:    fileHeader.Filename,
+			Header:      fileHeader.Header,
+			Size:        fileHeader.Size,
+			fileHeader:  fileHeader,
+		}
+	}
+
+	if field.Type() == fileType {
+		field.Set(reflect.ValueOf(files[0]))
+	} else {
+		field.Set(reflect.ValueOf(files))
+	}
+
+	return nil
+}
+
+func formFieldError(name string, err error) error {
+	return apierror.WithCode(apierror.NewBadRequestf("Form field `%s` %s.", name, err), apierror.CodeInvalidBody)
+}
+
+// formDecoder decodes `application/x-www-form-urlencoded` request bodies into
+// fields with `form` tags.
+type formDecoder struct{}
+
+func (d *formDecoder) Decode(_ *http.Request, data []byte, v any) error {
+	bindings, err := formBindings(reflect.TypeOf(v).Elem())
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return apierror.WithCode(apierror.NewBadRequestf("Error parsing form request body: %s.", err), apierror.CodeInvalidBody)
+	}
+
+	return bindForm(bindings, values, nil, v)
+}
+
+// MultipartDecoderOpts are options for NewMultipartDecoder.
+type MultipartDecoderOpts struct {
+	// MaxFileBytes is the maximum size of any single uploaded file, which may
+	// be lowered for a specific field with a `maxsize` option on its `form`
+	// tag. If not specified, files are only limited by the size limit of the
+	// request body as a whole.
+	MaxFileBytes int64
+
+	// MaxMemory is the maximum number of bytes of uploaded files that are
+	// kept in memory. Beyond it, files are streamed to temporary files on
+	// disk, which are removed after the endpoint finishes executing. If not
+	// specified, DefaultMultipartMaxMemory is used.
+	MaxMemory int64
+}
+
+// NewMultipartDecoder returns a decoder for `multipart/form-data` request
+// bodies, which binds form fields and uploaded files into fields with `form`
+// tags. NewDecoderRegistry registers one with default options, and it can be
+// replaced to change them:
+//
+//	decoders := apiendpoint.NewDecoderRegistry()
+//	decoders.Register(apiendpoint.MediaTypeMultipartForm, apiendpoint.NewMultipartDecoder(&apiendpoint.MultipartDecoderOpts{
+//		MaxFileBytes: 10 << 20, // 10 MB
+//	}))
+func NewMultipartDecoder(opts *MultipartDecoderOpts) StreamDecoder {
+	if opts == nil {
+		opts = &MultipartDecoderOpts{}
+	}
+
+	maxMemory := opts.MaxMemory
+	if maxMemory == 0 {
+		maxMemory = DefaultMultipartMaxMemory
+	}
+
+	return &multipartDecoder{maxFileBytes: opts.MaxFileBytes, maxMemory: maxMemory}
+}
+
+type multipartDecoder struct {
+	maxFileBytes int64
+	maxMemory    int64
+}
+
+func (d *multipartDecoder) Decode(r *http.Request, data []byte, v any) error {
+	return d.decode(r, bytes.NewReader(data), v)
+}
+
+func (d *multipartDecoder) DecodeStream(r *http.Request, v any) error {
+	return d.decode(r, r.Body, v)
+}
+
+func (d *multipartDecoder) decode(r *http.Request, body io.Reader, v any) error {
+	bindings, err := formBindings(reflect.TypeOf(v).Elem())
+	if err != nil {
+		return err
+	}
+
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		return apierror.WithCode(apierror.NewBadRequest("Multipart request body is missing a boundary in its Content-Type."), apierror.CodeInvalidBody)
+	}
+
+	form, err := d.readForm(body, params["boundary"], bindings)
+	if err != nil {
+		var (
+			apiErr      apierror.Interface
+			maxBytesErr *http.MaxBytesError
+		)
+		switch {
+		case errors.As(err, &apiErr):
+			return err
+		case errors.As(err, &maxBytesErr):
+			return requestEntityTooLargeError(maxBytesErr.Limit)
+		case errors.Is(err, multipart.ErrMessageTooLarge):
+			return requestEntityTooLargeError(0)
+		}
+
+		return apierror.WithCode(apierror.NewBadRequestf("Error parsing multipart request body: %s.", err), apierror.CodeInvalidBody)
+	}
+
+	// Temporary files are removed once the endpoint is done with them. See
+	// executeAPIEndpoint.
+	r.MultipartForm = form
+
+	return bindForm(bindings, form.Value, form.File, v)
+}
+
+// readForm reads a multipart body into a form like multipart.Reader.ReadForm,
+// but with file size limits checked as each file is streamed in rather than
+// after it's already been read into memory or to disk. Parts are copied from
+// body through a pipe into ReadForm, stopping at the first file that's too
+// large, so ReadForm's handling of memory and temporary files is kept.
+func (d *multipartDecoder) readForm(body io.Reader, boundary string, bindings []formBinding) (*multipart.Form, error) {
+	var (
+		pipeReader, pipeWriter = io.Pipe()
+		reader                 = multipart.NewReader(body, boundary)
+		wg                     sync.WaitGroup
+		writer                 = multipart.NewWriter(pipeWriter)
+	)
+
+	wg.Go(func() {
+		pipeWriter.CloseWithError(d.copyParts(reader, writer, bindings))
+	})
+
+	form, err := multipart.NewReader(pipeReader, writer.Boundary()).ReadForm(d.maxMemory)
+
+	// Unblock the copy if ReadForm stopped early.
+	pipeReader.CloseWithError(io.ErrClosedPipe)
+	wg.Wait()
+
+	return form, err
+}
+
+// copyParts copies every part of a multipart body from reader to writer,
+// failing with a RequestEntityTooLarge as soon as a file exceeds its size
+// limit.
+func (d *multipartDecoder) copyParts(reader *multipart.Reader, writer *multipart.Writer, bindings []formBinding) error {
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return writer.Close()
+		}
+		if err != nil {
+			return err
+		}
+
+		partWriter, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+
+		maxBytes := d.maxFileBytesFor(part.FormName(), bindings)
+		if part.FileName() == "" || maxBytes <= 0 {
+			if _, err := io.Copy(partWriter, part); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Copy one byte more than the limit so that a file that's exactly
+		// at it isn't mistaken for one that's over it.
+		written, err := io.Copy(partWriter, io.LimitReader(part, maxBytes+1))
+		if err != nil {
+			return err
+		}
+		if written > maxBytes {
+			return apierror.WithCode(
+				apierror.NewRequestEntityTooLargef("File `%s` is larger than the maximum size of %s.", part.FormName(), formatByteSize(maxBytes)),
+				apierror.CodeRequestEntityTooLarge,
+			)
+		}
+	}
+}
+
+// maxFileBytesFor returns the maximum size of a file uploaded in the named
+// form field, which is the lower of the decoder's MaxFileBytes and the
+// field's `maxsize` option. Zero means that there's no limit.
+func (d *multipartDecoder) maxFileBytesFor(name string, bindings []formBinding) int64 {
+	maxBytes := d.maxFileBytes
+
+	for _, binding := range bindings {
+		if binding.name == name && binding.maxBytes > 0 && (maxBytes <= 0 || binding.maxBytes < maxBytes) {
+			maxBytes = binding.maxBytes
+		}
+	}
+
+	return maxBytes
+}
+
+// byteSizeUnits are the suffixes accepted by parseByteSize and produced by
+// formatByteSize, largest first.
+var byteSizeUnits = []struct { //nolint:gochecknoglobals
+	suffix string
+	size   int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+}
+
+// parseByteSize parses a size like `512`, `64KB`, or `5MB` into a number of
+// bytes.
+func parseByteSize(s string) (int64, error) {
+	var (
+		multiplier = int64(1)
+		number     = s
+	)
+
+	for _, unit := range byteSizeUnits {
+		if trimmed, ok := strings.CutSuffix(s, unit.suffix); ok {
+			number, multiplier = trimmed, unit.size
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("%q isn't a positive number of bytes like `512`, `64KB`, or `5MB`", s)
+	}
+
+	return size * multiplier, nil
+}
+
+// formatByteSize formats a number of bytes for use in a message, using the
+// largest unit that divides it evenly, like `5 MB` or `1500 bytes`.
+func formatByteSize(size int64) string {
+	for _, unit := range byteSizeUnits {
+		if size >= unit.size && size%unit.size == 0 {
+			return fmt.Sprintf("%d %s", size/unit.size, unit.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%d bytes", size)
+}
@@ -0,0 +1,476 @@
+package apiendpoint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestFormBindings(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Bindings", func(t *testing.T) {
+		t.Parallel()
+
+		type Embedded struct {
+			Note string `form:"note"`
+		}
+
+		type testRequest struct {
+			Embedded
+
+			Avatar  *File    `form:"avatar,maxsize=5MB,accept=image/png|image/jpeg"`
+			Files   []*File  `form:"file"`
+			Ignored string   `form:"-"`
+			Name    string   `form:"name"`
+			Tags    []string `form:"tag"`
+		}
+
+		bindings, err := formBindings(reflect.TypeFor[testRequest]())
+		require.NoError(t, err)
+		require.Equal(t, []formBinding{
+			{index: []int{0, 0}, name: "note"},
+			{accept: []string{"image/png", "image/jpeg"}, index: []int{1}, isFile: true, maxBytes: 5 << 20, name: "avatar"},
+			{index: []int{2}, isFile: true, name: "file"},
+			{index: []int{4}, name: "name"},
+			{index: []int{5}, name: "tag"},
+		}, bindings)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+
+		requireBindingError := func(t *testing.T, typ reflect.Type, expectedErr string) {
+			t.Helper()
+
+			_, err := formBindings(typ)
+			require.EqualError(t, err, expectedErr)
+		}
+
+		type emptyTag struct {
+			Name string `form:""`
+		}
+		requireBindingError(t, reflect.TypeFor[emptyTag](), "field emptyTag.Name has an empty `form` tag")
+
+		type unexported struct {
+			name string `form:"name"`
+		}
+		requireBindingError(t, reflect.TypeFor[unexported](), "field unexported.name has a `form` tag, but isn't exported")
+
+		type unbindable struct {
+			Data map[string]string `form:"data"`
+		}
+		requireBindingError(t, reflect.TypeFor[unbindable](), "field unbindable.Data has a `form` tag, but its type map[string]string can't be bound")
+
+		type optionsOnNonFile struct {
+			Name string `form:"name,maxsize=5MB"`
+		}
+		requireBindingError(t, reflect.TypeFor[optionsOnNonFile](), "field optionsOnNonFile.Name has `form` tag options, but they're only allowed on *File and []*File fields")
+
+		type invalidMaxSize struct {
+			Avatar *File `form:"avatar,maxsize=big"`
+		}
+		requireBindingError(t, reflect.TypeFor[invalidMaxSize](), "field invalidMaxSize.Avatar has an invalid `maxsize` option: \"big\" isn't a positive number of bytes like `512`, `64KB`, or `5MB`")
+
+		type unknownOption struct {
+			Avatar *File `form:"avatar,minsize=5MB"`
+		}
+		requireBindingError(t, reflect.TypeFor[unknownOption](), "field unknownOption.Avatar has an unknown `form` tag option \"minsize\"")
+	})
+}
+
+func TestParseAndFormatByteSize(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		s    string
+		size int64
+	}{
+		{"512", 512},
+		{"64KB", 64 << 10},
+		{"5MB", 5 << 20},
+		{"2GB", 2 << 30},
+	} {
+		size, err := parseByteSize(tt.s)
+		require.NoError(t, err)
+		require.Equal(t, tt.size, size)
+	}
+
+	for _, s := range []string{"", "0", "-1KB", "5 MB", "5TB"} {
+		_, err := parseByteSize(s)
+		require.Error(t, err, "Expected error for %q", s)
+	}
+
+	require.Equal(t, "512 bytes", formatByteSize(512))
+	require.Equal(t, "1500 bytes", formatByteSize(1500))
+	require.Equal(t, "64 KB", formatByteSize(64<<10))
+	require.Equal(t, "5 MB", formatByteSize(5<<20))
+	require.Equal(t, "2 GB", formatByteSize(2<<30))
+}
+
+func TestMountForms(t *testing.T) {
+	t.Parallel()
+
+	type testBundle struct {
+		mux *http.ServeMux
+	}
+
+	setup := func(t *testing.T, opts *MountOpts) *testBundle {
+		t.Helper()
+
+		if opts == nil {
+			opts = &MountOpts{}
+		}
+		opts.Logger = riversharedtest.Logger(t)
+
+		mux := http.NewServeMux()
+		Mount(mux, &uploadEndpoint{}, opts)
+
+		return &testBundle{mux: mux}
+	}
+
+	type formFile struct {
+		contentType string
+		data        string
+		filename    string
+		name        string
+	}
+
+	multipartBody := func(t *testing.T, values map[string][]string, files ...*formFile) (*bytes.Buffer, string) {
+		t.Helper()
+
+		var (
+			body   bytes.Buffer
+			writer = multipart.NewWriter(&body)
+		)
+
+		for name, fieldValues := range values {
+			for _, value := range fieldValues {
+				require.NoError(t, writer.WriteField(name, value))
+			}
+		}
+
+		for _, file := range files {
+			header := make(textproto.MIMEHeader)
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, file.name, file.filename))
+			if file.contentType != "" {
+				header.Set("Content-Type", file.contentType)
+			}
+
+			part, err := writer.CreatePart(header)
+			require.NoError(t, err)
+
+			_, err = part.Write([]byte(file.data))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, writer.Close())
+
+		return &body, writer.FormDataContentType()
+	}
+
+	serve := func(bundle *testBundle, contentType string, body io.Reader) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/upload-endpoint", body)
+		req.Header.Set("Content-Type", contentType)
+		bundle.mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("URLEncoded", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		recorder := serve(bundle, MediaTypeForm, strings.NewReader("name=Report&count=3&tag=a,b&tag=c"))
+		requireStatusAndJSONResponse(t, http.StatusOK, &uploadResponse{Count: 3, Name: "Report", Tags: []string{"a,b", "c"}}, recorder)
+	})
+
+	t.Run("URLEncodedInvalidValue", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		recorder := serve(bundle, MediaTypeForm, strings.NewReader("name=Report&count=three"))
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidBody, Message: "Form field `count` must be an integer."}, recorder)
+	})
+
+	t.Run("URLEncodedMalformed", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		recorder := serve(bundle, MediaTypeForm, strings.NewReader("name=%zz"))
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidBody, Message: `Error parsing form request body: invalid URL escape "%zz".`}, recorder)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		recorder := serve(bundle, MediaTypeForm, strings.NewReader("count=3"))
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{
+			Code:    apierror.CodeValidationFailed,
+			Errors:  []*apierror.FieldError{{Field: "name", JSONPath: "name", Message: "Field `name` is required.", Tag: "required"}},
+			Message: "Field `name` is required.",
+		}, recorder)
+	})
+
+	t.Run("Multipart", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		body, contentType := multipartBody(t, map[string][]string{"name": {"Report"}, "tag": {"a", "b"}},
+			&formFile{name: "avatar", filename: "avatar.png", contentType: "image/png", data: "PNG data"},
+			&formFile{name: "attachment", filename: "one.txt", data: "One."},
+			&formFile{name: "attachment", filename: "two.txt", data: "Two."},
+		)
+
+		recorder := serve(bundle, contentType, body)
+		requireStatusAndJSONResponse(t, http.StatusOK, &uploadResponse{
+			Attachments: []string{"one.txt (application/octet-stream): One.", "two.txt (application/octet-stream): Two."},
+			Avatar:      "avatar.png (image/png): PNG data",
+			Name:        "Report",
+			Tags:        []string{"a", "b"},
+		}, recorder)
+	})
+
+	t.Run("MultipartStoredOnDisk", func(t *testing.T) {
+		t.Parallel()
+
+		decoders := NewDecoderRegistry()
+		decoders.Register(MediaTypeMultipartForm, NewMultipartDecoder(&MultipartDecoderOpts{MaxMemory: 1}))
+
+		bundle := setup(t, &MountOpts{Decoders: decoders})
+
+		data := strings.Repeat("x", 1000)
+		body, contentType := multipartBody(t, map[string][]string{"name": {"Report"}},
+			&formFile{name: "attachment", filename: "big.txt", contentType: "text/plain", data: data},
+		)
+
+		recorder := serve(bundle, contentType, body)
+		requireStatusAndJSONResponse(t, http.StatusOK, &uploadResponse{
+			Attachments: []string{"big.txt (text/plain): " + data},
+			Name:        "Report",
+		}, recorder)
+	})
+
+	t.Run("MultipartFileTooLarge", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		body, contentType := multipartBody(t, map[string][]string{"name": {"Report"}},
+			&formFile{name: "avatar", filename: "avatar.png", contentType: "image/png", data: strings.Repeat("x", 1025)},
+		)
+
+		recorder := serve(bundle, contentType, body)
+		requireStatusAndJSONResponse(t, http.StatusRequestEntityTooLarge, &apierror.APIError{Code: apierror.CodeRequestEntityTooLarge, Message: "File `avatar` is larger than the maximum size of 1 KB."}, recorder)
+	})
+
+	t.Run("MultipartFileTooLargeStopsReading", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		// A 1 GB avatar that's generated as it's read. The request should be
+		// rejected shortly after the avatar's 1 KB limit rather than after
+		// the whole file has been read.
+		const boundary = "boundary"
+		body := &countingReader{reader: io.MultiReader(
+			strings.NewReader("--"+boundary+"\r\n"+
+				`Content-Disposition: form-data; name="avatar"; filename="avatar.png"`+"\r\n"+
+				"Content-Type: image/png\r\n\r\n"),
+			io.LimitReader(repeatReader('x'), 1<<30),
+			strings.NewReader("\r\n--"+boundary+"--\r\n"),
+		)}
+
+		recorder := serve(bundle, MediaTypeMultipartForm+"; boundary="+boundary, body)
+		requireStatusAndJSONResponse(t, http.StatusRequestEntityTooLarge, &apierror.APIError{Code: apierror.CodeRequestEntityTooLarge, Message: "File `avatar` is larger than the maximum size of 1 KB."}, recorder)
+		require.Less(t, body.read, int64(1<<20))
+	})
+
+	t.Run("MultipartMaxFileBytes", func(t *testing.T) {
+		t.Parallel()
+
+		decoders := NewDecoderRegistry()
+		decoders.Register(MediaTypeMultipartForm, NewMultipartDecoder(&MultipartDecoderOpts{MaxFileBytes: 10}))
+
+		bundle := setup(t, &MountOpts{Decoders: decoders})
+
+		body, contentType := multipartBody(t, map[string][]string{"name": {"Report"}},
+			&formFile{name: "attachment", filename: "big.txt", data: "More than ten bytes."},
+		)
+
+		recorder := serve(bundle, contentType, body)
+		requireStatusAndJSONResponse(t, http.StatusRequestEntityTooLarge, &apierror.APIError{Code: apierror.CodeRequestEntityTooLarge, Message: "File `attachment` is larger than the maximum size of 10 bytes."}, recorder)
+	})
+
+	t.Run("MultipartUnsupportedFileType", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		body, contentType := multipartBody(t, map[string][]string{"name": {"Report"}},
+			&formFile{name: "avatar", filename: "avatar.txt", contentType: "text/plain", data: "Not an image."},
+		)
+
+		recorder := serve(bundle, contentType, body)
+		requireStatusAndJSONResponse(t, http.StatusUnsupportedMediaType, &apierror.APIError{Code: apierror.CodeUnsupportedMediaType, Message: "File `avatar` has unsupported content type `text/plain`. Supported types: `image/*`."}, recorder)
+	})
+
+	t.Run("MultipartMissingBoundary", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		recorder := serve(bundle, MediaTypeMultipartForm, strings.NewReader("name=Report"))
+		requireStatusAndJSONResponse(t, http.StatusBadRequest, &apierror.APIError{Code: apierror.CodeInvalidBody, Message: "Multipart request body is missing a boundary in its Content-Type."}, recorder)
+	})
+
+	t.Run("MultipartMalformed", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, nil)
+
+		recorder := serve(bundle, MediaTypeMultipartForm+"; boundary=abc", strings.NewReader("--abc\r\nnot a valid part"))
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		require.Equal(t, apierror.CodeInvalidBody, mustUnmarshalJSON[apierror.APIError](t, recorder.Body.Bytes()).Code)
+	})
+
+	t.Run("InvalidBindingPanics", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, "error binding request struct for \"POST /api/invalid-form-endpoint\": field invalidFormRequest.Data has a `form` tag, but its type map[string]string can't be bound", func() {
+			Mount(http.NewServeMux(), &invalidFormEndpoint{}, nil)
+		})
+	})
+}
+
+//
+// uploadEndpoint
+//
+
+type uploadEndpoint struct {
+	Endpoint[uploadRequest, uploadResponse]
+}
+
+func (*uploadEndpoint) Meta() *EndpointMeta {
+	return &EndpointMeta{
+		Pattern:           "POST /api/upload-endpoint",
+		RequestMediaTypes: []string{MediaTypeForm, MediaTypeMultipartForm},
+		StatusCode:        http.StatusOK,
+	}
+}
+
+type uploadRequest struct {
+	Attachments []*File  `form:"attachment"                         json:"-"`
+	Avatar      *File    `form:"avatar,maxsize=1KB,accept=image/*" json:"-"`
+	Count       int      `form:"count"                              json:"-"`
+	Name        string   `form:"name"                               json:"-" validate:"required"`
+	Tags        []string `form:"tag"                                json:"-"`
+}
+
+type uploadResponse struct {
+	Attachments []string `json:"attachments"`
+	Avatar      string   `json:"avatar"`
+	Count       int      `json:"count"`
+	Name        string   `json:"name"`
+	Tags        []string `json:"tags"`
+}
+
+func (*uploadEndpoint) Execute(_ context.Context, req *uploadRequest) (*uploadResponse, error) {
+	describeFile := func(file *File) (string, error) {
+		f, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s (%s): %s", file.Filename, file.ContentType, data), nil
+	}
+
+	resp := &uploadResponse{Count: req.Count, Name: req.Name, Tags: req.Tags}
+
+	for _, attachment := range req.Attachments {
+		description, err := describeFile(attachment)
+		if err != nil {
+			return nil, err
+		}
+		resp.Attachments = append(resp.Attachments, description)
+	}
+
+	if req.Avatar != nil {
+		var err error
+		if resp.Avatar, err = describeFile(req.Avatar); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	read   int64
+	reader io.Reader
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// repeatReader is an endless reader of a single byte.
+type repeatReader byte
+
+func (r repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+//
+// invalidFormEndpoint
+//
+
+type invalidFormEndpoint struct {
+	Endpoint[invalidFormRequest, struct{}]
+}
+
+func (*invalidFormEndpoint) Meta() *EndpointMeta {
+	return &EndpointMeta{
+		Pattern:    "POST /api/invalid-form-endpoint",
+		StatusCode: http.StatusOK,
+	}
+}
+
+type invalidFormRequest struct {
+	Data map[string]string `form:"data"`
+}
+
+func (*invalidFormEndpoint) Execute(_ context.Context, _ *invalidFormRequest) (*struct{}, error) {
+	return &struct{}{}, nil
+}
@@ -0,0 +1,74 @@
+package apiendpoint
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// RequestIDHeader is the header that a request ID is read from when logging a
+// panic. It's usually set by a proxy or a middleware in front of the API.
+const RequestIDHeader = "X-Request-ID"
+
+// PanicInfo is information about a panic recovered while executing an
+// endpoint, passed to MountOpts.OnPanic.
+type PanicInfo struct {
+	// Pattern is the pattern of the endpoint that panicked, like
+	// `GET /jobs/{id}`.
+	Pattern string
+
+	// Recovered is the value that was passed to panic.
+	Recovered any
+
+	// Request is the request being served when the panic occurred.
+	Request *http.Request
+
+	// RequestID is the request's ID from its RequestIDHeader header, or empty
+	// if it didn't have one.
+	RequestID string
+
+	// Stack is the stack trace of the goroutine that panicked, as returned by
+	// runtime/debug.Stack.
+	Stack []byte
+}
+
+// panicError is returned in place of a normal error when an endpoint panics so
+// that the panic can be handled alongside other errors.
+type panicError struct {
+	recovered any
+	stack     []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.recovered)
+}
+
+// handlePanic logs a recovered panic and passes it along to the OnPanic hook
+// if one is configured.
+func handlePanic(ctx context.Context, r *http.Request, config *mountConfig, panicErr *panicError) {
+	info := &PanicInfo{
+		Pattern:   config.meta.Pattern,
+		Recovered: panicErr.recovered,
+		Request:   r,
+		RequestID: r.Header.Get(RequestIDHeader),
+		Stack:     panicErr.stack,
+	}
+
+	logAttrs := []any{
+		slog.String("panic", fmt.Sprint(info.Recovered)),
+		slog.String("pattern", info.Pattern),
+	}
+
+	if info.RequestID != "" {
+		logAttrs = append(logAttrs, slog.String("request_id", info.RequestID))
+	}
+
+	logAttrs = append(logAttrs, slog.String("stack", string(info.Stack)))
+
+	config.logger.ErrorContext(ctx, "panic in API endpoint", logAttrs...)
+
+	if config.onPanic != nil {
+		config.onPanic(ctx, info)
+	}
+}
@@ -0,0 +1,118 @@
+package apiendpoint
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+func TestPanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	type testBundle struct {
+		logBuf    *bytes.Buffer
+		panicInfo *PanicInfo
+	}
+
+	setup := func(t *testing.T, panicValue any) (*http.ServeMux, *testBundle) {
+		t.Helper()
+
+		var (
+			bundle = &testBundle{logBuf: &bytes.Buffer{}}
+			mux    = http.NewServeMux()
+		)
+
+		Mount(mux, &panicEndpoint{panicValue: panicValue}, &MountOpts{
+			Logger: slog.New(slog.NewJSONHandler(bundle.logBuf, nil)),
+			OnPanic: func(_ context.Context, info *PanicInfo) {
+				bundle.panicInfo = info
+			},
+		})
+
+		return mux, bundle
+	}
+
+	t.Run("RecoversAndLogs", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t, "something went wrong")
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/panic-endpoint", nil)
+		req.Header.Set(RequestIDHeader, "req_123")
+		mux.ServeHTTP(recorder, req)
+
+		requireStatusAndJSONResponse(t, http.StatusInternalServerError, &apierror.APIError{Code: apierror.CodeInternalError, Message: "Internal server error. Check logs for more information."}, recorder)
+
+		logRecord := mustUnmarshalJSON[map[string]any](t, bundle.logBuf.Bytes())
+		require.Equal(t, "ERROR", (*logRecord)["level"])
+		require.Equal(t, "panic in API endpoint", (*logRecord)["msg"])
+		require.Equal(t, "something went wrong", (*logRecord)["panic"])
+		require.Equal(t, "GET /api/panic-endpoint", (*logRecord)["pattern"])
+		require.Equal(t, "req_123", (*logRecord)["request_id"])
+		require.Contains(t, (*logRecord)["stack"], "recover_test.go")
+
+		require.NotNil(t, bundle.panicInfo)
+		require.Equal(t, "GET /api/panic-endpoint", bundle.panicInfo.Pattern)
+		require.Equal(t, "something went wrong", bundle.panicInfo.Recovered)
+		require.Equal(t, req, bundle.panicInfo.Request)
+		require.Equal(t, "req_123", bundle.panicInfo.RequestID)
+		require.Contains(t, string(bundle.panicInfo.Stack), "recover_test.go")
+	})
+
+	t.Run("NoRequestID", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t, "something went wrong")
+
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/panic-endpoint", nil))
+
+		require.Equal(t, http.StatusInternalServerError, recorder.Code)
+		require.NotContains(t, *mustUnmarshalJSON[map[string]any](t, bundle.logBuf.Bytes()), "request_id")
+	})
+
+	t.Run("ErrAbortHandlerRepanics", func(t *testing.T) {
+		t.Parallel()
+
+		mux, bundle := setup(t, http.ErrAbortHandler)
+
+		require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/panic-endpoint", nil))
+		})
+
+		require.Nil(t, bundle.panicInfo)
+	})
+}
+
+//
+// panicEndpoint
+//
+
+type panicEndpoint struct {
+	Endpoint[panicRequest, panicResponse]
+
+	panicValue any
+}
+
+func (*panicEndpoint) Meta() *EndpointMeta {
+	return &EndpointMeta{
+		Pattern:    "GET /api/panic-endpoint",
+		StatusCode: http.StatusOK,
+	}
+}
+
+type panicRequest struct{}
+
+type panicResponse struct{}
+
+func (e *panicEndpoint) Execute(_ context.Context, _ *panicRequest) (*panicResponse, error) {
+	panic(e.panicValue)
+}
@@ -0,0 +1,105 @@
+package apiendpoint
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Registry collects information about endpoints as they're passed to Mount so
+// that it can be used afterwards to generate artifacts like API documentation.
+// A registry is activated by assigning it to MountOpts.Registry.
+type Registry struct {
+	mu     sync.RWMutex
+	routes []*Route
+}
+
+// NewRegistry initializes a new empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Routes returns every route that's been mounted with the registry, in the
+// order they were mounted.
+func (r *Registry) Routes() []*Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*Route(nil), r.routes...)
+}
+
+func (r *Registry) add(route *Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.routes = append(r.routes, route)
+}
+
+// Route is information about an endpoint that was mounted with a Registry.
+type Route struct {
+	// Endpoint is the mounted endpoint.
+	Endpoint EndpointInterface
+
+	// Meta is the endpoint's metadata.
+	Meta *EndpointMeta
+
+	// Parameters are request struct fields bound from parts of the request
+	// other than its body, like path variables or query parameters.
+	Parameters []*RouteParameter
+
+	// RequestType is the type of the endpoint's request struct (TReq).
+	RequestType reflect.Type
+
+	// ResponseType is the type of the endpoint's response struct (TResp).
+	ResponseType reflect.Type
+}
+
+// ConsumesJSON returns true if the endpoint accepts JSON request bodies, as
+// opposed to only something like forms. Generated clients only support
+// endpoints that consume JSON, because they send every request body as JSON.
+func (r *Route) ConsumesJSON() bool {
+	return len(r.Meta.RequestMediaTypes) < 1 || slices.ContainsFunc(r.Meta.RequestMediaTypes, func(mediaType string) bool {
+		return strings.EqualFold(mediaType, MediaTypeJSON)
+	})
+}
+
+// ProducesJSON returns true if the endpoint can respond with JSON, as opposed
+// to only something like CSV or an event stream, or upgrading to a WebSocket.
+// Generated clients only support endpoints that produce JSON.
+func (r *Route) ProducesJSON() bool {
+	if r.Meta.StatusCode == http.StatusSwitchingProtocols {
+		return false
+	}
+
+	return len(r.Meta.ResponseMediaTypes) < 1 || slices.Contains(r.Meta.ResponseMediaTypes, MediaTypeJSON)
+}
+
+// RouteParameter is a request struct field bound from a part of the request
+// other than its body with a tag like `path` or `query`.
+type RouteParameter struct {
+	// Field is the struct field that the parameter is bound to.
+	Field reflect.StructField
+
+	// In is where the parameter comes from. One of `cookie`, `header`, `path`,
+	// or `query`.
+	In string
+
+	// Name is the name of the parameter, like the name of a query parameter or
+	// header.
+	Name string
+}
+
+func routeParameters(typ reflect.Type, bindings []fieldBinding) []*RouteParameter {
+	params := make([]*RouteParameter, len(bindings))
+	for i, binding := range bindings {
+		params[i] = &RouteParameter{
+			Field: typ.FieldByIndex(binding.index),
+			In:    binding.tag,
+			Name:  binding.name,
+		}
+	}
+
+	return params
+}
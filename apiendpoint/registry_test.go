@@ -0,0 +1,82 @@
+package apiendpoint
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux      = http.NewServeMux()
+		registry = NewRegistry()
+	)
+
+	getEndpoint := Mount(mux, &getEndpoint{}, &MountOpts{Registry: registry})
+	pathEndpoint := Mount(mux, &pathEndpoint{}, &MountOpts{Registry: registry})
+
+	// Not mounted with the registry.
+	Mount(mux, &queryEndpoint{}, nil)
+
+	routes := registry.Routes()
+	require.Len(t, routes, 2)
+
+	require.Equal(t, getEndpoint, routes[0].Endpoint)
+	require.Equal(t, "GET /api/get-endpoint", routes[0].Meta.Pattern)
+	require.Empty(t, routes[0].Parameters)
+	require.Equal(t, reflect.TypeFor[getRequest](), routes[0].RequestType)
+	require.Equal(t, reflect.TypeFor[getResponse](), routes[0].ResponseType)
+
+	require.Equal(t, pathEndpoint, routes[1].Endpoint)
+	require.Len(t, routes[1].Parameters, 2)
+	require.Equal(t, "id", routes[1].Parameters[0].Name)
+	require.Equal(t, "path", routes[1].Parameters[0].In)
+	require.Equal(t, "ID", routes[1].Parameters[0].Field.Name)
+	require.Equal(t, "queue", routes[1].Parameters[1].Name)
+}
+
+func TestRouteConsumesJSON(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux      = http.NewServeMux()
+		registry = NewRegistry()
+	)
+
+	Mount(mux, &getEndpoint{}, &MountOpts{Registry: registry})
+	Mount(mux, &uploadEndpoint{}, &MountOpts{Registry: registry})
+
+	routes := registry.Routes()
+	require.Len(t, routes, 2)
+
+	require.True(t, routes[0].ConsumesJSON())
+	require.False(t, routes[1].ConsumesJSON())
+}
+
+func TestRouteProducesJSON(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux      = http.NewServeMux()
+		registry = NewRegistry()
+	)
+
+	Mount(mux, &getEndpoint{}, &MountOpts{Registry: registry})
+	MountSSE(mux, &eventsEndpoint{}, &MountOpts{Registry: registry})
+	MountWebSocket(mux, &consoleEndpoint{}, &MountOpts{Registry: registry})
+
+	routes := registry.Routes()
+	require.Len(t, routes, 3)
+
+	require.True(t, routes[0].ProducesJSON())
+
+	require.False(t, routes[1].ProducesJSON())
+	require.Equal(t, []string{MediaTypeEventStream}, routes[1].Meta.ResponseMediaTypes)
+	require.Equal(t, reflect.TypeFor[jobEvent](), routes[1].ResponseType)
+
+	require.False(t, routes[2].ProducesJSON())
+}
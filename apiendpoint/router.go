@@ -0,0 +1,211 @@
+package apiendpoint
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/riverqueue/apiframe/apimiddleware"
+)
+
+// Mux is something that endpoints can be mounted to with Mount and its
+// variants. It's implemented by http.ServeMux, and by Router, which mounts
+// endpoints to an http.ServeMux with a shared path prefix, options, and
+// middleware.
+type Mux interface {
+	http.Handler
+
+	// Handle registers a handler for a pattern like `GET /api/jobs/{id}`.
+	Handle(pattern string, handler http.Handler)
+}
+
+// Router mounts endpoints to an http.ServeMux with a shared path prefix,
+// MountOpts defaults, and middleware, so that they don't need to be repeated
+// for every endpoint. It's passed to Mount in place of a mux:
+//
+//	router := apiendpoint.NewRouter(mux, &apiendpoint.MountOpts{Logger: logger})
+//
+//	api := router.Group("/api", &apiendpoint.MountOpts{
+//		MiddlewareStack: apimiddleware.NewMiddlewareStack(authMiddleware),
+//		Timeout:         5 * time.Second,
+//	})
+//	apiendpoint.Mount(api, &jobGetEndpoint{}, nil) // GET /jobs/{id} at GET /api/jobs/{id}
+//
+// Groups nest, with each one's prefix appended to its parent's, and its
+// MountOpts used as defaults for settings that the endpoint's own MountOpts
+// leaves unset, falling back to its parent's. A setting is unset if it has
+// its zero value, so overriding one with its default needs an explicit value
+// like apierror.FormatMessage or DefaultTimeout. The exception is
+// MountOpts.StrictJSON, which can't be turned back off once it's been
+// enabled by a parent.
+//
+// Middleware stacks don't override each other, but are combined so that a
+// request passes through a parent's middleware before a group's, and a
+// group's before any from the endpoint's own MountOpts. Error interpreters
+// are combined the same way, so a parent's are tried before a group's.
+//
+// Endpoint patterns are prefixed when they're mounted, so the prefixed
+// pattern is what appears in EndpointMeta.Pattern and generated
+// documentation.
+type Router struct {
+	middlewareStack *apimiddleware.MiddlewareStack
+	mux             Mux // only set on a root router
+	opts            *MountOpts
+	parent          *Router
+	prefix          string
+	registry        *Registry
+}
+
+// NewRouter initializes a router that mounts endpoints to the given mux using
+// opts as defaults. Use Group to create routers with a path prefix.
+func NewRouter(mux Mux, opts *MountOpts) *Router {
+	router := &Router{mux: mux, registry: NewRegistry()}
+	router.opts, router.middlewareStack = splitRouterOpts(&MountOpts{}, opts)
+	return router
+}
+
+// Group creates a router nested under this one whose endpoints are mounted at
+// the given path prefix (after this router's), with opts used as defaults
+// that take precedence over this router's. prefix must start with a slash,
+// and may contain path variables like `/queues/{queue}`.
+func (r *Router) Group(prefix string, opts *MountOpts) *Router {
+	if !strings.HasPrefix(prefix, "/") {
+		panic(fmt.Sprintf("router prefix %q must start with a slash", prefix))
+	}
+
+	group := &Router{
+		parent:   r,
+		prefix:   r.prefix + strings.TrimSuffix(prefix, "/"),
+		registry: NewRegistry(),
+	}
+	group.opts, group.middlewareStack = splitRouterOpts(r.opts, opts)
+	return group
+}
+
+// Handle registers a plain handler for a pattern like `GET /health` with the
+// router's prefix and middleware, for things that aren't endpoints. It doesn't
+// appear in Routes.
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	r.handle(r.prefixPattern(pattern), handler)
+}
+
+// Prefix returns the path prefix of the router's endpoints, including the
+// prefixes of any routers it's nested under.
+func (r *Router) Prefix() string {
+	return r.prefix
+}
+
+// Registry returns a registry of every endpoint that's been mounted through
+// the router or any group nested under it, which can be passed to generators
+// like apiopenapi.NewDocument. Endpoints are also added to any registry in
+// MountOpts, so it's not necessary to set one there.
+func (r *Router) Registry() *Registry {
+	return r.registry
+}
+
+// Routes returns every endpoint that's been mounted through the router or any
+// group nested under it, in the order they were mounted, with their full
+// patterns. Useful for debugging.
+func (r *Router) Routes() []*Route {
+	return r.registry.Routes()
+}
+
+// ServeHTTP serves a request using the mux that the router's root was
+// created with, so a router can be used as a server's handler directly.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.root().mux.ServeHTTP(w, req)
+}
+
+// addRoute adds a mounted endpoint to the registries of the router and every
+// router it's nested under.
+func (r *Router) addRoute(route *Route) {
+	for router := r; router != nil; router = router.parent {
+		router.registry.add(route)
+	}
+}
+
+// handle registers a handler for an already prefixed pattern on the root mux,
+// behind the middleware of the router and every router it's nested under.
+func (r *Router) handle(pattern string, handler http.Handler) {
+	for router := r; router != nil; router = router.parent {
+		if router.middlewareStack != nil {
+			handler = router.middlewareStack.Mount(handler)
+		}
+	}
+
+	r.root().mux.Handle(pattern, handler)
+}
+
+// prefixPattern adds the router's prefix to the path of a pattern like
+// `GET /jobs/{id}`.
+func (r *Router) prefixPattern(pattern string) string {
+	if r.prefix == "" {
+		return pattern
+	}
+
+	method, path, hasMethod := strings.Cut(pattern, " ")
+	if !hasMethod {
+		return r.prefix + pattern
+	}
+
+	return method + " " + r.prefix + strings.TrimLeft(path, " ")
+}
+
+func (r *Router) root() *Router {
+	router := r
+	for router.parent != nil {
+		router = router.parent
+	}
+	return router
+}
+
+// resolveRouter returns the router that an endpoint given to Mount with mux is
+// mounted through, and its MountOpts with the router's defaults applied. A mux
+// that's not a Router gets a throwaway root router without any defaults.
+func resolveRouter(mux Mux, opts *MountOpts) (*Router, *MountOpts) {
+	router, ok := mux.(*Router)
+	if !ok {
+		router = NewRouter(mux, nil)
+	}
+
+	return router, mergeMountOpts(router.opts, opts)
+}
+
+// splitRouterOpts merges a router's MountOpts with its parent's, returning the
+// middleware stack separately because stacks are combined rather than
+// overridden.
+func splitRouterOpts(parentOpts, opts *MountOpts) (*MountOpts, *apimiddleware.MiddlewareStack) {
+	merged := mergeMountOpts(parentOpts, opts)
+
+	middlewareStack := merged.MiddlewareStack
+	merged.MiddlewareStack = nil
+
+	return merged, middlewareStack
+}
+
+// mergeMountOpts returns a copy of defaults with every field that's set in
+// opts (i.e. isn't its zero value) overriding it, except for error
+// interpreters, which are appended to those of defaults.
+func mergeMountOpts(defaults, opts *MountOpts) *MountOpts {
+	merged := *defaults
+	if opts == nil {
+		return &merged
+	}
+
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	optsValue := reflect.ValueOf(opts).Elem()
+
+	for i := range optsValue.NumField() {
+		if field := optsValue.Field(i); !field.IsZero() {
+			mergedValue.Field(i).Set(field)
+		}
+	}
+
+	if len(defaults.ErrorInterpreters) > 0 && len(opts.ErrorInterpreters) > 0 {
+		merged.ErrorInterpreters = slices.Concat(defaults.ErrorInterpreters, opts.ErrorInterpreters)
+	}
+
+	return &merged
+}
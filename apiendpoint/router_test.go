@@ -0,0 +1,302 @@
+package apiendpoint
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/apimiddleware"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	// trailMiddleware appends a segment to the X-Trail header so that the
+	// order that middleware ran in can be checked.
+	trailMiddleware := func(segment string) *apimiddleware.MiddlewareStack {
+		return apimiddleware.NewMiddlewareStack(apimiddleware.MiddlewareFunc(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Trail", segment)
+				next.ServeHTTP(w, r)
+			})
+		}))
+	}
+
+	serve := func(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+
+	t.Run("PrefixesNestedGroups", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			router = NewRouter(http.NewServeMux(), &MountOpts{Logger: riversharedtest.Logger(t)})
+			api    = router.Group("/api", nil)
+			v1     = api.Group("/v1/", nil)
+		)
+
+		require.Equal(t, "/api/v1", v1.Prefix())
+
+		endpoint := &routerEndpoint{pattern: "GET /jobs/{id}"}
+		Mount(v1, endpoint, nil)
+		require.Equal(t, "GET /api/v1/jobs/{id}", endpoint.meta.Pattern)
+
+		requireStatusAndJSONResponse(t, http.StatusOK, &routerResponse{ID: 123}, serve(router, http.MethodGet, "/api/v1/jobs/123"))
+		require.Equal(t, http.StatusNotFound, serve(router, http.MethodGet, "/jobs/123").Code)
+	})
+
+	t.Run("PathVariablesInPrefix", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			router = NewRouter(http.NewServeMux(), &MountOpts{Logger: riversharedtest.Logger(t)})
+			queues = router.Group("/queues/{queue}", nil)
+		)
+
+		Mount(queues, &routerEndpoint{pattern: "GET /jobs/{id}"}, nil)
+
+		requireStatusAndJSONResponse(t, http.StatusOK, &routerResponse{ID: 123, Queue: "default"}, serve(router, http.MethodGet, "/queues/default/jobs/123"))
+	})
+
+	t.Run("CombinesMiddleware", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			router = NewRouter(http.NewServeMux(), &MountOpts{Logger: riversharedtest.Logger(t), MiddlewareStack: trailMiddleware("root")})
+			api    = router.Group("/api", &MountOpts{MiddlewareStack: trailMiddleware("group")})
+		)
+
+		Mount(api, &routerEndpoint{pattern: "GET /jobs/{id}"}, &MountOpts{MiddlewareStack: trailMiddleware("endpoint")})
+		Mount(router, &routerEndpoint{pattern: "GET /health/{id}"}, nil)
+
+		require.Equal(t, []string{"root", "group", "endpoint"}, serve(router, http.MethodGet, "/api/jobs/123").Header().Values("X-Trail"))
+		require.Equal(t, []string{"root"}, serve(router, http.MethodGet, "/health/1").Header().Values("X-Trail"))
+	})
+
+	t.Run("MountOptsDefaults", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			router = NewRouter(http.NewServeMux(), &MountOpts{ErrorFormat: apierror.FormatProblemDetails, Logger: riversharedtest.Logger(t), Timeout: time.Minute})
+			api    = router.Group("/api", &MountOpts{Timeout: time.Second})
+		)
+
+		endpoint := &routerEndpoint{err: apierror.NewNotFound("Job not found."), pattern: "GET /jobs/{id}"}
+		Mount(api, endpoint, nil)
+
+		// ErrorFormat comes from the root and Timeout from the group.
+		recorder := serve(router, http.MethodGet, "/api/jobs/123")
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+		require.Equal(t, apierror.ContentTypeProblemDetails, recorder.Header().Get("Content-Type"))
+		require.Equal(t, time.Second, endpoint.timeout)
+
+		// The endpoint's own options take precedence.
+		endpoint = &routerEndpoint{pattern: "GET /other/{id}"}
+		Mount(api, endpoint, &MountOpts{Timeout: 2 * time.Second})
+
+		serve(router, http.MethodGet, "/api/other/123")
+		require.Equal(t, 2*time.Second, endpoint.timeout)
+	})
+
+	t.Run("MountOptsOverrideWithDefaultValue", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			router = NewRouter(http.NewServeMux(), &MountOpts{ErrorFormat: apierror.FormatProblemDetails, Logger: riversharedtest.Logger(t)})
+			api    = router.Group("/api", &MountOpts{ErrorFormat: apierror.FormatMessage})
+		)
+
+		Mount(api, &routerEndpoint{err: apierror.NewNotFound("Job not found."), pattern: "GET /jobs/{id}"}, nil)
+
+		recorder := serve(router, http.MethodGet, "/api/jobs/123")
+		requireStatusAndJSONResponse(t, http.StatusNotFound, &apierror.APIError{Message: "Job not found."}, recorder)
+		require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("CombinesErrorInterpreters", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			errJobLocked   = errors.New("job locked")
+			errJobNotFound = errors.New("job not found")
+		)
+
+		var (
+			router = NewRouter(http.NewServeMux(), &MountOpts{ErrorInterpreters: []ErrorInterpreter{&routerErrorInterpreter{err: errJobNotFound, message: "Job not found.", statusCode: http.StatusNotFound}}, Logger: riversharedtest.Logger(t)})
+			api    = router.Group("/api", &MountOpts{ErrorInterpreters: []ErrorInterpreter{&routerErrorInterpreter{err: errJobLocked, message: "Job is locked.", statusCode: http.StatusConflict}}})
+		)
+
+		Mount(api, &routerEndpoint{err: errJobNotFound, pattern: "GET /jobs/{id}"}, nil)
+		Mount(api, &routerEndpoint{err: errJobLocked, pattern: "GET /locked/{id}"}, nil)
+
+		requireStatusAndJSONResponse(t, http.StatusNotFound, &apierror.APIError{Message: "Job not found."}, serve(router, http.MethodGet, "/api/jobs/123"))
+		requireStatusAndJSONResponse(t, http.StatusConflict, &apierror.APIError{Message: "Job is locked."}, serve(router, http.MethodGet, "/api/locked/123"))
+	})
+
+	t.Run("Routes", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			registry = NewRegistry()
+			router   = NewRouter(http.NewServeMux(), &MountOpts{Logger: riversharedtest.Logger(t), Registry: registry})
+			api      = router.Group("/api", nil)
+			admin    = api.Group("/admin", nil)
+		)
+
+		Mount(router, &routerEndpoint{pattern: "GET /health/{id}"}, nil)
+		Mount(api, &routerEndpoint{pattern: "GET /jobs/{id}"}, nil)
+		Mount(admin, &routerEndpoint{pattern: "DELETE /jobs/{id}"}, nil)
+
+		routePatterns := func(routes []*Route) []string {
+			patterns := make([]string, len(routes))
+			for i, route := range routes {
+				patterns[i] = route.Meta.Pattern
+			}
+			return patterns
+		}
+
+		require.Equal(t, []string{"GET /health/{id}", "GET /api/jobs/{id}", "DELETE /api/admin/jobs/{id}"}, routePatterns(router.Routes()))
+		require.Equal(t, []string{"GET /api/jobs/{id}", "DELETE /api/admin/jobs/{id}"}, routePatterns(api.Routes()))
+		require.Equal(t, []string{"DELETE /api/admin/jobs/{id}"}, routePatterns(admin.Routes()))
+		require.Equal(t, router.Routes(), router.Registry().Routes())
+
+		// Also added to the registry from MountOpts.
+		require.Equal(t, router.Routes(), registry.Routes())
+	})
+
+	t.Run("Handle", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			router = NewRouter(http.NewServeMux(), &MountOpts{MiddlewareStack: trailMiddleware("root")})
+			api    = router.Group("/api", &MountOpts{MiddlewareStack: trailMiddleware("group")})
+		)
+
+		api.Handle("GET /health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		recorder := serve(router, http.MethodGet, "/api/health")
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+		require.Equal(t, []string{"root", "group"}, recorder.Header().Values("X-Trail"))
+		require.Empty(t, router.Routes())
+	})
+
+	t.Run("InvalidPrefix", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, `router prefix "api" must start with a slash`, func() {
+			NewRouter(http.NewServeMux(), nil).Group("api", nil)
+		})
+	})
+}
+
+func TestMergeMountOpts(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+
+	merged := mergeMountOpts(
+		&MountOpts{Registry: registry, StrictJSON: true, Timeout: time.Minute},
+		&MountOpts{MaxRequestBodyBytes: 1024, Timeout: time.Second},
+	)
+	require.Equal(t, &MountOpts{MaxRequestBodyBytes: 1024, Registry: registry, StrictJSON: true, Timeout: time.Second}, merged)
+
+	// A zero value leaves the default in place, so only an explicit value
+	// can override it, and strictness can't be turned back off.
+	merged = mergeMountOpts(
+		&MountOpts{ErrorFormat: apierror.FormatProblemDetails, StrictJSON: true},
+		&MountOpts{ErrorFormat: apierror.FormatMessage, StrictJSON: false},
+	)
+	require.Equal(t, &MountOpts{ErrorFormat: apierror.FormatMessage, StrictJSON: true}, merged)
+
+	// Error interpreters are appended rather than overridden.
+	var (
+		parentInterpreter = &routerErrorInterpreter{err: errors.New("parent")}
+		childInterpreter  = &routerErrorInterpreter{err: errors.New("child")}
+	)
+	merged = mergeMountOpts(
+		&MountOpts{ErrorInterpreters: []ErrorInterpreter{parentInterpreter}},
+		&MountOpts{ErrorInterpreters: []ErrorInterpreter{childInterpreter}},
+	)
+	require.Equal(t, []ErrorInterpreter{parentInterpreter, childInterpreter}, merged.ErrorInterpreters)
+
+	defaults := &MountOpts{Timeout: time.Minute}
+	merged = mergeMountOpts(defaults, nil)
+	require.Equal(t, defaults, merged)
+	require.NotSame(t, defaults, merged)
+}
+
+//
+// routerEndpoint
+//
+
+type routerEndpoint struct {
+	Endpoint[routerRequest, routerResponse]
+
+	err     error
+	pattern string
+	timeout time.Duration
+}
+
+func (e *routerEndpoint) Meta() *EndpointMeta {
+	return &EndpointMeta{
+		Pattern:    e.pattern,
+		StatusCode: http.StatusOK,
+	}
+}
+
+type routerRequest struct {
+	ID    int64  `json:"-" path:"id"`
+	Queue string `json:"-"`
+}
+
+func (req *routerRequest) ExtractRaw(r *http.Request) error {
+	req.Queue = r.PathValue("queue")
+	return nil
+}
+
+type routerResponse struct {
+	ID    int64  `json:"id"`
+	Queue string `json:"queue,omitempty"`
+}
+
+func (e *routerEndpoint) Execute(ctx context.Context, req *routerRequest) (*routerResponse, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		e.timeout = time.Until(deadline).Round(time.Second)
+	}
+
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	return &routerResponse{ID: req.ID, Queue: req.Queue}, nil
+}
+
+//
+// routerErrorInterpreter
+//
+
+// routerErrorInterpreter interprets a single error as an API error.
+type routerErrorInterpreter struct {
+	err        error
+	message    string
+	statusCode int
+}
+
+func (i *routerErrorInterpreter) InterpretError(ctx context.Context, err error) apierror.Interface {
+	if !errors.Is(err, i.err) {
+		return nil
+	}
+
+	return apierror.FromStatusCode(i.statusCode, i.message)
+}
@@ -0,0 +1,335 @@
+package apiendpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/internal/mediatype"
+)
+
+// MediaTypeEventStream is the media type of Server-Sent Events streams.
+const MediaTypeEventStream = "text/event-stream"
+
+// DefaultHeartbeatInterval is the interval between heartbeats on streaming
+// responses when MountOpts.HeartbeatInterval isn't set.
+const DefaultHeartbeatInterval = 15 * time.Second
+
+// SSEEndpointExecuteInterface is an interface to an API endpoint that streams
+// Server-Sent Events to the client instead of returning a single response.
+// Like EndpointExecuteInterface, some of it is implemented by an embedded
+// Endpoint struct, and some of it should be implemented by the endpoint
+// itself.
+type SSEEndpointExecuteInterface[TReq any, TEvent any] interface {
+	EndpointInterface
+
+	// Execute executes the API endpoint, sending events to the client through
+	// sink until there's nothing left to send or ctx is cancelled because the
+	// client disconnected.
+	//
+	// An error returned before any events have been sent is written as a
+	// normal API error response. Afterwards, the response's status has
+	// already been sent, so the error is sent as an `error` event instead.
+	//
+	// This should be implemented by each specific API endpoint.
+	Execute(ctx context.Context, req *TReq, sink *EventSink[TEvent]) error
+}
+
+// Event is a Server-Sent Event sent with EventSink.SendEvent.
+type Event[TEvent any] struct {
+	// Data is the event's payload, which is marshaled to JSON.
+	Data *TEvent
+
+	// ID is the event's ID, which a reconnecting client sends back in a
+	// Last-Event-ID header so that the stream can be resumed where it left
+	// off. See EventSink.LastEventID. Optional.
+	ID string
+
+	// Name is the event's type, which browsers dispatch to listeners added
+	// with addEventListener. If empty, the event has the default type of
+	// `message`. Optional.
+	Name string
+
+	// Retry is how long a client should wait before reconnecting if the
+	// connection is lost. Optional.
+	Retry time.Duration
+}
+
+// EventSink sends Server-Sent Events to a client. It's passed to the Execute
+// function of endpoints mounted with MountSSE, and is safe for use by
+// multiple goroutines.
+//
+// The response is started with the first event or heartbeat, and every event
+// is flushed to the client as soon as it's sent.
+type EventSink[TEvent any] struct {
+	ctx         context.Context //nolint:containedctx
+	lastEventID string
+	mu          sync.Mutex
+	responseCtl *http.ResponseController
+	started     bool
+	statusCode  int
+	w           http.ResponseWriter
+}
+
+// LastEventID returns the ID of the last event that a reconnecting client
+// received, from its Last-Event-ID header. It's empty for a client that's
+// connecting for the first time.
+func (s *EventSink[TEvent]) LastEventID() string {
+	return s.lastEventID
+}
+
+// Send sends an event with the given data and no ID or name.
+func (s *EventSink[TEvent]) Send(data *TEvent) error {
+	return s.SendEvent(&Event[TEvent]{Data: data})
+}
+
+// SendEvent sends an event. Returns an error if the client has disconnected,
+// in which case the endpoint should stop sending events and return.
+func (s *EventSink[TEvent]) SendEvent(event *Event[TEvent]) error {
+	if strings.ContainsAny(event.ID, "\r\n") || strings.ContainsAny(event.Name, "\r\n") {
+		return errors.New("event ID and name may not contain newlines")
+	}
+
+	var frame strings.Builder
+
+	if event.ID != "" {
+		frame.WriteString("id: " + event.ID + "\n")
+	}
+	if event.Name != "" {
+		frame.WriteString("event: " + event.Name + "\n")
+	}
+	if event.Retry > 0 {
+		frame.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("error marshaling event JSON: %w", err)
+	}
+
+	// Marshaled JSON never contains newlines, so it always fits on a single
+	// `data` line.
+	frame.WriteString("data: " + string(data) + "\n\n")
+
+	return s.write(frame.String())
+}
+
+// heartbeat sends a comment that's ignored by clients, but which keeps
+// proxies from closing a connection that's otherwise idle.
+func (s *EventSink[TEvent]) heartbeat() error {
+	return s.write(": heartbeat\n\n")
+}
+
+// sendError sends an API error as an `error` event after the stream has
+// started, in the error format negotiated for the request.
+func (s *EventSink[TEvent]) sendError(ctx context.Context, apiErr apierror.Interface) error {
+	data, err := apierror.MarshalJSON(ctx, apiErr)
+	if err != nil {
+		return fmt.Errorf("error marshaling API error: %w", err)
+	}
+
+	return s.write("event: error\ndata: " + string(data) + "\n\n")
+}
+
+// isStarted returns true if the response has been started, after which its
+// status can no longer be changed.
+func (s *EventSink[TEvent]) isStarted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.started
+}
+
+// write writes a frame to the client and flushes it, starting the response
+// first if it hasn't been already.
+func (s *EventSink[TEvent]) write(frame string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	if !s.started {
+		s.started = true
+
+		// Streams are long-lived, so they're exempt from any write timeout
+		// configured on the server. Not all response writers support
+		// deadlines, which is fine.
+		_ = s.responseCtl.SetWriteDeadline(time.Time{})
+
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.Header().Set("Content-Type", MediaTypeEventStream)
+		s.w.Header().Set("X-Accel-Buffering", "no") // disable buffering in Nginx
+		s.w.WriteHeader(s.statusCode)
+	}
+
+	if _, err := s.w.Write([]byte(frame)); err != nil {
+		return fmt.Errorf("error writing event: %w", err)
+	}
+
+	if err := s.responseCtl.Flush(); err != nil {
+		return fmt.Errorf("error flushing event: %w", err)
+	}
+
+	return nil
+}
+
+// MountSSE mounts an endpoint that streams Server-Sent Events to a Go
+// http.ServeMux or a Router. Requests are decoded, bound, and validated the
+// same way as for endpoints mounted with Mount, and the same options apply,
+// except that:
+//
+//   - No timeout is applied unless the endpoint sets EndpointMeta.Timeout,
+//     because streams are expected to stay open until the client disconnects.
+//     MountOpts.Timeout is ignored.
+//
+//   - A heartbeat is sent every MountOpts.HeartbeatInterval so that idle
+//     connections aren't closed by proxies.
+//
+//   - A request whose Accept header doesn't accept `text/event-stream` is
+//     rejected with a NotAcceptable.
+//
+// If EndpointMeta.ResponseMediaTypes is empty, it's set to
+// MediaTypeEventStream so that the stream is described correctly by
+// generated documentation.
+func MountSSE[TReq any, TEvent any](mux Mux, apiEndpoint SSEEndpointExecuteInterface[TReq, TEvent], opts *MountOpts) EndpointInterface {
+	router, opts := resolveRouter(mux, opts)
+
+	config := resolveMountConfig[TReq](apiEndpoint, router, opts)
+	meta := config.meta
+
+	if len(meta.ResponseMediaTypes) < 1 {
+		meta.ResponseMediaTypes = []string{MediaTypeEventStream}
+	}
+
+	config.heartbeatInterval = opts.HeartbeatInterval
+	if config.heartbeatInterval == 0 {
+		config.heartbeatInterval = DefaultHeartbeatInterval
+	}
+
+	config.timeout = meta.Timeout
+	if config.timeout == 0 {
+		config.timeout = TimeoutNone
+	}
+
+	mountHandler(router, apiEndpoint, opts, config, reflect.TypeFor[TReq](), reflect.TypeFor[TEvent](), func(w http.ResponseWriter, r *http.Request) {
+		executeSSEEndpoint(w, r, config, apiEndpoint.Execute)
+	})
+
+	return apiEndpoint
+}
+
+func executeSSEEndpoint[TReq any, TEvent any](w http.ResponseWriter, r *http.Request, config *mountConfig, execute func(ctx context.Context, req *TReq, sink *EventSink[TEvent]) error) {
+	ctx := endpointContext(w, r, config)
+
+	ctx, cancel := withTimeout(ctx, config.timeout)
+	defer cancel()
+
+	defer removeMultipartFiles(r)
+
+	// The sink uses the request's context rather than the endpoint's so that
+	// an error event can still be sent after the endpoint times out.
+	sink := &EventSink[TEvent]{
+		ctx:         r.Context(),
+		lastEventID: r.Header.Get("Last-Event-ID"),
+		responseCtl: http.NewResponseController(w),
+		statusCode:  config.meta.StatusCode,
+		w:           w,
+	}
+
+	err := func() (err error) {
+		defer recoverPanic(&err)
+
+		if accept := r.Header.Get("Accept"); accept != "" {
+			if _, ok := mediatype.Negotiate(accept, []string{MediaTypeEventStream}); !ok {
+				return notAcceptableError(accept, []string{MediaTypeEventStream})
+			}
+		}
+
+		req, err := parseRequest[TReq](ctx, w, r, config)
+		if err != nil {
+			return err
+		}
+
+		stopHeartbeats := startHeartbeats(ctx, config.heartbeatInterval, sink.heartbeat)
+		defer stopHeartbeats()
+
+		if err := execute(ctx, req, sink); err != nil {
+			return err
+		}
+
+		// Make sure that a stream without any events still gets a response.
+		if !sink.isStarted() {
+			return sink.write(": no events\n\n")
+		}
+
+		return nil
+	}()
+	if err == nil {
+		return
+	}
+
+	// A client that disconnects is the normal way for a stream to end.
+	if r.Context().Err() != nil && errors.Is(err, context.Canceled) {
+		config.logger.DebugContext(ctx, "client disconnected from event stream", slog.String("pattern", config.meta.Pattern))
+		return
+	}
+
+	apiErr := resolveError(ctx, r, config, err)
+
+	if !sink.isStarted() {
+		apiErr.Write(ctx, config.logger, w)
+		return
+	}
+
+	if err := sink.sendError(ctx, apiErr); err != nil {
+		config.logger.DebugContext(ctx, "error sending error event", slog.String("error", err.Error()))
+	}
+}
+
+// startHeartbeats invokes heartbeat every interval until the returned
+// function is called or ctx is done. The returned function waits for any
+// heartbeat in progress to finish. A negative interval disables heartbeats.
+func startHeartbeats(ctx context.Context, interval time.Duration, heartbeat func() error) func() {
+	if interval < 0 {
+		return func() {}
+	}
+
+	var (
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+
+	wg.Go(func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := heartbeat(); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
@@ -0,0 +1,288 @@
+package apiendpoint
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestMountSSE(t *testing.T) {
+	t.Parallel()
+
+	type testBundle struct {
+		endpoint *eventsEndpoint
+		mux      *http.ServeMux
+	}
+
+	setup := func(t *testing.T, endpoint *eventsEndpoint, opts *MountOpts) *testBundle {
+		t.Helper()
+
+		if opts == nil {
+			opts = &MountOpts{}
+		}
+		opts.Logger = riversharedtest.Logger(t)
+
+		mux := http.NewServeMux()
+		MountSSE(mux, endpoint, opts)
+
+		return &testBundle{endpoint: endpoint, mux: mux}
+	}
+
+	serve := func(bundle *testBundle, req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		bundle.mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("StreamsEvents", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{numEvents: 2}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil)
+		req.Header.Set("Accept", MediaTypeEventStream)
+
+		recorder := serve(bundle, req)
+		requireStatusAndResponse(t, http.StatusOK,
+			"id: 1\nevent: job\ndata: {\"id\":1,\"queue\":\"default\"}\n\n"+
+				"id: 2\nevent: job\ndata: {\"id\":2,\"queue\":\"default\"}\n\n",
+			recorder,
+		)
+		require.Equal(t, MediaTypeEventStream, recorder.Header().Get("Content-Type"))
+		require.Equal(t, "no-cache", recorder.Header().Get("Cache-Control"))
+		require.True(t, recorder.Flushed)
+	})
+
+	t.Run("Send", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{numEvents: 1, plainSend: true}, nil)
+
+		recorder := serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil))
+		requireStatusAndResponse(t, http.StatusOK, "data: {\"id\":1,\"queue\":\"default\"}\n\n", recorder)
+	})
+
+	t.Run("ResumesFromLastEventID", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{numEvents: 3}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil)
+		req.Header.Set("Last-Event-ID", "2")
+
+		recorder := serve(bundle, req)
+		requireStatusAndResponse(t, http.StatusOK, "id: 3\nevent: job\ndata: {\"id\":3,\"queue\":\"default\"}\n\n", recorder)
+	})
+
+	t.Run("NoEvents", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{}, nil)
+
+		recorder := serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil))
+		requireStatusAndResponse(t, http.StatusOK, ": no events\n\n", recorder)
+		require.Equal(t, MediaTypeEventStream, recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("ValidationError", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{numEvents: 1}, nil)
+
+		recorder := serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		require.Equal(t, apierror.CodeValidationFailed, mustUnmarshalJSON[apierror.APIError](t, recorder.Body.Bytes()).Code)
+	})
+
+	t.Run("ErrorBeforeFirstEvent", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{err: apierror.NewNotFound("Queue not found.")}, nil)
+
+		recorder := serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil))
+		requireStatusAndJSONResponse(t, http.StatusNotFound, &apierror.APIError{Message: "Queue not found."}, recorder)
+	})
+
+	t.Run("ErrorAfterFirstEvent", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{err: errors.New("database went away"), numEvents: 1}, nil)
+
+		recorder := serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil))
+		requireStatusAndResponse(t, http.StatusOK,
+			"id: 1\nevent: job\ndata: {\"id\":1,\"queue\":\"default\"}\n\n"+
+				"event: error\ndata: {\"code\":\"internal_error\",\"message\":\"Internal server error. Check logs for more information.\"}\n\n",
+			recorder,
+		)
+	})
+
+	t.Run("ErrorAfterFirstEventProblemDetails", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{err: apierror.NewNotFound("Queue not found."), numEvents: 1}, &MountOpts{ErrorFormat: apierror.FormatProblemDetails})
+
+		recorder := serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil))
+		requireStatusAndResponse(t, http.StatusOK,
+			"id: 1\nevent: job\ndata: {\"id\":1,\"queue\":\"default\"}\n\n"+
+				"event: error\ndata: {\"detail\":\"Queue not found.\",\"status\":404,\"title\":\"Not Found\",\"type\":\"about:blank\"}\n\n",
+			recorder,
+		)
+	})
+
+	t.Run("NotAcceptable", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{numEvents: 1}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil)
+		req.Header.Set("Accept", "application/json")
+
+		recorder := serve(bundle, req)
+		requireStatusAndJSONResponse(t, http.StatusNotAcceptable, &apierror.APIError{Code: apierror.CodeNotAcceptable, Message: "None of the media types in the Accept header (`application/json`) can be produced. Available types: `text/event-stream`."}, recorder)
+	})
+
+	t.Run("NoTimeout", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{}, &MountOpts{Timeout: time.Second})
+
+		serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil))
+		require.False(t, bundle.endpoint.hadDeadline)
+	})
+
+	t.Run("EndpointTimeout", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{timeout: time.Second}, nil)
+
+		serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil))
+		require.True(t, bundle.endpoint.hadDeadline)
+	})
+
+	t.Run("Heartbeats", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{wait: 100 * time.Millisecond}, &MountOpts{HeartbeatInterval: 10 * time.Millisecond})
+
+		recorder := serve(bundle, httptest.NewRequest(http.MethodGet, "/api/events?queue=default", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.True(t, strings.HasPrefix(recorder.Body.String(), ": heartbeat\n\n"), "Expected heartbeats; response body: %s", recorder.Body.String())
+	})
+
+	t.Run("ClientDisconnect", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &eventsEndpoint{numEvents: 1, wait: time.Minute}, nil)
+
+		server := httptest.NewServer(bundle.mux)
+		t.Cleanup(server.Close)
+
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/events?queue=default", nil)
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		// The first event arrives right away because it's flushed.
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "id: 1\n", line)
+
+		cancel()
+
+		select {
+		case <-bundle.endpoint.done:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "Timed out waiting for endpoint to notice the disconnect")
+		}
+	})
+}
+
+//
+// eventsEndpoint
+//
+
+type eventsEndpoint struct {
+	Endpoint[eventsRequest, jobEvent]
+
+	done        chan struct{}
+	err         error
+	hadDeadline bool
+	numEvents   int
+	plainSend   bool
+	timeout     time.Duration
+	wait        time.Duration
+}
+
+func (e *eventsEndpoint) Meta() *EndpointMeta {
+	e.done = make(chan struct{})
+
+	return &EndpointMeta{
+		Pattern:    "GET /api/events",
+		StatusCode: http.StatusOK,
+		Timeout:    e.timeout,
+	}
+}
+
+type eventsRequest struct {
+	Queue string `json:"-" query:"queue" validate:"required"`
+}
+
+type jobEvent struct {
+	ID    int64  `json:"id"`
+	Queue string `json:"queue"`
+}
+
+func (e *eventsEndpoint) Execute(ctx context.Context, req *eventsRequest, sink *EventSink[jobEvent]) error {
+	defer close(e.done)
+
+	_, e.hadDeadline = ctx.Deadline()
+
+	var lastID int64
+	if sink.LastEventID() != "" {
+		var err error
+		if lastID, err = strconv.ParseInt(sink.LastEventID(), 10, 64); err != nil {
+			return apierror.NewBadRequest("Invalid Last-Event-ID.")
+		}
+	}
+
+	for id := lastID + 1; id <= int64(e.numEvents); id++ {
+		event := &jobEvent{ID: id, Queue: req.Queue}
+
+		if e.plainSend {
+			if err := sink.Send(event); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := sink.SendEvent(&Event[jobEvent]{Data: event, ID: strconv.FormatInt(id, 10), Name: "job"}); err != nil {
+			return err
+		}
+	}
+
+	if e.wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.wait):
+		}
+	}
+
+	return e.err
+}
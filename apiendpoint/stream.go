@@ -0,0 +1,358 @@
+package apiendpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/internal/mediatype"
+)
+
+// MediaTypeNDJSON is the media type of newline-delimited JSON, in which every
+// line is a JSON value.
+const MediaTypeNDJSON = "application/x-ndjson"
+
+// streamFlushInterval is the minimum amount of time between flushes of a
+// streamed response. Items written sooner than this after the last flush are
+// flushed once it's elapsed, so that a fast stream isn't flushed after every
+// item, but a slow one still reaches the client promptly.
+const streamFlushInterval = 100 * time.Millisecond
+
+// StreamEndpointExecuteInterface is an interface to an API endpoint that
+// streams a potentially large list of items to the client instead of
+// returning a single response that needs to be buffered in full. Like
+// EndpointExecuteInterface, some of it is implemented by an embedded Endpoint
+// struct, and some of it should be implemented by the endpoint itself.
+type StreamEndpointExecuteInterface[TReq any, TItem any] interface {
+	EndpointInterface
+
+	// Execute executes the API endpoint, returning an iterator over the items
+	// to be sent to the client. Items are written as they're produced, and
+	// iteration stops early if one can't be written because the client
+	// disconnected.
+	//
+	// An error yielded before any items is written as a normal API error
+	// response. Afterwards, the response's status has already been sent, so
+	// the error is sent in a trailing error object instead. See
+	// StreamResponse.
+	//
+	// This should be implemented by each specific API endpoint.
+	Execute(ctx context.Context, req *TReq) iter.Seq2[*TItem, error]
+}
+
+// StreamResponse is the body of a response from an endpoint mounted with
+// MountStream when it's streamed as JSON. Items are written to Data as
+// they're produced, and if an error occurs after the first item, the array
+// is closed and the error written to Error in the error format negotiated
+// for the request, which is why it's left as raw JSON.
+//
+// When streamed as newline-delimited JSON, every item is written on its own
+// line instead, followed by a final `{"error":...}` line if an error occurs
+// after the first item.
+type StreamResponse[TItem any] struct {
+	Data  []*TItem        `json:"data"`
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// MountStream mounts an endpoint that streams a list of items to a Go
+// http.ServeMux or a Router. Requests are decoded, bound, and validated the
+// same way as for endpoints mounted with Mount, and the same options apply,
+// including timeouts. Endpoints producing very long lists may want to push
+// their deadline back as they make progress with ExtendTimeout or
+// ResetTimeout.
+//
+// Items are streamed as a StreamResponse when JSON is negotiated, or as
+// newline-delimited JSON when MediaTypeNDJSON is. If
+// EndpointMeta.ResponseMediaTypes is empty, it's set to both so that either
+// is produced depending on the request's Accept header, with JSON preferred.
+// MountOpts.Encoders isn't used.
+func MountStream[TReq any, TItem any](mux Mux, apiEndpoint StreamEndpointExecuteInterface[TReq, TItem], opts *MountOpts) EndpointInterface {
+	router, opts := resolveRouter(mux, opts)
+
+	config := resolveMountConfig[TReq](apiEndpoint, router, opts)
+	meta := config.meta
+
+	if len(meta.ResponseMediaTypes) < 1 {
+		meta.ResponseMediaTypes = []string{MediaTypeJSON, MediaTypeNDJSON}
+	}
+
+	for _, mediaType := range meta.ResponseMediaTypes {
+		if !slices.Contains([]string{MediaTypeJSON, MediaTypeNDJSON}, strings.ToLower(mediaType)) {
+			panic(fmt.Sprintf("error resolving response encoders for %q: streamed responses can't be encoded as media type %q", meta.Pattern, mediaType))
+		}
+	}
+
+	config.timeout = meta.Timeout
+	if config.timeout == 0 {
+		config.timeout = opts.Timeout
+	}
+	if config.timeout == 0 {
+		config.timeout = DefaultTimeout
+	}
+
+	mountHandler(router, apiEndpoint, opts, config, reflect.TypeFor[TReq](), reflect.TypeFor[StreamResponse[TItem]](), func(w http.ResponseWriter, r *http.Request) {
+		executeStreamEndpoint(w, r, config, apiEndpoint.Execute)
+	})
+
+	return apiEndpoint
+}
+
+func executeStreamEndpoint[TReq any, TItem any](w http.ResponseWriter, r *http.Request, config *mountConfig, execute func(ctx context.Context, req *TReq) iter.Seq2[*TItem, error]) {
+	ctx := endpointContext(w, r, config)
+
+	if len(config.meta.ResponseMediaTypes) > 1 {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	ctx, cancel := withTimeout(ctx, config.timeout)
+	defer cancel()
+
+	defer removeMultipartFiles(r)
+
+	stream := &streamWriter{
+		responseCtl: http.NewResponseController(w),
+		statusCode:  config.meta.StatusCode,
+		w:           w,
+	}
+	defer stream.stop()
+
+	err := func() (err error) {
+		defer recoverPanic(&err)
+
+		accept := r.Header.Get("Accept")
+
+		mediaType, ok := mediatype.Negotiate(accept, config.meta.ResponseMediaTypes)
+		if !ok {
+			return notAcceptableError(accept, config.meta.ResponseMediaTypes)
+		}
+		stream.mediaType = strings.ToLower(mediaType)
+
+		req, err := parseRequest[TReq](ctx, w, r, config)
+		if err != nil {
+			return err
+		}
+
+		for item, err := range execute(ctx, req) {
+			if err != nil {
+				return err
+			}
+
+			if err := stream.writeItem(item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	// A client that disconnects is left with a truncated response, and
+	// there's no point trying to tell it about an error.
+	if r.Context().Err() != nil {
+		config.logger.DebugContext(ctx, "client disconnected from stream", slog.String("pattern", config.meta.Pattern))
+		return
+	}
+
+	if err == nil {
+		if err := stream.finish(ctx, nil); err != nil {
+			config.logger.DebugContext(ctx, "error finishing stream", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	apiErr := resolveError(ctx, r, config, err)
+
+	if !stream.isStarted() {
+		apiErr.Write(ctx, config.logger, w)
+		return
+	}
+
+	if err := stream.finish(ctx, apiErr); err != nil {
+		config.logger.DebugContext(ctx, "error sending stream error", slog.String("error", err.Error()))
+	}
+}
+
+// streamWriter writes the items of a streamed response, starting the response
+// with the first item so that errors that occur before it can still be sent
+// with an appropriate status code.
+//
+// Items are flushed at most once every streamFlushInterval so that quickly
+// produced items are batched together, with a timer making sure that items
+// written since the last flush reach the client even if the next item is slow
+// to arrive.
+type streamWriter struct {
+	flushTimer  *time.Timer
+	lastFlush   time.Time
+	mediaType   string
+	mu          sync.Mutex
+	numItems    int
+	responseCtl *http.ResponseController
+	started     bool
+	statusCode  int
+	stopped     bool
+	w           http.ResponseWriter
+}
+
+// writeItem writes an item to the response, flushing it to the client if
+// enough time has passed since the last flush, and scheduling a flush for
+// when it has otherwise.
+func (s *streamWriter) writeItem(item any) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("error encoding stream item: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var chunk []byte
+	switch {
+	case s.mediaType == MediaTypeNDJSON:
+		chunk = append(data, '\n')
+	case s.numItems > 0:
+		chunk = append([]byte{','}, data...)
+	default:
+		chunk = data
+	}
+
+	if err := s.write(chunk); err != nil {
+		return err
+	}
+
+	s.numItems++
+
+	if sinceFlush := time.Since(s.lastFlush); sinceFlush < streamFlushInterval {
+		if s.flushTimer == nil {
+			s.flushTimer = time.AfterFunc(streamFlushInterval-sinceFlush, s.delayedFlush)
+		}
+		return nil
+	}
+
+	return s.flush()
+}
+
+// delayedFlush is invoked by the flush timer to flush items that were written
+// since the last flush. Errors are left for the next write to find.
+func (s *streamWriter) delayedFlush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flushTimer = nil
+
+	if s.stopped {
+		return
+	}
+
+	_ = s.flush()
+}
+
+// finish ends the response, writing a trailing error object in the error
+// format negotiated for the request if apiErr is non-nil.
+func (s *streamWriter) finish(ctx context.Context, apiErr apierror.Interface) error {
+	var errData []byte
+	if apiErr != nil {
+		var err error
+		if errData, err = apierror.MarshalJSON(ctx, apiErr); err != nil {
+			return fmt.Errorf("error marshaling API error: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var trailer []byte
+	switch {
+	case s.mediaType == MediaTypeNDJSON && errData != nil:
+		trailer = []byte(`{"error":` + string(errData) + "}\n")
+	case s.mediaType == MediaTypeNDJSON:
+	case errData != nil:
+		trailer = []byte(`],"error":` + string(errData) + "}")
+	default:
+		trailer = []byte("]}")
+	}
+
+	if err := s.write(trailer); err != nil {
+		return err
+	}
+
+	return s.flush()
+}
+
+// flush flushes written items to the client. Must be called with the mutex
+// held.
+func (s *streamWriter) flush() error {
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+
+	s.lastFlush = time.Now()
+
+	if err := s.responseCtl.Flush(); err != nil {
+		return fmt.Errorf("error flushing stream: %w", err)
+	}
+
+	return nil
+}
+
+// isStarted returns true if the response has been started, after which its
+// status can no longer be changed.
+func (s *streamWriter) isStarted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.started
+}
+
+// stop stops any pending flush so that the response isn't written to after
+// the handler has returned.
+func (s *streamWriter) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+}
+
+// write writes a chunk of the response, starting the response first if it
+// hasn't been already. Must be called with the mutex held.
+func (s *streamWriter) write(chunk []byte) error {
+	if !s.started {
+		s.started = true
+
+		contentType := MediaTypeNDJSON
+		if s.mediaType != MediaTypeNDJSON {
+			contentType = "application/json; charset=utf-8"
+		}
+
+		s.w.Header().Set("Content-Type", contentType)
+		s.w.Header().Set("X-Accel-Buffering", "no") // disable buffering in Nginx
+		s.w.WriteHeader(s.statusCode)
+
+		if s.mediaType != MediaTypeNDJSON {
+			chunk = append([]byte(`{"data":[`), chunk...)
+		}
+	}
+
+	if len(chunk) < 1 {
+		return nil
+	}
+
+	if _, err := s.w.Write(chunk); err != nil {
+		return fmt.Errorf("error writing stream: %w", err)
+	}
+
+	return nil
+}
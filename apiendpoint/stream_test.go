@@ -0,0 +1,298 @@
+package apiendpoint
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestMountStream(t *testing.T) {
+	t.Parallel()
+
+	type testBundle struct {
+		endpoint *streamEndpoint
+		mux      *http.ServeMux
+	}
+
+	setup := func(t *testing.T, endpoint *streamEndpoint, opts *MountOpts) *testBundle {
+		t.Helper()
+
+		if opts == nil {
+			opts = &MountOpts{}
+		}
+		opts.Logger = riversharedtest.Logger(t)
+
+		mux := http.NewServeMux()
+		MountStream(mux, endpoint, opts)
+
+		return &testBundle{endpoint: endpoint, mux: mux}
+	}
+
+	serve := func(bundle *testBundle, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/stream?queue=default", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		recorder := httptest.NewRecorder()
+		bundle.mux.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{numItems: 3}, nil)
+
+		recorder := serve(bundle, "")
+		requireStatusAndResponse(t, http.StatusOK, `{"data":[{"id":1,"queue":"default"},{"id":2,"queue":"default"},{"id":3,"queue":"default"}]}`, recorder)
+		require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+		require.Equal(t, "Accept", recorder.Header().Get("Vary"))
+		require.True(t, recorder.Flushed)
+
+		resp := mustUnmarshalJSON[StreamResponse[jobEvent]](t, recorder.Body.Bytes())
+		require.Len(t, resp.Data, 3)
+		require.Nil(t, resp.Error)
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{numItems: 2}, nil)
+
+		recorder := serve(bundle, MediaTypeNDJSON)
+		requireStatusAndResponse(t, http.StatusOK, "{\"id\":1,\"queue\":\"default\"}\n{\"id\":2,\"queue\":\"default\"}\n", recorder)
+		require.Equal(t, MediaTypeNDJSON, recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("NoItems", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{}, nil)
+
+		requireStatusAndResponse(t, http.StatusOK, `{"data":[]}`, serve(bundle, ""))
+		requireStatusAndResponse(t, http.StatusOK, "", serve(bundle, MediaTypeNDJSON))
+	})
+
+	t.Run("ValidationError", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{numItems: 1}, nil)
+
+		recorder := httptest.NewRecorder()
+		bundle.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/jobs/stream", nil))
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		require.Equal(t, apierror.CodeValidationFailed, mustUnmarshalJSON[apierror.APIError](t, recorder.Body.Bytes()).Code)
+	})
+
+	t.Run("ErrorBeforeFirstItem", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{err: apierror.NewNotFound("Queue not found.")}, nil)
+
+		requireStatusAndJSONResponse(t, http.StatusNotFound, &apierror.APIError{Message: "Queue not found."}, serve(bundle, ""))
+		requireStatusAndJSONResponse(t, http.StatusNotFound, &apierror.APIError{Message: "Queue not found."}, serve(bundle, MediaTypeNDJSON))
+	})
+
+	t.Run("ErrorAfterFirstItemJSON", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{err: errors.New("database went away"), numItems: 1}, nil)
+
+		recorder := serve(bundle, "")
+		requireStatusAndResponse(t, http.StatusOK, `{"data":[{"id":1,"queue":"default"}],"error":{"code":"internal_error","message":"Internal server error. Check logs for more information."}}`, recorder)
+
+		resp := mustUnmarshalJSON[StreamResponse[jobEvent]](t, recorder.Body.Bytes())
+		require.Len(t, resp.Data, 1)
+		require.Equal(t, apierror.CodeInternalError, mustUnmarshalJSON[apierror.APIError](t, resp.Error).Code)
+	})
+
+	t.Run("ErrorAfterFirstItemNDJSON", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{err: errors.New("database went away"), numItems: 1}, nil)
+
+		requireStatusAndResponse(t, http.StatusOK,
+			"{\"id\":1,\"queue\":\"default\"}\n"+
+				"{\"error\":{\"code\":\"internal_error\",\"message\":\"Internal server error. Check logs for more information.\"}}\n",
+			serve(bundle, MediaTypeNDJSON),
+		)
+	})
+
+	t.Run("ErrorAfterFirstItemProblemDetailsJSON", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{err: apierror.NewNotFound("Queue not found."), numItems: 1}, &MountOpts{ErrorFormat: apierror.FormatProblemDetails})
+
+		recorder := serve(bundle, "")
+		requireStatusAndResponse(t, http.StatusOK, `{"data":[{"id":1,"queue":"default"}],"error":{"detail":"Queue not found.","status":404,"title":"Not Found","type":"about:blank"}}`, recorder)
+	})
+
+	t.Run("ErrorAfterFirstItemProblemDetailsNDJSON", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{err: apierror.NewNotFound("Queue not found."), numItems: 1}, nil)
+
+		// Problem details are negotiated by the Accept header.
+		requireStatusAndResponse(t, http.StatusOK,
+			"{\"id\":1,\"queue\":\"default\"}\n"+
+				"{\"error\":{\"detail\":\"Queue not found.\",\"status\":404,\"title\":\"Not Found\",\"type\":\"about:blank\"}}\n",
+			serve(bundle, MediaTypeNDJSON+", "+apierror.ContentTypeProblemDetails),
+		)
+	})
+
+	t.Run("PanicAfterFirstItem", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{numItems: 1, panicAfter: true}, nil)
+
+		requireStatusAndResponse(t, http.StatusOK, `{"data":[{"id":1,"queue":"default"}],"error":{"code":"internal_error","message":"Internal server error. Check logs for more information."}}`, serve(bundle, ""))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{numItems: 1, wait: time.Minute}, &MountOpts{Timeout: 10 * time.Millisecond})
+
+		requireStatusAndResponse(t, http.StatusOK, `{"data":[{"id":1,"queue":"default"}],"error":{"code":"timeout","message":"Request timed out after 10ms. Retrying the request might work."}}`, serve(bundle, ""))
+	})
+
+	t.Run("NotAcceptable", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{numItems: 1}, nil)
+
+		requireStatusAndJSONResponse(t, http.StatusNotAcceptable, &apierror.APIError{Code: apierror.CodeNotAcceptable, Message: "None of the media types in the Accept header (`text/csv`) can be produced. Available types: `application/json`, `application/x-ndjson`."}, serve(bundle, "text/csv"))
+	})
+
+	t.Run("ResponseMediaTypesRestricted", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{numItems: 1, responseMediaTypes: []string{MediaTypeNDJSON}}, nil)
+
+		recorder := serve(bundle, "")
+		requireStatusAndResponse(t, http.StatusOK, "{\"id\":1,\"queue\":\"default\"}\n", recorder)
+		require.Empty(t, recorder.Header().Get("Vary"))
+	})
+
+	t.Run("UnsupportedResponseMediaType", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, `error resolving response encoders for "GET /api/jobs/stream": streamed responses can't be encoded as media type "text/csv"`, func() {
+			MountStream(http.NewServeMux(), &streamEndpoint{responseMediaTypes: []string{"text/csv"}}, nil)
+		})
+	})
+
+	t.Run("SlowProducerFlushes", func(t *testing.T) {
+		t.Parallel()
+
+		// Items written in a burst shouldn't wait for the next one to be
+		// flushed, which here doesn't come for a minute.
+		bundle := setup(t, &streamEndpoint{numItems: 3, wait: time.Minute}, &MountOpts{Timeout: TimeoutNone})
+
+		server := httptest.NewServer(bundle.mux)
+		t.Cleanup(server.Close)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/jobs/stream?queue=default", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", MediaTypeNDJSON)
+
+		resp, err := server.Client().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for _, expected := range []string{`{"id":1,"queue":"default"}`, `{"id":2,"queue":"default"}`, `{"id":3,"queue":"default"}`} {
+			require.True(t, scanner.Scan(), "expected another line: %v", scanner.Err())
+			require.Equal(t, expected, scanner.Text())
+		}
+	})
+
+	t.Run("ClientDisconnect", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &streamEndpoint{numItems: 1, wait: time.Minute}, &MountOpts{Timeout: TimeoutNone})
+
+		ctx, cancel := context.WithCancel(t.Context())
+
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/jobs/stream?queue=default", nil)
+		recorder := httptest.NewRecorder()
+
+		go func() {
+			<-bundle.endpoint.started
+			cancel()
+		}()
+
+		bundle.mux.ServeHTTP(recorder, req)
+
+		// The response is left truncated without an error object.
+		requireStatusAndResponse(t, http.StatusOK, `{"data":[{"id":1,"queue":"default"}`, recorder)
+	})
+}
+
+//
+// streamEndpoint
+//
+
+type streamEndpoint struct {
+	Endpoint[eventsRequest, jobEvent]
+
+	err                error
+	numItems           int
+	panicAfter         bool
+	responseMediaTypes []string
+	started            chan struct{}
+	wait               time.Duration
+}
+
+func (e *streamEndpoint) Meta() *EndpointMeta {
+	e.started = make(chan struct{})
+
+	return &EndpointMeta{
+		Pattern:            "GET /api/jobs/stream",
+		ResponseMediaTypes: e.responseMediaTypes,
+		StatusCode:         http.StatusOK,
+	}
+}
+
+func (e *streamEndpoint) Execute(ctx context.Context, req *eventsRequest) iter.Seq2[*jobEvent, error] {
+	return func(yield func(*jobEvent, error) bool) {
+		for id := int64(1); id <= int64(e.numItems); id++ {
+			if !yield(&jobEvent{ID: id, Queue: req.Queue}, nil) {
+				return
+			}
+		}
+
+		if e.panicAfter {
+			panic("panic after first item")
+		}
+
+		if e.wait > 0 {
+			close(e.started)
+
+			select {
+			case <-ctx.Done():
+				yield(nil, ctx.Err())
+				return
+			case <-time.After(e.wait):
+			}
+		}
+
+		if e.err != nil {
+			yield(nil, e.err)
+		}
+	}
+}
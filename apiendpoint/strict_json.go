@@ -0,0 +1,199 @@
+package apiendpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/riverqueue/apiframe/internal/apireflect"
+)
+
+// strictJSONError is a violation of strict JSON decoding, like an unknown
+// field. Its message is suitable for public-facing consumption.
+type strictJSONError struct {
+	message string
+}
+
+func (e *strictJSONError) Error() string { return e.message }
+
+var jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]() //nolint:gochecknoglobals
+
+// checkStrictJSON checks a JSON request body against the type it's going to be
+// unmarshaled into, returning a *strictJSONError if an object has a field that
+// doesn't exist on its struct, an object has the same key more than once, or
+// there's data after the JSON value. encoding/json silently ignores all of
+// these.
+//
+// Other problems like syntax errors are returned as they come from the JSON
+// tokenizer, and are expected to be reported by a subsequent unmarshal
+// instead.
+func checkStrictJSON(data []byte, typ reflect.Type) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := checkStrictJSONValue(dec, typ, ""); err != nil {
+		return err
+	}
+
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return &strictJSONError{message: "Request body contains unexpected data after its JSON value."}
+	}
+
+	return nil
+}
+
+// checkStrictJSONValue checks the next JSON value from the decoder against
+// typ. A nil typ means that the value is being unmarshaled into an interface
+// like `any`, so any object fields are allowed, but duplicate keys are still
+// checked.
+func checkStrictJSONValue(dec *json.Decoder, typ reflect.Type, path string) error {
+	for typ != nil {
+		if valueType, ok := apireflect.ExplicitNullableValueType(typ); ok {
+			typ = valueType
+			continue
+		}
+
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+			continue
+		}
+
+		break
+	}
+
+	if typ != nil {
+		if typ.Kind() == reflect.Interface {
+			typ = nil
+		} else if reflect.PointerTo(typ).Implements(jsonUnmarshalerType) || reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+			// Types with custom unmarshaling decide for themselves what they
+			// accept.
+			return skipJSONValue(dec, 0)
+		}
+	}
+
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		return checkStrictJSONObject(dec, typ, path)
+
+	case json.Delim('['):
+		var elemType reflect.Type
+		if typ != nil {
+			switch typ.Kind() { //nolint:exhaustive
+			case reflect.Array, reflect.Slice:
+				elemType = typ.Elem()
+			default:
+				// Mismatched types are left for the unmarshal to report.
+				return skipJSONValue(dec, 1)
+			}
+		}
+
+		for i := 0; dec.More(); i++ {
+			if err := checkStrictJSONValue(dec, elemType, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+		_, err := dec.Token() // closing `]`
+		return err
+	}
+
+	return nil
+}
+
+// checkStrictJSONObject checks the fields of a JSON object whose opening brace
+// has already been read from the decoder.
+func checkStrictJSONObject(dec *json.Decoder, typ reflect.Type, path string) error {
+	var fields []*apireflect.Field
+
+	if typ != nil {
+		switch typ.Kind() { //nolint:exhaustive
+		case reflect.Map:
+		case reflect.Struct:
+			fields = apireflect.JSONFields(typ)
+		default:
+			// Mismatched types are left for the unmarshal to report.
+			return skipJSONValue(dec, 1)
+		}
+	}
+
+	seen := make(map[string]struct{})
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		var (
+			key       = token.(string) //nolint:forcetypeassert
+			keyPath   = key
+			seenKey   = key
+			valueType reflect.Type
+		)
+
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		if typ != nil {
+			switch typ.Kind() { //nolint:exhaustive
+			case reflect.Map:
+				valueType = typ.Elem()
+
+			case reflect.Struct:
+				field := apireflect.LookupJSONField(fields, key)
+				if field == nil {
+					return &strictJSONError{message: fmt.Sprintf("Unknown field `%s` in request body.", keyPath)}
+				}
+
+				// Keys that differ only by case go to the same field, so
+				// they're considered duplicates of each other.
+				seenKey = field.Name
+				valueType = field.StructField.Type
+			}
+		}
+
+		if _, ok := seen[seenKey]; ok {
+			return &strictJSONError{message: fmt.Sprintf("Duplicate field `%s` in request body.", keyPath)}
+		}
+		seen[seenKey] = struct{}{}
+
+		if err := checkStrictJSONValue(dec, valueType, keyPath); err != nil {
+			return err
+		}
+	}
+
+	_, err := dec.Token() // closing `}`
+	return err
+}
+
+// skipJSONValue reads tokens from the decoder until the end of the current
+// value. depth is the number of objects or arrays that have already been
+// opened, so it's zero to skip an entire value that hasn't been started yet.
+func skipJSONValue(dec *json.Decoder, depth int) error {
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
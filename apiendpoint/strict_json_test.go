@@ -0,0 +1,114 @@
+package apiendpoint
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apitype"
+)
+
+func TestCheckStrictJSON(t *testing.T) {
+	t.Parallel()
+
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	type embedded struct {
+		Cursor string `json:"cursor"`
+	}
+
+	type request struct {
+		embedded
+
+		Attrs    map[string]any                 `json:"attrs"`
+		ID       int64                          `json:"-"        path:"id"`
+		Ignored  string                         `json:"-"`
+		Items    []*item                        `json:"items"`
+		Meta     any                            `json:"meta"`
+		Nullable apitype.ExplicitNullable[item] `json:"nullable"`
+		Raw      json.RawMessage                `json:"raw"`
+		Time     time.Time                      `json:"time"`
+		Title    string                         `json:"title"`
+	}
+
+	check := func(data string) error {
+		return checkStrictJSON([]byte(data), reflect.TypeFor[request]())
+	}
+
+	requireStrictErr := func(t *testing.T, expectedMessage string, err error) {
+		t.Helper()
+
+		var strictErr *strictJSONError
+		require.ErrorAs(t, err, &strictErr)
+		require.Equal(t, expectedMessage, strictErr.message)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, check(`{
+			"attrs": {"a": {"b": 1}},
+			"cursor": "abc",
+			"items": [{"name": "a"}, {"name": "b"}],
+			"meta": {"anything": [1, 2]},
+			"nullable": {"name": "a"},
+			"raw": {"whatever": {"x": 1, "x": 2}},
+			"time": "2025-01-02T03:04:05Z",
+			"Title": "case-insensitive"
+		}`))
+		require.NoError(t, check(`{"nullable": null}`))
+		require.NoError(t, check("{}\n"))
+	})
+
+	t.Run("UnknownField", func(t *testing.T) {
+		t.Parallel()
+
+		requireStrictErr(t, "Unknown field `titel` in request body.", check(`{"titel": "x"}`))
+		requireStrictErr(t, "Unknown field `items[1].nmae` in request body.", check(`{"items": [{"name": "a"}, {"nmae": "b"}]}`))
+		requireStrictErr(t, "Unknown field `nullable.nmae` in request body.", check(`{"nullable": {"nmae": "a"}}`))
+	})
+
+	t.Run("IgnoredAndBoundFieldsUnknown", func(t *testing.T) {
+		t.Parallel()
+
+		requireStrictErr(t, "Unknown field `Ignored` in request body.", check(`{"Ignored": "x"}`))
+		requireStrictErr(t, "Unknown field `ID` in request body.", check(`{"ID": 123}`))
+	})
+
+	t.Run("DuplicateField", func(t *testing.T) {
+		t.Parallel()
+
+		requireStrictErr(t, "Duplicate field `title` in request body.", check(`{"title": "a", "title": "b"}`))
+		requireStrictErr(t, "Duplicate field `Title` in request body.", check(`{"title": "a", "Title": "b"}`))
+		requireStrictErr(t, "Duplicate field `attrs.a` in request body.", check(`{"attrs": {"a": 1, "a": 2}}`))
+		requireStrictErr(t, "Duplicate field `meta.x.y` in request body.", check(`{"meta": {"x": {"y": 1, "y": 2}}}`))
+	})
+
+	t.Run("TrailingData", func(t *testing.T) {
+		t.Parallel()
+
+		requireStrictErr(t, "Request body contains unexpected data after its JSON value.", check(`{"title": "a"} {"title": "b"}`))
+		requireStrictErr(t, "Request body contains unexpected data after its JSON value.", check(`{"title": "a"} x`))
+	})
+
+	t.Run("MismatchedTypesLeftForUnmarshal", func(t *testing.T) {
+		t.Parallel()
+
+		require.NoError(t, check(`{"title": {"nested": 1}, "items": {"name": "a"}, "cursor": [1]}`))
+	})
+
+	t.Run("SyntaxErrorNotStrictError", func(t *testing.T) {
+		t.Parallel()
+
+		err := check(`{"title": `)
+		require.Error(t, err)
+
+		var strictErr *strictJSONError
+		require.NotErrorAs(t, err, &strictErr)
+	})
+}
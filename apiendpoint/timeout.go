@@ -0,0 +1,197 @@
+package apiendpoint
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the timeout applied to an endpoint's execution when neither
+// EndpointMeta.Timeout nor MountOpts.Timeout is set.
+const DefaultTimeout = 10 * time.Second
+
+// TimeoutNone can be assigned to EndpointMeta.Timeout or MountOpts.Timeout to
+// disable the request deadline entirely. Any negative duration has the same
+// effect.
+const TimeoutNone time.Duration = -1
+
+// ExtendTimeout pushes the deadline of an endpoint's context back by the given
+// duration. It's meant for use by long running or streaming endpoints that make
+// incremental progress and don't want to be cut off by the timeout configured
+// with EndpointMeta.Timeout or MountOpts.Timeout.
+//
+// Returns false if the context isn't one created for an endpoint with a timeout
+// or the timeout has already elapsed, in which case nothing was changed.
+func ExtendTimeout(ctx context.Context, d time.Duration) bool {
+	timeoutCtx, ok := ctx.Value(timeoutContextKey{}).(*timeoutContext)
+	if !ok {
+		return false
+	}
+
+	return timeoutCtx.setDeadline(func(deadline time.Time) (time.Time, time.Duration) {
+		return deadline.Add(d), timeoutCtx.limit + d
+	})
+}
+
+// ResetTimeout resets the deadline of an endpoint's context so that it's given
+// its full configured timeout again, measured from now. This is useful for
+// endpoints that want a timeout to apply to the time between units of progress
+// rather than to the request as a whole.
+//
+// Returns false if the context isn't one created for an endpoint with a timeout
+// or the timeout has already elapsed, in which case nothing was changed.
+func ResetTimeout(ctx context.Context) bool {
+	timeoutCtx, ok := ctx.Value(timeoutContextKey{}).(*timeoutContext)
+	if !ok {
+		return false
+	}
+
+	return timeoutCtx.setDeadline(func(_ time.Time) (time.Time, time.Duration) {
+		return time.Now().Add(timeoutCtx.initialLimit), timeoutCtx.initialLimit
+	})
+}
+
+type timeoutContextKey struct{}
+
+// timeoutContext is similar to a context produced by context.WithTimeout, but
+// whose deadline can be moved after it's been created. The standard library
+// doesn't provide a way to do this, so it's implemented as a custom context.
+type timeoutContext struct {
+	context.Context //nolint:containedctx
+
+	done         chan struct{}
+	initialLimit time.Duration
+	stopParent   func() bool
+
+	mu       sync.Mutex
+	deadline time.Time
+	err      error
+	limit    time.Duration
+	timedOut bool
+	timer    *time.Timer
+}
+
+// withTimeout returns a context that's cancelled after the given timeout, or a
+// plain cancellable context if timeout is negative.
+func withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout < 0 {
+		return context.WithCancel(parent)
+	}
+
+	ctx := &timeoutContext{
+		Context:      parent,
+		deadline:     time.Now().Add(timeout),
+		done:         make(chan struct{}),
+		initialLimit: timeout,
+		limit:        timeout,
+	}
+
+	// Both callbacks take the mutex, so hold it until both fields are set in
+	// case one of them fires immediately.
+	ctx.mu.Lock()
+	ctx.stopParent = context.AfterFunc(parent, func() { ctx.cancel(parent.Err(), false) })
+	ctx.timer = time.AfterFunc(timeout, ctx.expire)
+	ctx.mu.Unlock()
+
+	// context.AfterFunc runs asynchronously, so make sure that a context
+	// derived from an already finished parent is finished right away.
+	if err := parent.Err(); err != nil {
+		ctx.cancel(err, false)
+	}
+
+	return ctx, func() { ctx.cancel(context.Canceled, false) }
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+
+	if parentDeadline, ok := c.Context.Deadline(); ok && parentDeadline.Before(deadline) {
+		return parentDeadline, true
+	}
+
+	return deadline, true
+}
+
+func (c *timeoutContext) Done() <-chan struct{} { return c.done }
+
+func (c *timeoutContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *timeoutContext) Value(key any) any {
+	if _, ok := key.(timeoutContextKey); ok {
+		return c
+	}
+
+	return c.Context.Value(key)
+}
+
+func (c *timeoutContext) cancel(err error, timedOut bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancelLocked(err, timedOut)
+}
+
+// cancelLocked is cancel for a caller that's already holding the mutex.
+func (c *timeoutContext) cancelLocked(err error, timedOut bool) {
+	if c.err != nil {
+		return
+	}
+
+	c.err = err
+	c.timedOut = timedOut
+	c.stopParent()
+	c.timer.Stop()
+	close(c.done)
+}
+
+// expire is invoked when the context's timer fires. The deadline is checked
+// again in case it was moved while the timer was firing, in which case the
+// reset timer will fire again later. The check and cancellation happen under
+// the same lock so that a deadline moved by ExtendTimeout or ResetTimeout
+// (which then return true) can't be cancelled anyway.
+func (c *timeoutContext) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().Before(c.deadline) {
+		return
+	}
+
+	c.cancelLocked(context.DeadlineExceeded, true)
+}
+
+func (c *timeoutContext) setDeadline(newDeadlineAndLimit func(deadline time.Time) (time.Time, time.Duration)) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return false
+	}
+
+	c.deadline, c.limit = newDeadlineAndLimit(c.deadline)
+	c.timer.Reset(time.Until(c.deadline))
+
+	return true
+}
+
+// timeoutExceeded returns the limit that was exceeded if the given context was
+// produced by withTimeout and was cancelled because its own timeout elapsed (as
+// opposed to a deadline or cancellation inherited from its parent).
+func timeoutExceeded(ctx context.Context) (time.Duration, bool) {
+	timeoutCtx, ok := ctx.Value(timeoutContextKey{}).(*timeoutContext)
+	if !ok {
+		return 0, false
+	}
+
+	timeoutCtx.mu.Lock()
+	defer timeoutCtx.mu.Unlock()
+
+	return timeoutCtx.limit, timeoutCtx.timedOut
+}
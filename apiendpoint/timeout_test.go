@@ -0,0 +1,221 @@
+package apiendpoint
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Expires", func(t *testing.T) {
+		t.Parallel()
+
+		timeoutCtx, cancel := withTimeout(ctx, 10*time.Millisecond)
+		t.Cleanup(cancel)
+
+		_, ok := timeoutCtx.Deadline()
+		require.True(t, ok)
+
+		<-timeoutCtx.Done()
+		require.ErrorIs(t, timeoutCtx.Err(), context.DeadlineExceeded)
+
+		limit, ok := timeoutExceeded(timeoutCtx)
+		require.True(t, ok)
+		require.Equal(t, 10*time.Millisecond, limit)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		t.Parallel()
+
+		timeoutCtx, cancel := withTimeout(ctx, time.Hour)
+		cancel()
+
+		<-timeoutCtx.Done()
+		require.ErrorIs(t, timeoutCtx.Err(), context.Canceled)
+
+		_, ok := timeoutExceeded(timeoutCtx)
+		require.False(t, ok)
+	})
+
+	t.Run("ParentCancelled", func(t *testing.T) {
+		t.Parallel()
+
+		parentCtx, parentCancel := context.WithCancel(ctx)
+
+		timeoutCtx, cancel := withTimeout(parentCtx, time.Hour)
+		t.Cleanup(cancel)
+
+		parentCancel()
+
+		<-timeoutCtx.Done()
+		require.ErrorIs(t, timeoutCtx.Err(), context.Canceled)
+
+		_, ok := timeoutExceeded(timeoutCtx)
+		require.False(t, ok)
+	})
+
+	t.Run("ParentDeadlineEarlier", func(t *testing.T) {
+		t.Parallel()
+
+		parentDeadline := time.Now().Add(time.Minute)
+
+		parentCtx, parentCancel := context.WithDeadline(ctx, parentDeadline)
+		t.Cleanup(parentCancel)
+
+		timeoutCtx, cancel := withTimeout(parentCtx, time.Hour)
+		t.Cleanup(cancel)
+
+		deadline, ok := timeoutCtx.Deadline()
+		require.True(t, ok)
+		require.Equal(t, parentDeadline, deadline)
+	})
+
+	t.Run("Negative", func(t *testing.T) {
+		t.Parallel()
+
+		timeoutCtx, cancel := withTimeout(ctx, TimeoutNone)
+		t.Cleanup(cancel)
+
+		_, ok := timeoutCtx.Deadline()
+		require.False(t, ok)
+
+		require.False(t, ExtendTimeout(timeoutCtx, time.Second))
+		require.False(t, ResetTimeout(timeoutCtx))
+	})
+}
+
+func TestExtendTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Extends", func(t *testing.T) {
+		t.Parallel()
+
+		timeoutCtx, cancel := withTimeout(ctx, 20*time.Millisecond)
+		t.Cleanup(cancel)
+
+		deadlineBefore, _ := timeoutCtx.Deadline()
+		require.True(t, ExtendTimeout(timeoutCtx, time.Hour))
+
+		deadlineAfter, _ := timeoutCtx.Deadline()
+		require.Equal(t, deadlineBefore.Add(time.Hour), deadlineAfter)
+
+		// Would have expired without the extension.
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, timeoutCtx.Err())
+	})
+
+	t.Run("ChildContext", func(t *testing.T) {
+		t.Parallel()
+
+		timeoutCtx, cancel := withTimeout(ctx, time.Hour)
+		t.Cleanup(cancel)
+
+		childCtx, childCancel := context.WithCancel(timeoutCtx)
+		t.Cleanup(childCancel)
+		require.True(t, ExtendTimeout(childCtx, time.Second))
+	})
+
+	t.Run("AfterExpiry", func(t *testing.T) {
+		t.Parallel()
+
+		timeoutCtx, cancel := withTimeout(ctx, time.Millisecond)
+		t.Cleanup(cancel)
+
+		<-timeoutCtx.Done()
+		require.False(t, ExtendTimeout(timeoutCtx, time.Hour))
+	})
+
+	t.Run("NotEndpointContext", func(t *testing.T) {
+		t.Parallel()
+
+		require.False(t, ExtendTimeout(ctx, time.Hour))
+	})
+
+	t.Run("RacesExpiry", func(t *testing.T) {
+		t.Parallel()
+
+		// Run expiry at the same time as an extension. Whichever wins, an
+		// extension that reports success must leave the context running.
+		for range 10_000 {
+			timeoutCtx, cancel := withTimeout(ctx, time.Hour)
+			t.Cleanup(cancel)
+
+			tc := timeoutCtx.(*timeoutContext) //nolint:forcetypeassert
+			tc.mu.Lock()
+			tc.deadline = time.Now().Add(-time.Second)
+			tc.mu.Unlock()
+
+			var (
+				extended bool
+				start    = make(chan struct{})
+				wg       sync.WaitGroup
+			)
+			wg.Go(func() { <-start; tc.expire() })
+			wg.Go(func() { <-start; extended = ExtendTimeout(timeoutCtx, 2*time.Second) })
+			close(start)
+			wg.Wait()
+
+			if extended {
+				require.NoError(t, timeoutCtx.Err())
+			} else {
+				require.ErrorIs(t, timeoutCtx.Err(), context.DeadlineExceeded)
+			}
+		}
+	})
+
+	t.Run("ExceededLimitIncludesExtension", func(t *testing.T) {
+		t.Parallel()
+
+		timeoutCtx, cancel := withTimeout(ctx, 5*time.Millisecond)
+		t.Cleanup(cancel)
+
+		require.True(t, ExtendTimeout(timeoutCtx, 5*time.Millisecond))
+
+		<-timeoutCtx.Done()
+
+		limit, ok := timeoutExceeded(timeoutCtx)
+		require.True(t, ok)
+		require.Equal(t, 10*time.Millisecond, limit)
+	})
+}
+
+func TestResetTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Resets", func(t *testing.T) {
+		t.Parallel()
+
+		timeoutCtx, cancel := withTimeout(ctx, 50*time.Millisecond)
+		t.Cleanup(cancel)
+
+		// Keep resetting for longer than the original timeout.
+		for range 5 {
+			time.Sleep(20 * time.Millisecond)
+			require.True(t, ResetTimeout(timeoutCtx))
+		}
+		require.NoError(t, timeoutCtx.Err())
+
+		<-timeoutCtx.Done()
+
+		limit, ok := timeoutExceeded(timeoutCtx)
+		require.True(t, ok)
+		require.Equal(t, 50*time.Millisecond, limit)
+	})
+
+	t.Run("NotEndpointContext", func(t *testing.T) {
+		t.Parallel()
+
+		require.False(t, ResetTimeout(ctx))
+	})
+}
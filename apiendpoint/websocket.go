@@ -0,0 +1,499 @@
+package apiendpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/internal/validate"
+)
+
+// DefaultWebSocketMaxMessageBytes is the maximum size of messages received on
+// WebSocket connections when WebSocketOpts.MaxMessageBytes isn't set.
+const DefaultWebSocketMaxMessageBytes int64 = 32 << 10 // 32 KB
+
+// DefaultWebSocketPongTimeout is how long to wait for a pong after pinging a
+// WebSocket client when WebSocketOpts.PongTimeout isn't set.
+const DefaultWebSocketPongTimeout = 10 * time.Second
+
+// webSocketReceiveBufferSize is the number of messages read from a WebSocket
+// client that are buffered while waiting for the endpoint to receive them.
+const webSocketReceiveBufferSize = 16
+
+// ErrWebSocketClosed is returned by WebSocketConn.Receive when the client has
+// closed the connection or it's been lost. An endpoint that returns it (or an
+// error wrapping it) from Execute is considered to have finished normally.
+var ErrWebSocketClosed = errors.New("WebSocket connection closed by client")
+
+// WebSocketCloseCode is a status code sent to a WebSocket client when closing
+// its connection, as defined by RFC 6455. Applications may use their own codes
+// in the range 4000 to 4999.
+type WebSocketCloseCode int
+
+const (
+	WebSocketCloseNormal          WebSocketCloseCode = 1000
+	WebSocketCloseGoingAway       WebSocketCloseCode = 1001
+	WebSocketClosePolicyViolation WebSocketCloseCode = 1008
+	WebSocketCloseMessageTooBig   WebSocketCloseCode = 1009
+	WebSocketCloseInternalError   WebSocketCloseCode = 1011
+	WebSocketCloseTryAgainLater   WebSocketCloseCode = 1013
+)
+
+// WebSocketOpts are options for endpoints mounted with MountWebSocket, given
+// to it with MountOpts.WebSocket.
+type WebSocketOpts struct {
+	// MaxMessageBytes is the maximum size of messages received from clients
+	// in bytes. A client that sends a larger message has its connection
+	// closed with WebSocketCloseMessageTooBig. If not specified,
+	// DefaultWebSocketMaxMessageBytes is used. Set to a negative value to
+	// disable the limit.
+	MaxMessageBytes int64
+
+	// OriginPatterns are host patterns like `*.example.com` for origins that
+	// are allowed to open connections from a browser in addition to the
+	// endpoint's own host. If not specified, only same-origin connections
+	// are allowed, which protects against cross-site WebSocket hijacking.
+	OriginPatterns []string
+
+	// PongTimeout is how long to wait for a pong after a ping before the
+	// client is considered unresponsive and its connection is closed. Pings
+	// are sent every MountOpts.HeartbeatInterval. If not specified,
+	// DefaultWebSocketPongTimeout is used.
+	PongTimeout time.Duration
+}
+
+// WebSocketEndpointExecuteInterface is an interface to an API endpoint that
+// holds a bidirectional WebSocket connection with the client, receiving
+// messages of type TIn and sending messages of type TOut. Like
+// EndpointExecuteInterface, some of it is implemented by an embedded Endpoint
+// struct, and some of it should be implemented by the endpoint itself.
+type WebSocketEndpointExecuteInterface[TReq any, TIn any, TOut any] interface {
+	EndpointInterface
+
+	// Execute executes the API endpoint after the connection has been
+	// upgraded to a WebSocket, receiving and sending messages with conn until
+	// it's done or ctx is cancelled because the client disconnected. The
+	// request struct is bound from the upgrade request, so it can contain
+	// things like path variables or query parameters, but never a body.
+	//
+	// Returning nil closes the connection with WebSocketCloseNormal, unless
+	// it's already been closed with WebSocketConn.Close. Returning an error
+	// sends it in an error message before closing the connection with a code
+	// that depends on its status: WebSocketCloseTryAgainLater for a 503,
+	// WebSocketCloseInternalError for other 5xx statuses, and
+	// WebSocketClosePolicyViolation for everything else.
+	//
+	// This should be implemented by each specific API endpoint.
+	Execute(ctx context.Context, req *TReq, conn *WebSocketConn[TIn, TOut]) error
+}
+
+// WebSocketConn is a WebSocket connection to a client that's passed to the
+// Execute function of endpoints mounted with MountWebSocket. Messages are
+// encoded as JSON in both directions.
+//
+// Errors are sent to the client as an error message of the form
+// `{"error":{"code":...,"message":...}}`, so TOut shouldn't have a top-level
+// `error` field that would make them ambiguous.
+//
+// Messages are read from the client as they arrive and buffered until they're
+// received, so an endpoint that's busy with one message doesn't stop the
+// connection from being read. Once the buffer is full, reading stops until
+// the endpoint catches up.
+//
+// Receive may only be called by one goroutine at a time, but every other
+// function is safe for use by multiple goroutines.
+type WebSocketConn[TIn any, TOut any] struct {
+	backlogged  atomic.Bool
+	conn        *websocket.Conn
+	decoder     Decoder
+	lastRead    atomic.Int64 // Unix nanoseconds of the last message read
+	messages    chan *webSocketMessage
+	pongTimeout time.Duration
+	readDone    chan struct{}
+	readErr     error // set before messages and readDone are closed
+	request     *http.Request
+	validator   *validator.Validate
+}
+
+type webSocketMessage struct {
+	data []byte
+	typ  websocket.MessageType
+}
+
+// Close closes the connection with the given close code and reason, which
+// should be short and static because it's limited to 123 bytes.
+func (c *WebSocketConn[TIn, TOut]) Close(code WebSocketCloseCode, reason string) error {
+	if err := c.conn.Close(websocket.StatusCode(code), reason); err != nil {
+		return fmt.Errorf("error closing WebSocket: %w", err)
+	}
+
+	return nil
+}
+
+// Receive waits for the next message from the client, decodes it, and
+// validates it with the validator from MountOpts.
+//
+// A message that can't be decoded or that fails validation doesn't end the
+// connection. Instead, the problem is sent back to the client as an error
+// message, and Receive waits for the next one. Returns an error wrapping
+// ErrWebSocketClosed once the client closes the connection or it's lost.
+func (c *WebSocketConn[TIn, TOut]) Receive(ctx context.Context) (*TIn, error) {
+	for {
+		var (
+			message *webSocketMessage
+			ok      bool
+		)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case message, ok = <-c.messages:
+		}
+
+		if !ok {
+			return nil, c.readErr
+		}
+
+		msg, err := c.decode(ctx, message)
+		if err != nil {
+			var apiErr apierror.Interface
+			if !errors.As(err, &apiErr) {
+				return nil, err
+			}
+
+			if err := c.SendError(ctx, apiErr); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		return msg, nil
+	}
+}
+
+// Send sends a message to the client.
+func (c *WebSocketConn[TIn, TOut]) Send(ctx context.Context, msg *TOut) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshaling WebSocket message: %w", err)
+	}
+
+	return c.write(ctx, data)
+}
+
+// SendError sends an API error to the client in an error message without
+// closing the connection.
+func (c *WebSocketConn[TIn, TOut]) SendError(ctx context.Context, apiErr apierror.Interface) error {
+	data, err := json.Marshal(apiErr)
+	if err != nil {
+		return fmt.Errorf("error marshaling API error: %w", err)
+	}
+
+	return c.write(ctx, []byte(`{"error":`+string(data)+"}"))
+}
+
+func (c *WebSocketConn[TIn, TOut]) decode(ctx context.Context, message *webSocketMessage) (*TIn, error) {
+	if message.typ != websocket.MessageText {
+		return nil, apierror.WithCode(apierror.NewBadRequest("Binary messages aren't supported. Messages should be JSON sent as text."), apierror.CodeInvalidBody)
+	}
+
+	var msg TIn
+	if err := c.decoder.Decode(c.request, message.data, &msg); err != nil {
+		return nil, err
+	}
+
+	if err := c.validator.StructCtx(ctx, &msg); err != nil {
+		return nil, validate.PublicFacingError(c.validator, err)
+	}
+
+	return &msg, nil
+}
+
+// ping pings the client, closing the connection if it doesn't respond with a
+// pong in time.
+//
+// Pongs are only processed by the read loop, so a pong that arrives while the
+// loop is waiting for the endpoint to receive buffered messages can't be
+// seen. A client that's sent a message since the ping was sent has shown
+// it's still there though, so its connection is left open in that case.
+func (c *WebSocketConn[TIn, TOut]) ping(ctx context.Context) error {
+	// Not bound to ctx's cancellation, because a cancelled ping closes the
+	// connection, and it should stay open long enough for any final error
+	// message to be sent.
+	pingCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.pongTimeout)
+	defer cancel()
+
+	sentAt := time.Now().UnixNano()
+
+	if err := c.conn.Ping(pingCtx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			if c.backlogged.Load() || c.lastRead.Load() >= sentAt {
+				return nil
+			}
+
+			_ = c.conn.Close(websocket.StatusPolicyViolation, "pong timeout")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// readLoop reads messages from the client until the connection is closed.
+// Messages are read continuously into a buffer rather than only during calls
+// to Receive so that control frames like pongs are processed while the
+// endpoint is busy. If the buffer fills up, the loop is marked as backlogged
+// until the endpoint makes room in it.
+func (c *WebSocketConn[TIn, TOut]) readLoop(ctx context.Context) {
+	defer close(c.readDone)
+	defer close(c.messages)
+
+	for {
+		typ, data, err := c.conn.Read(ctx)
+		if err != nil {
+			c.readErr = fmt.Errorf("%w: %w", ErrWebSocketClosed, err)
+			return
+		}
+
+		c.lastRead.Store(time.Now().UnixNano())
+
+		message := &webSocketMessage{data: data, typ: typ}
+
+		select {
+		case c.messages <- message:
+			continue
+		default:
+		}
+
+		c.backlogged.Store(true)
+
+		select {
+		case <-ctx.Done():
+			c.readErr = ctx.Err()
+			return
+		case c.messages <- message:
+		}
+
+		c.backlogged.Store(false)
+	}
+}
+
+// isReadDone returns true if the read loop has stopped because the connection
+// was closed or lost.
+func (c *WebSocketConn[TIn, TOut]) isReadDone() bool {
+	select {
+	case <-c.readDone:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *WebSocketConn[TIn, TOut]) write(ctx context.Context, data []byte) error {
+	if err := c.conn.Write(ctx, websocket.MessageText, data); err != nil {
+		return fmt.Errorf("error writing WebSocket message: %w", err)
+	}
+
+	return nil
+}
+
+// MountWebSocket mounts an endpoint that holds a WebSocket connection with the
+// client to a Go http.ServeMux or a Router. The endpoint's pattern must use
+// the GET method, and its StatusCode must be http.StatusSwitchingProtocols.
+//
+// The upgrade request is bound and validated the same way as requests to
+// endpoints mounted with Mount, and problems with it are returned as normal
+// API errors before the connection is upgraded. The same options apply,
+// except that:
+//
+//   - No timeout is applied unless the endpoint sets EndpointMeta.Timeout,
+//     because connections are expected to stay open until the client
+//     disconnects. MountOpts.Timeout is ignored.
+//
+//   - The client is pinged every MountOpts.HeartbeatInterval, and its
+//     connection is closed if it doesn't respond in time. See WebSocketOpts.
+//
+//   - Messages are decoded as JSON, strictly if MountOpts.StrictJSON or
+//     EndpointMeta.StrictJSON is set. Other decoders and encoders aren't used.
+func MountWebSocket[TReq any, TIn any, TOut any](mux Mux, apiEndpoint WebSocketEndpointExecuteInterface[TReq, TIn, TOut], opts *MountOpts) EndpointInterface {
+	router, opts := resolveRouter(mux, opts)
+
+	config := resolveMountConfig[TReq](apiEndpoint, router, opts)
+	meta := config.meta
+
+	if !strings.HasPrefix(meta.Pattern, http.MethodGet+" ") {
+		panic(fmt.Sprintf("WebSocket endpoint %q must have a GET pattern", meta.Pattern))
+	}
+
+	if meta.StatusCode != http.StatusSwitchingProtocols {
+		panic(fmt.Sprintf("WebSocket endpoint %q must have StatusCode http.StatusSwitchingProtocols", meta.Pattern))
+	}
+
+	config.heartbeatInterval = opts.HeartbeatInterval
+	if config.heartbeatInterval == 0 {
+		config.heartbeatInterval = DefaultHeartbeatInterval
+	}
+
+	config.timeout = meta.Timeout
+	if config.timeout == 0 {
+		config.timeout = TimeoutNone
+	}
+
+	webSocketOpts := opts.WebSocket
+	if webSocketOpts == nil {
+		webSocketOpts = &WebSocketOpts{}
+	}
+
+	wsConfig := &webSocketConfig{
+		decoder:         &jsonDecoder{strict: meta.StrictJSON || opts.StrictJSON},
+		maxMessageBytes: webSocketOpts.MaxMessageBytes,
+		originPatterns:  webSocketOpts.OriginPatterns,
+		pongTimeout:     webSocketOpts.PongTimeout,
+	}
+	if wsConfig.maxMessageBytes == 0 {
+		wsConfig.maxMessageBytes = DefaultWebSocketMaxMessageBytes
+	}
+	if wsConfig.maxMessageBytes < 0 {
+		wsConfig.maxMessageBytes = -1
+	}
+	if wsConfig.pongTimeout == 0 {
+		wsConfig.pongTimeout = DefaultWebSocketPongTimeout
+	}
+
+	mountHandler(router, apiEndpoint, opts, config, reflect.TypeFor[TReq](), reflect.TypeFor[TOut](), func(w http.ResponseWriter, r *http.Request) {
+		executeWebSocketEndpoint(w, r, config, wsConfig, apiEndpoint.Execute)
+	})
+
+	return apiEndpoint
+}
+
+// webSocketConfig is the resolved configuration specific to a mounted
+// WebSocket endpoint.
+type webSocketConfig struct {
+	decoder         Decoder
+	maxMessageBytes int64
+	originPatterns  []string
+	pongTimeout     time.Duration
+}
+
+func executeWebSocketEndpoint[TReq any, TIn any, TOut any](w http.ResponseWriter, r *http.Request, config *mountConfig, wsConfig *webSocketConfig, execute func(ctx context.Context, req *TReq, conn *WebSocketConn[TIn, TOut]) error) {
+	ctx := endpointContext(w, r, config)
+
+	req, err := func() (req *TReq, err error) {
+		defer recoverPanic(&err)
+
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			return nil, apierror.WithCode(apierror.NewBadRequest("Expected a WebSocket upgrade request."), apierror.CodeInvalidParameter)
+		}
+
+		return parseRequest[TReq](ctx, w, r, config)
+	}()
+	if err != nil {
+		resolveError(ctx, r, config, err).Write(ctx, config.logger, w)
+		return
+	}
+
+	// Connections are long-lived, so they're exempt from any timeouts
+	// configured on the server, which would otherwise still apply after the
+	// connection is hijacked. Not all response writers support deadlines,
+	// which is fine.
+	responseCtl := http.NewResponseController(w)
+	_ = responseCtl.SetReadDeadline(time.Time{})
+	_ = responseCtl.SetWriteDeadline(time.Time{})
+
+	wsConn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: wsConfig.originPatterns})
+	if err != nil {
+		// Accept has already responded with an appropriate error.
+		config.logger.InfoContext(ctx, "error accepting WebSocket connection", slog.String("error", err.Error()))
+		return
+	}
+	wsConn.SetReadLimit(wsConfig.maxMessageBytes)
+
+	conn := &WebSocketConn[TIn, TOut]{
+		conn:        wsConn,
+		decoder:     wsConfig.decoder,
+		messages:    make(chan *webSocketMessage, webSocketReceiveBufferSize),
+		pongTimeout: wsConfig.pongTimeout,
+		readDone:    make(chan struct{}),
+		request:     r,
+		validator:   config.validator,
+	}
+
+	// The endpoint's context is cancelled when the connection is lost, but
+	// the read loop's is independent of it, because a cancelled read closes
+	// the connection before any final error message can be sent.
+	ctx, cancelConn := context.WithCancel(ctx)
+	defer cancelConn()
+
+	readCtx, cancelRead := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRead()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	wg.Go(func() {
+		defer cancelConn()
+		conn.readLoop(readCtx)
+	})
+
+	ctx, cancel := withTimeout(ctx, config.timeout)
+	defer cancel()
+
+	stopPings := startHeartbeats(ctx, config.heartbeatInterval, func() error { return conn.ping(ctx) })
+	defer stopPings()
+
+	err = func() (err error) {
+		defer recoverPanic(&err)
+
+		return execute(ctx, req, conn)
+	}()
+
+	// Final messages are sent with a context that isn't cancelled so that
+	// they're still sent after the endpoint has timed out.
+	closeCtx := context.WithoutCancel(ctx)
+
+	switch {
+	case err == nil:
+		_ = conn.Close(WebSocketCloseNormal, "")
+
+	case errors.Is(err, ErrWebSocketClosed) || (errors.Is(err, context.Canceled) && conn.isReadDone()):
+		config.logger.DebugContext(ctx, "client disconnected from WebSocket", slog.String("pattern", config.meta.Pattern))
+		_ = wsConn.CloseNow()
+
+	default:
+		apiErr := resolveError(ctx, r, config, err)
+
+		if err := conn.SendError(closeCtx, apiErr); err != nil {
+			config.logger.DebugContext(ctx, "error sending WebSocket error message", slog.String("error", err.Error()))
+		}
+
+		_ = conn.Close(webSocketErrorCloseCode(apiErr), http.StatusText(apiErr.GetStatusCode()))
+	}
+
+	cancelRead()
+}
+
+// webSocketErrorCloseCode returns the code that a WebSocket connection is
+// closed with after an endpoint returns an API error.
+func webSocketErrorCloseCode(apiErr apierror.Interface) WebSocketCloseCode {
+	switch statusCode := apiErr.GetStatusCode(); {
+	case statusCode == http.StatusServiceUnavailable:
+		return WebSocketCloseTryAgainLater
+	case statusCode >= http.StatusInternalServerError:
+		return WebSocketCloseInternalError
+	default:
+		return WebSocketClosePolicyViolation
+	}
+}
@@ -0,0 +1,419 @@
+package apiendpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestMountWebSocket(t *testing.T) {
+	t.Parallel()
+
+	type testBundle struct {
+		endpoint *consoleEndpoint
+		server   *httptest.Server
+	}
+
+	setup := func(t *testing.T, endpoint *consoleEndpoint, opts *MountOpts) *testBundle {
+		t.Helper()
+
+		if opts == nil {
+			opts = &MountOpts{}
+		}
+		opts.Logger = riversharedtest.Logger(t)
+
+		mux := http.NewServeMux()
+		MountWebSocket(mux, endpoint, opts)
+
+		// Track handlers so that the test waits for them to finish, because
+		// they outlive requests once connections are hijacked, and logging
+		// after a test has finished is an error.
+		var handlers sync.WaitGroup
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlers.Add(1)
+			defer handlers.Done()
+			mux.ServeHTTP(w, r)
+		}))
+		t.Cleanup(server.Close)
+		t.Cleanup(handlers.Wait)
+
+		return &testBundle{endpoint: endpoint, server: server}
+	}
+
+	dial := func(t *testing.T, bundle *testBundle, opts *websocket.DialOptions) *websocket.Conn {
+		t.Helper()
+
+		conn, _, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(bundle.server.URL, "http")+"/api/console?queue=default", opts)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.CloseNow() })
+
+		return conn
+	}
+
+	write := func(t *testing.T, conn *websocket.Conn, msg string) {
+		t.Helper()
+
+		require.NoError(t, conn.Write(t.Context(), websocket.MessageText, []byte(msg)))
+	}
+
+	read := func(t *testing.T, conn *websocket.Conn) string {
+		t.Helper()
+
+		_, data, err := conn.Read(t.Context())
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	readCloseStatus := func(t *testing.T, conn *websocket.Conn) websocket.StatusCode {
+		t.Helper()
+
+		_, _, err := conn.Read(t.Context())
+		require.Error(t, err)
+		return websocket.CloseStatus(err)
+	}
+
+	waitDone := func(t *testing.T, bundle *testBundle) error {
+		t.Helper()
+
+		select {
+		case err := <-bundle.endpoint.done:
+			return err
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "Timed out waiting for endpoint to return")
+			return nil
+		}
+	}
+
+	t.Run("SendsAndReceives", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+		conn := dial(t, bundle, nil)
+
+		write(t, conn, `{"command":"echo hello"}`)
+		require.JSONEq(t, `{"output":"hello","queue":"default"}`, read(t, conn))
+
+		write(t, conn, `{"command":"echo again"}`)
+		require.JSONEq(t, `{"output":"again","queue":"default"}`, read(t, conn))
+
+		require.NoError(t, conn.Close(websocket.StatusNormalClosure, ""))
+		require.ErrorIs(t, waitDone(t, bundle), ErrWebSocketClosed)
+	})
+
+	t.Run("InvalidMessageSendsErrorAndContinues", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+		conn := dial(t, bundle, nil)
+
+		write(t, conn, `{}`)
+		require.Equal(t, apierror.CodeValidationFailed, mustUnmarshalWebSocketError(t, read(t, conn)).Code)
+
+		write(t, conn, `{"command":`)
+		require.Equal(t, &apierror.APIError{Code: apierror.CodeInvalidBody, Message: "Request body contains incomplete JSON."}, mustUnmarshalWebSocketError(t, read(t, conn)))
+
+		require.NoError(t, conn.Write(t.Context(), websocket.MessageBinary, []byte(`{"command":"echo binary"}`)))
+		require.Equal(t, apierror.CodeInvalidBody, mustUnmarshalWebSocketError(t, read(t, conn)).Code)
+
+		// The connection is still usable.
+		write(t, conn, `{"command":"echo still here"}`)
+		require.JSONEq(t, `{"output":"still here","queue":"default"}`, read(t, conn))
+	})
+
+	t.Run("APIErrorClosesWithPolicyViolation", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+		conn := dial(t, bundle, nil)
+
+		write(t, conn, `{"command":"missing"}`)
+		require.Equal(t, &apierror.APIError{Message: "Command not found."}, mustUnmarshalWebSocketError(t, read(t, conn)))
+		require.Equal(t, websocket.StatusPolicyViolation, readCloseStatus(t, conn))
+	})
+
+	t.Run("InternalErrorClosesWithInternalError", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+		conn := dial(t, bundle, nil)
+
+		write(t, conn, `{"command":"crash"}`)
+		require.Equal(t, &apierror.APIError{Code: apierror.CodeInternalError, Message: "Internal server error. Check logs for more information."}, mustUnmarshalWebSocketError(t, read(t, conn)))
+		require.Equal(t, websocket.StatusInternalError, readCloseStatus(t, conn))
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+		conn := dial(t, bundle, nil)
+
+		write(t, conn, `{"command":"panic"}`)
+		require.Equal(t, apierror.CodeInternalError, mustUnmarshalWebSocketError(t, read(t, conn)).Code)
+		require.Equal(t, websocket.StatusInternalError, readCloseStatus(t, conn))
+	})
+
+	t.Run("EndpointReturnsNil", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+		conn := dial(t, bundle, nil)
+
+		write(t, conn, `{"command":"exit"}`)
+		require.Equal(t, websocket.StatusNormalClosure, readCloseStatus(t, conn))
+		require.NoError(t, waitDone(t, bundle))
+	})
+
+	t.Run("EndpointClosesWithCode", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+		conn := dial(t, bundle, nil)
+
+		write(t, conn, `{"command":"kick"}`)
+		require.Equal(t, websocket.StatusCode(4000), readCloseStatus(t, conn))
+	})
+
+	t.Run("MaxMessageBytes", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, &MountOpts{WebSocket: &WebSocketOpts{MaxMessageBytes: 32}})
+		conn := dial(t, bundle, nil)
+
+		write(t, conn, `{"command":"echo `+strings.Repeat("x", 32)+`"}`)
+		require.Equal(t, websocket.StatusMessageTooBig, readCloseStatus(t, conn))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{meta: EndpointMeta{Pattern: "GET /api/console", StatusCode: http.StatusSwitchingProtocols, Timeout: 10 * time.Millisecond}}, nil)
+		conn := dial(t, bundle, nil)
+
+		require.Equal(t, apierror.CodeTimeout, mustUnmarshalWebSocketError(t, read(t, conn)).Code)
+		require.Equal(t, websocket.StatusTryAgainLater, readCloseStatus(t, conn))
+	})
+
+	t.Run("Pings", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, &MountOpts{HeartbeatInterval: 5 * time.Millisecond})
+
+		var numPings atomic.Int64
+		conn := dial(t, bundle, &websocket.DialOptions{
+			OnPingReceived: func(ctx context.Context, payload []byte) bool {
+				numPings.Add(1)
+				return true
+			},
+		})
+
+		// Pings are only answered while the client reads.
+		readCtx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		_, _, err := conn.Read(readCtx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		require.GreaterOrEqual(t, numPings.Load(), int64(2))
+	})
+
+	t.Run("PongTimeout", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, &MountOpts{
+			HeartbeatInterval: 5 * time.Millisecond,
+			WebSocket:         &WebSocketOpts{PongTimeout: 10 * time.Millisecond},
+		})
+
+		conn := dial(t, bundle, &websocket.DialOptions{
+			OnPingReceived: func(ctx context.Context, payload []byte) bool { return false }, // never pong
+		})
+
+		require.Equal(t, websocket.StatusPolicyViolation, readCloseStatus(t, conn))
+		require.Error(t, waitDone(t, bundle))
+	})
+
+	t.Run("SlowEndpointKeepsReading", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, &MountOpts{
+			HeartbeatInterval: 10 * time.Millisecond,
+			WebSocket:         &WebSocketOpts{PongTimeout: 20 * time.Millisecond},
+		})
+		conn := dial(t, bundle, nil)
+
+		// The client keeps sending while the endpoint is busy, more than
+		// can be buffered, and pings that go out in the meantime mustn't
+		// time out waiting for pongs stuck behind its messages.
+		write(t, conn, `{"command":"sleep 200ms"}`)
+		for i := range webSocketReceiveBufferSize + 4 {
+			write(t, conn, `{"command":"echo `+strconv.Itoa(i)+`"}`)
+		}
+
+		require.JSONEq(t, `{"output":"slept 200ms","queue":"default"}`, read(t, conn))
+		for i := range webSocketReceiveBufferSize + 4 {
+			require.JSONEq(t, `{"output":"`+strconv.Itoa(i)+`","queue":"default"}`, read(t, conn))
+		}
+
+		require.NoError(t, conn.Close(websocket.StatusNormalClosure, ""))
+		require.ErrorIs(t, waitDone(t, bundle), ErrWebSocketClosed)
+	})
+
+	t.Run("NotUpgradeRequest", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+
+		resp, err := http.Get(bundle.server.URL + "/api/console?queue=default") //nolint:noctx
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		var apiErr apierror.APIError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiErr))
+		require.Equal(t, apierror.CodeInvalidParameter, apiErr.Code)
+		require.Equal(t, "Expected a WebSocket upgrade request.", apiErr.Message)
+	})
+
+	t.Run("InvalidUpgradeRequest", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+
+		_, resp, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(bundle.server.URL, "http")+"/api/console", nil)
+		require.Error(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var apiErr apierror.APIError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiErr))
+		require.Equal(t, apierror.CodeValidationFailed, apiErr.Code)
+	})
+
+	t.Run("CrossOriginRejected", func(t *testing.T) {
+		t.Parallel()
+
+		bundle := setup(t, &consoleEndpoint{}, nil)
+
+		_, resp, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(bundle.server.URL, "http")+"/api/console?queue=default", &websocket.DialOptions{
+			HTTPHeader: http.Header{"Origin": []string{"https://evil.example.com"}},
+		})
+		require.Error(t, err)
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("InvalidMeta", func(t *testing.T) {
+		t.Parallel()
+
+		require.PanicsWithValue(t, `WebSocket endpoint "POST /api/console" must have a GET pattern`, func() {
+			MountWebSocket(http.NewServeMux(), &consoleEndpoint{meta: EndpointMeta{Pattern: "POST /api/console", StatusCode: http.StatusSwitchingProtocols}}, nil)
+		})
+
+		require.PanicsWithValue(t, `WebSocket endpoint "GET /api/console" must have StatusCode http.StatusSwitchingProtocols`, func() {
+			MountWebSocket(http.NewServeMux(), &consoleEndpoint{meta: EndpointMeta{Pattern: "GET /api/console", StatusCode: http.StatusOK}}, nil)
+		})
+	})
+}
+
+func mustUnmarshalWebSocketError(t *testing.T, data string) *apierror.APIError {
+	t.Helper()
+
+	var msg struct {
+		Error *apierror.APIError `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &msg))
+	require.NotNil(t, msg.Error, "Expected error message; got: %s", data)
+	return msg.Error
+}
+
+//
+// consoleEndpoint
+//
+
+type consoleEndpoint struct {
+	Endpoint[eventsRequest, consoleOutput]
+
+	done chan error
+	meta EndpointMeta
+}
+
+func (e *consoleEndpoint) Meta() *EndpointMeta {
+	e.done = make(chan error, 1)
+
+	if e.meta.Pattern == "" {
+		e.meta = EndpointMeta{
+			Pattern:    "GET /api/console",
+			StatusCode: http.StatusSwitchingProtocols,
+		}
+	}
+
+	return &e.meta
+}
+
+type consoleCommand struct {
+	Command string `json:"command" validate:"required"`
+}
+
+type consoleOutput struct {
+	Output string `json:"output"`
+	Queue  string `json:"queue"`
+}
+
+func (e *consoleEndpoint) Execute(ctx context.Context, req *eventsRequest, conn *WebSocketConn[consoleCommand, consoleOutput]) error {
+	err := e.execute(ctx, req, conn)
+	e.done <- err
+	return err
+}
+
+func (e *consoleEndpoint) execute(ctx context.Context, req *eventsRequest, conn *WebSocketConn[consoleCommand, consoleOutput]) error {
+	for {
+		cmd, err := conn.Receive(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch name, arg, _ := strings.Cut(cmd.Command, " "); name {
+		case "crash":
+			return errors.New("console crashed")
+		case "echo":
+			if err := conn.Send(ctx, &consoleOutput{Output: arg, Queue: req.Queue}); err != nil {
+				return err
+			}
+		case "exit":
+			return nil
+		case "kick":
+			return conn.Close(4000, "kicked")
+		case "panic":
+			panic("console panicked")
+		case "sleep":
+			duration, err := time.ParseDuration(arg)
+			if err != nil {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(duration):
+			}
+
+			if err := conn.Send(ctx, &consoleOutput{Output: "slept " + arg, Queue: req.Queue}); err != nil {
+				return err
+			}
+		default:
+			return apierror.NewNotFound("Command not found.")
+		}
+	}
+}
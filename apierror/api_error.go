@@ -7,7 +7,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riverqueue/apiframe/internal/mediatype"
 )
 
 // APIError is a struct that's embedded on a more specific API error struct (as
@@ -16,6 +22,34 @@ import (
 //
 // APIErrorInterface should be used with errors.As instead of this struct.
 type APIError struct {
+	// Code is a stable, machine-readable code identifying the kind of error,
+	// like `job_not_found`, that clients can branch on instead of matching
+	// on Message (which may be reworded at any time). Omitted if empty. See
+	// Catalog for a way to keep track of codes.
+	Code string `json:"code,omitempty"`
+
+	// Errors are errors specific to individual fields of a request, like
+	// those produced by validation, so that clients can point out exactly
+	// which inputs were invalid. Message should still describe every
+	// problem. Omitted if empty.
+	Errors []*FieldError `json:"errors,omitempty"`
+
+	// Extensions are extra members included in the error when it's written as
+	// problem details (see FormatProblemDetails), like a request ID or a
+	// machine-readable reason. Members with the same name as a standard
+	// problem details member are ignored. Not included in the message-only
+	// format.
+	Extensions map[string]any `json:"-"`
+
+	// Header are extra headers written along with the error, like a
+	// Retry-After header for a 429. Usually set through methods on specific
+	// error types like TooManyRequests.WithRetryAfter.
+	Header http.Header `json:"-"`
+
+	// Instance is a URI reference identifying the specific occurrence of the
+	// problem, included when the error is written as problem details.
+	Instance string `json:"-"`
+
 	// InternalError is an additional error that might be associated with the
 	// API error. It's not returned in the API error response, but is logged in
 	// API endpoint execution to provide extra information for operators.
@@ -29,38 +63,274 @@ type APIError struct {
 	// StatusCode is the API error's HTTP status code. It's not marshaled to
 	// JSON, but determines how the error is written to a response.
 	StatusCode int `json:"-"`
+
+	// Title is a short, human-readable summary of the problem type, included
+	// when the error is written as problem details. Defaults to the status
+	// code's text, like "Not Found".
+	Title string `json:"-"`
+
+	// Type is a URI reference identifying the problem type, included when the
+	// error is written as problem details. Defaults to "about:blank", which
+	// indicates that the problem has no semantics beyond its status code.
+	Type string `json:"-"`
 }
 
 func (e *APIError) Error() string                      { return e.Message }
+func (e *APIError) GetCode() string                    { return e.Code }
 func (e *APIError) GetInternalError() error            { return e.InternalError }
+func (e *APIError) GetStatusCode() int                 { return e.StatusCode }
+func (e *APIError) SetCode(code string)                { e.Code = code }
 func (e *APIError) SetInternalError(internalErr error) { e.InternalError = internalErr }
 
 // Write writes the API error to an HTTP response, writing to the given logger
-// in case of a problem.
+// in case of a problem. The error is written in the format set on the context
+// with WithFormat, and in the message-only format by default. It's encoded
+// with the encoder set on the context with WithEncoder, or as JSON if there
+// isn't one or if the encoder fails.
 func (e *APIError) Write(ctx context.Context, logger *slog.Logger, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(e.StatusCode)
+	value, contentType := formatValue(ctx, e)
 
-	respData, err := json.Marshal(e)
+	respData, contentType, err := encodeWithContext(ctx, logger, value, contentType)
 	if err != nil {
 		logger.ErrorContext(ctx, "error marshaling API error", slog.String("error", err.Error()))
 	}
 
+	for name, values := range e.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(e.StatusCode)
+
 	if _, err := w.Write(respData); err != nil {
 		logger.ErrorContext(ctx, "error writing API error", slog.String("error", err.Error()))
 	}
 }
 
+// MarshalJSON marshals an API error to JSON in the format set on the context
+// with WithFormat, like Write, but ignoring any encoder set with WithEncoder.
+// It's for embedding an error in a response that's already underway, like in
+// an event of a Server-Sent Events stream.
+func MarshalJSON(ctx context.Context, apiErr Interface) ([]byte, error) {
+	value, _ := formatValue(ctx, apiErr)
+	return json.Marshal(value)
+}
+
+// formatValue returns the value that an API error is encoded from in the
+// format set on the context, along with the format's JSON content type.
+func formatValue(ctx context.Context, apiErr Interface) (any, string) {
+	if formatFromContext(ctx) == FormatProblemDetails {
+		if problemDetailer, ok := apiErr.(interface{ ProblemDetails() map[string]any }); ok {
+			return problemDetailer.ProblemDetails(), ContentTypeProblemDetails
+		}
+	}
+
+	return apiErr, "application/json; charset=utf-8"
+}
+
+// encodeWithContext encodes an API error's value with the encoder from the
+// context, returning the encoded value and its content type. If there's no
+// encoder or it fails, the value is marshaled to JSON with the given JSON
+// content type instead.
+func encodeWithContext(ctx context.Context, logger *slog.Logger, value any, jsonContentType string) ([]byte, string, error) {
+	if encoder := encoderFromContext(ctx); encoder != nil {
+		respData, err := encoder.Encode(value)
+		if err == nil {
+			return respData, encoder.ContentType(), nil
+		}
+
+		logger.ErrorContext(ctx, "error encoding API error; falling back to JSON",
+			slog.String("content_type", encoder.ContentType()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	respData, err := json.Marshal(value)
+	return respData, jsonContentType, err
+}
+
+// setHeader sets an extra header to be written along with the error.
+func (e *APIError) setHeader(name, value string) {
+	if e.Header == nil {
+		e.Header = make(http.Header)
+	}
+
+	e.Header.Set(name, value)
+}
+
+// ProblemDetails returns the members of the API error as RFC 9457 problem
+// details, as they're written in FormatProblemDetails.
+func (e *APIError) ProblemDetails() map[string]any {
+	members := make(map[string]any, len(e.Extensions)+5)
+	for name, value := range e.Extensions {
+		members[name] = value
+	}
+
+	problemType := e.Type
+	if problemType == "" {
+		problemType = "about:blank"
+	}
+
+	title := e.Title
+	if title == "" {
+		title = http.StatusText(e.StatusCode)
+	}
+
+	if e.Code != "" {
+		members["code"] = e.Code
+	}
+	if len(e.Errors) > 0 {
+		members["errors"] = e.Errors
+	}
+
+	members["detail"] = e.Message
+	members["status"] = e.StatusCode
+	members["title"] = title
+	members["type"] = problemType
+
+	if e.Instance != "" {
+		members["instance"] = e.Instance
+	} else {
+		delete(members, "instance")
+	}
+
+	return members
+}
+
+// FieldError is an error specific to a single field of a request.
+type FieldError struct {
+	// Field is the public name of the field, like `name`.
+	Field string `json:"field"`
+
+	// JSONPath is the full path to the field using public names, including
+	// any parent structs and slice indexes, like `items[3].name`. Empty for
+	// fields bound from outside the request body, like headers and query
+	// parameters.
+	JSONPath string `json:"json_path"`
+
+	// Message is a human-friendly message describing what's wrong with the
+	// field, like "Field `name` is required."
+	Message string `json:"message"`
+
+	// Param is the parameter of the rule that failed, like `100` for a
+	// `max=100` validation. Empty for rules without a parameter.
+	Param string `json:"param"`
+
+	// Tag is the name of the rule that failed, like `required` or `max`.
+	Tag string `json:"tag"`
+}
+
+// retryAfterSeconds formats a duration as the number of seconds in a
+// Retry-After header, rounding up so that clients don't retry too early.
+func retryAfterSeconds(retryAfter time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(max(retryAfter, 0).Seconds())), 10)
+}
+
+// ContentTypeProblemDetails is the media type of API errors written as RFC
+// 9457 problem details.
+const ContentTypeProblemDetails = "application/problem+json"
+
+// Format is a wire format that API errors are written in. The zero value
+// means that no format was specified, and is written like FormatMessage, so
+// that options with a Format can tell an explicit FormatMessage apart from
+// one that was left unset.
+type Format int
+
+const (
+	// FormatMessage writes API errors as a JSON object containing only a
+	// message, like `{"message":"Job not found."}`. This is the default.
+	FormatMessage Format = iota + 1
+
+	// FormatProblemDetails writes API errors as RFC 9457 problem details with
+	// a content type of `application/problem+json`, like:
+	//
+	//	{"detail":"Job not found.","status":404,"title":"Not Found","type":"about:blank"}
+	//
+	// The error's message becomes `detail`, and its Extensions are included
+	// as extra members.
+	FormatProblemDetails
+)
+
+type formatContextKey struct{}
+
+// WithFormat returns a context that makes API errors written with it use the
+// given format.
+func WithFormat(ctx context.Context, format Format) context.Context {
+	return context.WithValue(ctx, formatContextKey{}, format)
+}
+
+func formatFromContext(ctx context.Context) Format {
+	if format, ok := ctx.Value(formatContextKey{}).(Format); ok {
+		return format
+	}
+
+	return FormatMessage
+}
+
+// NegotiateFormat picks a format for API errors based on a request's Accept
+// header. Clients that explicitly accept `application/problem+json` get
+// FormatProblemDetails. Otherwise, defaultFormat is used.
+func NegotiateFormat(accept string, defaultFormat Format) Format {
+	for _, mediaRange := range mediatype.ParseAccept(accept) {
+		if mediaRange.MediaType == ContentTypeProblemDetails && mediaRange.Q > 0 {
+			return FormatProblemDetails
+		}
+	}
+
+	return defaultFormat
+}
+
+// Encoder encodes API errors in a media type other than JSON, like
+// MessagePack, so that they're written in a format that a client accepts. It
+// has the same methods as apiendpoint.Encoder, so an encoder for responses
+// can be used for errors too.
+type Encoder interface {
+	// ContentType is the Content-Type header written along with encoded
+	// errors, like `application/msgpack`.
+	ContentType() string
+
+	// Encode encodes an API error, or a map of its members if it's being
+	// written as problem details.
+	Encode(v any) ([]byte, error)
+}
+
+type encoderContextKey struct{}
+
+// WithEncoder returns a context that makes API errors written with it use the
+// given encoder instead of JSON.
+func WithEncoder(ctx context.Context, encoder Encoder) context.Context {
+	return context.WithValue(ctx, encoderContextKey{}, encoder)
+}
+
+func encoderFromContext(ctx context.Context) Encoder {
+	encoder, _ := ctx.Value(encoderContextKey{}).(Encoder)
+	return encoder
+}
+
 // Interface is an interface to an API error. This is needed for use with
 // errors.As because APIError itself is embedded on another error struct, and
 // won't be usable as an errors.As target.
 type Interface interface {
 	Error() string
+	GetCode() string
 	GetInternalError() error
+	GetStatusCode() int
+	SetCode(code string)
 	SetInternalError(internalErr error)
 	Write(ctx context.Context, logger *slog.Logger, w http.ResponseWriter)
 }
 
+// WithCode is a convenience function for assigning a machine-readable code to
+// the given API error and returning it:
+//
+//	return nil, apierror.WithCode(apierror.NewNotFoundf("Job not found: %d.", req.ID), codeJobNotFound)
+func WithCode[TAPIError Interface](apiErr TAPIError, code string) TAPIError {
+	apiErr.SetCode(code)
+	return apiErr
+}
+
 // WithInternalError is a convenience function for assigning an internal error
 // to the given API error and returning it.
 func WithInternalError[TAPIError Interface](apiErr TAPIError, internalErr error) TAPIError {
@@ -68,6 +338,49 @@ func WithInternalError[TAPIError Interface](apiErr TAPIError, internalErr error)
 	return apiErr
 }
 
+// FromStatusCode returns an API error of the type that corresponds to the given
+// HTTP status code, like a *NotFound for a 404. Status codes without a more
+// specific type produce a plain *APIError. It's useful for turning an error
+// response back into an API error on the client side.
+func FromStatusCode(statusCode int, message string) Interface {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return NewBadRequest(message)
+	case http.StatusConflict:
+		return NewConflict(message)
+	case http.StatusForbidden:
+		return NewForbidden(message)
+	case http.StatusGone:
+		return NewGone(message)
+	case http.StatusInternalServerError:
+		return NewInternalServerError(message)
+	case http.StatusMethodNotAllowed:
+		return NewMethodNotAllowed(message)
+	case http.StatusNotAcceptable:
+		return NewNotAcceptable(message)
+	case http.StatusNotFound:
+		return NewNotFound(message)
+	case http.StatusNotImplemented:
+		return NewNotImplemented(message)
+	case http.StatusPreconditionFailed:
+		return NewPreconditionFailed(message)
+	case http.StatusRequestEntityTooLarge:
+		return NewRequestEntityTooLarge(message)
+	case http.StatusServiceUnavailable:
+		return NewServiceUnavailable(message)
+	case http.StatusTooManyRequests:
+		return NewTooManyRequests(message)
+	case http.StatusUnauthorized:
+		return NewUnauthorized("%s", message)
+	case http.StatusUnprocessableEntity:
+		return NewUnprocessableEntity(message)
+	case http.StatusUnsupportedMediaType:
+		return NewUnsupportedMediaType(message)
+	}
+
+	return &APIError{Message: message, StatusCode: statusCode}
+}
+
 //
 // BadRequest
 //
@@ -89,6 +402,69 @@ func NewBadRequestf(format string, a ...any) *BadRequest {
 	return NewBadRequest(fmt.Sprintf(format, a...))
 }
 
+//
+// Conflict
+//
+
+type Conflict struct { //nolint:errname
+	APIError
+}
+
+func NewConflict(message string) *Conflict {
+	return &Conflict{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusConflict,
+		},
+	}
+}
+
+func NewConflictf(format string, a ...any) *Conflict {
+	return NewConflict(fmt.Sprintf(format, a...))
+}
+
+//
+// Forbidden
+//
+
+type Forbidden struct { //nolint:errname
+	APIError
+}
+
+func NewForbidden(message string) *Forbidden {
+	return &Forbidden{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusForbidden,
+		},
+	}
+}
+
+func NewForbiddenf(format string, a ...any) *Forbidden {
+	return NewForbidden(fmt.Sprintf(format, a...))
+}
+
+//
+// Gone
+//
+
+type Gone struct { //nolint:errname
+	APIError
+}
+
+func NewGone(message string) *Gone {
+	return &Gone{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusGone,
+		},
+	}
+}
+
+func NewGonef(format string, a ...any) *Gone {
+	return NewGone(fmt.Sprintf(format, a...))
+}
+
 //
 // InternalServerError
 //
@@ -110,6 +486,55 @@ func NewInternalServerErrorf(format string, a ...any) *InternalServerError {
 	return NewInternalServerError(fmt.Sprintf(format, a...))
 }
 
+//
+// MethodNotAllowed
+//
+
+type MethodNotAllowed struct { //nolint:errname
+	APIError
+}
+
+func NewMethodNotAllowed(message string) *MethodNotAllowed {
+	return &MethodNotAllowed{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusMethodNotAllowed,
+		},
+	}
+}
+
+func NewMethodNotAllowedf(format string, a ...any) *MethodNotAllowed {
+	return NewMethodNotAllowed(fmt.Sprintf(format, a...))
+}
+
+// WithAllow sets the methods that the target resource supports, which are sent
+// in an Allow header as required for a 405 response.
+func (e *MethodNotAllowed) WithAllow(methods ...string) *MethodNotAllowed {
+	e.setHeader("Allow", strings.Join(methods, ", "))
+	return e
+}
+
+//
+// NotAcceptable
+//
+
+type NotAcceptable struct { //nolint:errname
+	APIError
+}
+
+func NewNotAcceptable(message string) *NotAcceptable {
+	return &NotAcceptable{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusNotAcceptable,
+		},
+	}
+}
+
+func NewNotAcceptablef(format string, a ...any) *NotAcceptable {
+	return NewNotAcceptable(fmt.Sprintf(format, a...))
+}
+
 //
 // NotFound
 //
@@ -131,6 +556,48 @@ func NewNotFoundf(format string, a ...any) *NotFound {
 	return NewNotFound(fmt.Sprintf(format, a...))
 }
 
+//
+// NotImplemented
+//
+
+type NotImplemented struct { //nolint:errname
+	APIError
+}
+
+func NewNotImplemented(message string) *NotImplemented {
+	return &NotImplemented{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusNotImplemented,
+		},
+	}
+}
+
+func NewNotImplementedf(format string, a ...any) *NotImplemented {
+	return NewNotImplemented(fmt.Sprintf(format, a...))
+}
+
+//
+// PreconditionFailed
+//
+
+type PreconditionFailed struct { //nolint:errname
+	APIError
+}
+
+func NewPreconditionFailed(message string) *PreconditionFailed {
+	return &PreconditionFailed{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusPreconditionFailed,
+		},
+	}
+}
+
+func NewPreconditionFailedf(format string, a ...any) *PreconditionFailed {
+	return NewPreconditionFailed(fmt.Sprintf(format, a...))
+}
+
 //
 // RequestEntityTooLarge
 //
@@ -148,6 +615,10 @@ func NewRequestEntityTooLarge(message string) *RequestEntityTooLarge {
 	}
 }
 
+func NewRequestEntityTooLargef(format string, a ...any) *RequestEntityTooLarge {
+	return NewRequestEntityTooLarge(fmt.Sprintf(format, a...))
+}
+
 //
 // ServiceUnavailable
 //
@@ -169,6 +640,41 @@ func NewServiceUnavailablef(format string, a ...any) *ServiceUnavailable {
 	return NewServiceUnavailable(fmt.Sprintf(format, a...))
 }
 
+// WithRetryAfter sets how long the client should wait before retrying, which
+// is sent in a Retry-After header.
+func (e *ServiceUnavailable) WithRetryAfter(retryAfter time.Duration) *ServiceUnavailable {
+	e.setHeader("Retry-After", retryAfterSeconds(retryAfter))
+	return e
+}
+
+//
+// TooManyRequests
+//
+
+type TooManyRequests struct { //nolint:errname
+	APIError
+}
+
+func NewTooManyRequests(message string) *TooManyRequests {
+	return &TooManyRequests{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusTooManyRequests,
+		},
+	}
+}
+
+func NewTooManyRequestsf(format string, a ...any) *TooManyRequests {
+	return NewTooManyRequests(fmt.Sprintf(format, a...))
+}
+
+// WithRetryAfter sets how long the client should wait before making another
+// request, which is sent in a Retry-After header.
+func (e *TooManyRequests) WithRetryAfter(retryAfter time.Duration) *TooManyRequests {
+	e.setHeader("Retry-After", retryAfterSeconds(retryAfter))
+	return e
+}
+
 //
 // Unauthorized
 //
@@ -177,6 +683,9 @@ type Unauthorized struct { //nolint:errname
 	APIError
 }
 
+// NewUnauthorized returns a new Unauthorized error. Unlike other constructors,
+// it accepts format arguments for historical reasons. Prefer
+// NewUnauthorizedf when formatting a message.
 func NewUnauthorized(format string, a ...any) *Unauthorized {
 	return &Unauthorized{
 		APIError: APIError{
@@ -185,3 +694,64 @@ func NewUnauthorized(format string, a ...any) *Unauthorized {
 		},
 	}
 }
+
+func NewUnauthorizedf(format string, a ...any) *Unauthorized {
+	return NewUnauthorized(format, a...)
+}
+
+// WithWWWAuthenticate sets a challenge describing how to authenticate, like
+// `Bearer realm="api"`, which is sent in a WWW-Authenticate header as
+// required for a 401 response.
+func (e *Unauthorized) WithWWWAuthenticate(challenge string) *Unauthorized {
+	e.setHeader("WWW-Authenticate", challenge)
+	return e
+}
+
+//
+// UnprocessableEntity
+//
+
+type UnprocessableEntity struct { //nolint:errname
+	APIError
+}
+
+func NewUnprocessableEntity(message string) *UnprocessableEntity {
+	return &UnprocessableEntity{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+}
+
+func NewUnprocessableEntityf(format string, a ...any) *UnprocessableEntity {
+	return NewUnprocessableEntity(fmt.Sprintf(format, a...))
+}
+
+//
+// UnsupportedMediaType
+//
+
+type UnsupportedMediaType struct { //nolint:errname
+	APIError
+}
+
+func NewUnsupportedMediaType(message string) *UnsupportedMediaType {
+	return &UnsupportedMediaType{
+		APIError: APIError{
+			Message:    message,
+			StatusCode: http.StatusUnsupportedMediaType,
+		},
+	}
+}
+
+func NewUnsupportedMediaTypef(format string, a ...any) *UnsupportedMediaType {
+	return NewUnsupportedMediaType(fmt.Sprintf(format, a...))
+}
+
+// WithAccept sets the media types that the target resource accepts, which are
+// sent in an Accept header so that clients know what to send instead.
+func (e *UnsupportedMediaType) WithAccept(mediaTypes ...string) *UnsupportedMediaType {
+	e.setHeader("Accept", strings.Join(mediaTypes, ", "))
+	return e
+}
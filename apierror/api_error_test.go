@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -22,6 +25,7 @@ func TestAPIError(t *testing.T) {
 
 	apiErr.SetInternalError(anErr)
 	require.Equal(t, anErr, apiErr.GetInternalError())
+	require.Equal(t, http.StatusBadRequest, apiErr.GetStatusCode())
 }
 
 func TestAPIErrorJSON(t *testing.T) {
@@ -34,6 +38,15 @@ func TestAPIErrorJSON(t *testing.T) {
 	)
 }
 
+func TestAPIErrorJSONWithCode(t *testing.T) {
+	t.Parallel()
+
+	require.JSONEq(t,
+		`{"code":"job_not_found","message":"Job not found."}`,
+		string(mustMarshalJSON(t, WithCode(NewNotFound("Job not found."), "job_not_found"))),
+	)
+}
+
 func TestAPIErrorWrite(t *testing.T) {
 	t.Parallel()
 
@@ -53,6 +66,281 @@ func TestAPIErrorWrite(t *testing.T) {
 	require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
 }
 
+func TestAPIErrorWriteEncoder(t *testing.T) {
+	t.Parallel()
+
+	logger := riversharedtest.Logger(t)
+
+	// Encodes values as a string in Go syntax, which is enough to check what
+	// an encoder was given.
+	textEncoder := &testEncoder{contentType: "text/plain; charset=utf-8", encode: func(v any) ([]byte, error) {
+		if apiErr, ok := v.(Interface); ok {
+			return []byte("error: " + apiErr.Error()), nil
+		}
+		return fmt.Appendf(nil, "%v", v), nil
+	}}
+
+	t.Run("Encoder", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		NewNotFound("Job not found.").Write(WithEncoder(context.Background(), textEncoder), logger, recorder)
+
+		require.Equal(t, http.StatusNotFound, recorder.Result().StatusCode)
+		require.Equal(t, "error: Job not found.", recorder.Body.String())
+		require.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("ProblemDetails", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := WithEncoder(WithFormat(context.Background(), FormatProblemDetails), textEncoder)
+
+		recorder := httptest.NewRecorder()
+		NewNotFound("Job not found.").Write(ctx, logger, recorder)
+
+		require.Equal(t, "map[detail:Job not found. status:404 title:Not Found type:about:blank]", recorder.Body.String())
+		require.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("EncoderErrorFallsBackToJSON", func(t *testing.T) {
+		t.Parallel()
+
+		failingEncoder := &testEncoder{contentType: "text/csv", encode: func(_ any) ([]byte, error) {
+			return nil, errors.New("can't encode errors as CSV")
+		}}
+
+		recorder := httptest.NewRecorder()
+		NewNotFound("Job not found.").Write(WithEncoder(context.Background(), failingEncoder), logger, recorder)
+
+		require.Equal(t, http.StatusNotFound, recorder.Result().StatusCode)
+		require.JSONEq(t, `{"message":"Job not found."}`, recorder.Body.String())
+		require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	})
+}
+
+func TestAPIErrorWriteHeaders(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	write := func(t *testing.T, apiErr Interface) *httptest.ResponseRecorder {
+		t.Helper()
+
+		recorder := httptest.NewRecorder()
+		apiErr.Write(ctx, riversharedtest.Logger(t), recorder)
+		return recorder
+	}
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := write(t, NewMethodNotAllowed("Method not allowed.").WithAllow(http.MethodGet, http.MethodPost))
+		require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+		require.Equal(t, "GET, POST", recorder.Header().Get("Allow"))
+	})
+
+	t.Run("ServiceUnavailable", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := write(t, NewServiceUnavailable("Down for maintenance.").WithRetryAfter(2*time.Minute))
+		require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		require.Equal(t, "120", recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("TooManyRequests", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := write(t, NewTooManyRequests("Slow down.").WithRetryAfter(1500*time.Millisecond))
+		require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		require.Equal(t, "2", recorder.Header().Get("Retry-After"))
+		require.JSONEq(t, `{"message":"Slow down."}`, recorder.Body.String())
+	})
+
+	t.Run("TooManyRequestsNegativeRetryAfter", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := write(t, NewTooManyRequests("Slow down.").WithRetryAfter(-time.Second))
+		require.Equal(t, "0", recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := write(t, NewUnauthorizedf("Token %s expired.", "tok_123").WithWWWAuthenticate(`Bearer realm="api"`))
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		require.Equal(t, `Bearer realm="api"`, recorder.Header().Get("WWW-Authenticate"))
+		require.JSONEq(t, `{"message":"Token tok_123 expired."}`, recorder.Body.String())
+	})
+
+	t.Run("UnsupportedMediaType", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := write(t, NewUnsupportedMediaType("Unsupported.").WithAccept("application/json", "application/msgpack"))
+		require.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+		require.Equal(t, "application/json, application/msgpack", recorder.Header().Get("Accept"))
+	})
+
+	t.Run("NoHeaders", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := write(t, NewTooManyRequests("Slow down."))
+		require.Empty(t, recorder.Header().Get("Retry-After"))
+	})
+}
+
+func TestAPIErrorWriteProblemDetails(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx    = WithFormat(context.Background(), FormatProblemDetails)
+		logger = riversharedtest.Logger(t)
+	)
+
+	t.Run("Defaults", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		NewNotFound("Job not found.").Write(ctx, logger, recorder)
+
+		require.Equal(t, http.StatusNotFound, recorder.Result().StatusCode)
+		require.JSONEq(t,
+			`{"detail":"Job not found.","status":404,"title":"Not Found","type":"about:blank"}`,
+			recorder.Body.String(),
+		)
+		require.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	})
+
+	t.Run("AllMembers", func(t *testing.T) {
+		t.Parallel()
+
+		apiErr := WithCode(NewBadRequest("Your account doesn't have enough credit."), "out_of_credit")
+		apiErr.Extensions = map[string]any{"balance": 30, "status": 999}
+		apiErr.Instance = "/account/12345/msgs/abc"
+		apiErr.Title = "You do not have enough credit."
+		apiErr.Type = "https://example.com/probs/out-of-credit"
+
+		recorder := httptest.NewRecorder()
+		apiErr.Write(ctx, logger, recorder)
+
+		require.Equal(t, http.StatusBadRequest, recorder.Result().StatusCode)
+		require.JSONEq(t, `{
+			"balance": 30,
+			"code": "out_of_credit",
+			"detail": "Your account doesn't have enough credit.",
+			"instance": "/account/12345/msgs/abc",
+			"status": 400,
+			"title": "You do not have enough credit.",
+			"type": "https://example.com/probs/out-of-credit"
+		}`, recorder.Body.String())
+	})
+
+	t.Run("MessageFormatExcludesProblemMembers", func(t *testing.T) {
+		t.Parallel()
+
+		apiErr := NewBadRequest("Bad request.")
+		apiErr.Extensions = map[string]any{"balance": 30}
+		apiErr.Type = "https://example.com/probs/out-of-credit"
+
+		recorder := httptest.NewRecorder()
+		apiErr.Write(WithFormat(ctx, FormatMessage), logger, recorder)
+
+		require.JSONEq(t, `{"message":"Bad request."}`, recorder.Body.String())
+	})
+
+	t.Run("UnspecifiedFormatWritesMessage", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		NewBadRequest("Bad request.").Write(WithFormat(ctx, Format(0)), logger, recorder)
+
+		require.JSONEq(t, `{"message":"Bad request."}`, recorder.Body.String())
+	})
+}
+
+func TestFromStatusCode(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, NewBadRequest("Bad request."), FromStatusCode(http.StatusBadRequest, "Bad request."))
+	require.Equal(t, NewConflict("Conflict."), FromStatusCode(http.StatusConflict, "Conflict."))
+	require.Equal(t, NewForbidden("Forbidden."), FromStatusCode(http.StatusForbidden, "Forbidden."))
+	require.Equal(t, NewGone("Gone."), FromStatusCode(http.StatusGone, "Gone."))
+	require.Equal(t, NewMethodNotAllowed("Method not allowed."), FromStatusCode(http.StatusMethodNotAllowed, "Method not allowed."))
+	require.Equal(t, NewNotAcceptable("Not acceptable."), FromStatusCode(http.StatusNotAcceptable, "Not acceptable."))
+	require.Equal(t, NewNotFound("Not found."), FromStatusCode(http.StatusNotFound, "Not found."))
+	require.Equal(t, NewNotImplemented("Not implemented."), FromStatusCode(http.StatusNotImplemented, "Not implemented."))
+	require.Equal(t, NewPreconditionFailed("Precondition failed."), FromStatusCode(http.StatusPreconditionFailed, "Precondition failed."))
+	require.Equal(t, NewTooManyRequests("Too many requests."), FromStatusCode(http.StatusTooManyRequests, "Too many requests."))
+	require.Equal(t, NewUnauthorized("100%% unauthorized."), FromStatusCode(http.StatusUnauthorized, "100% unauthorized."))
+	require.Equal(t, NewUnprocessableEntity("Unprocessable."), FromStatusCode(http.StatusUnprocessableEntity, "Unprocessable."))
+	require.Equal(t, NewUnsupportedMediaType("Unsupported."), FromStatusCode(http.StatusUnsupportedMediaType, "Unsupported."))
+	require.Equal(t, &APIError{Message: "Teapot.", StatusCode: http.StatusTeapot}, FromStatusCode(http.StatusTeapot, "Teapot."))
+}
+
+func TestNewf(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		apiErr     Interface
+		statusCode int
+	}{
+		{NewBadRequestf("Job %d failed.", 123), http.StatusBadRequest},
+		{NewConflictf("Job %d failed.", 123), http.StatusConflict},
+		{NewForbiddenf("Job %d failed.", 123), http.StatusForbidden},
+		{NewGonef("Job %d failed.", 123), http.StatusGone},
+		{NewInternalServerErrorf("Job %d failed.", 123), http.StatusInternalServerError},
+		{NewMethodNotAllowedf("Job %d failed.", 123), http.StatusMethodNotAllowed},
+		{NewNotAcceptablef("Job %d failed.", 123), http.StatusNotAcceptable},
+		{NewNotFoundf("Job %d failed.", 123), http.StatusNotFound},
+		{NewNotImplementedf("Job %d failed.", 123), http.StatusNotImplemented},
+		{NewPreconditionFailedf("Job %d failed.", 123), http.StatusPreconditionFailed},
+		{NewRequestEntityTooLargef("Job %d failed.", 123), http.StatusRequestEntityTooLarge},
+		{NewServiceUnavailablef("Job %d failed.", 123), http.StatusServiceUnavailable},
+		{NewTooManyRequestsf("Job %d failed.", 123), http.StatusTooManyRequests},
+		{NewUnauthorizedf("Job %d failed.", 123), http.StatusUnauthorized},
+		{NewUnprocessableEntityf("Job %d failed.", 123), http.StatusUnprocessableEntity},
+		{NewUnsupportedMediaTypef("Job %d failed.", 123), http.StatusUnsupportedMediaType},
+	} {
+		require.Equal(t, "Job 123 failed.", tt.apiErr.Error())
+		require.Equal(t, tt.statusCode, tt.apiErr.GetStatusCode())
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	apiErr := WithCode(NewNotFound("Job not found."), "job_not_found")
+
+	data, err := MarshalJSON(context.Background(), apiErr)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"code":"job_not_found","message":"Job not found."}`, string(data))
+
+	data, err = MarshalJSON(WithFormat(context.Background(), FormatProblemDetails), apiErr)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"code":"job_not_found","detail":"Job not found.","status":404,"title":"Not Found","type":"about:blank"}`, string(data))
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, FormatMessage, NegotiateFormat("", FormatMessage))
+	require.Equal(t, FormatMessage, NegotiateFormat("application/json", FormatMessage))
+	require.Equal(t, FormatMessage, NegotiateFormat("*/*", FormatMessage))
+	require.Equal(t, FormatMessage, NegotiateFormat("application/problem+json;q=0", FormatMessage))
+	require.Equal(t, FormatMessage, NegotiateFormat("not a media type", FormatMessage))
+	require.Equal(t, FormatProblemDetails, NegotiateFormat("application/problem+json", FormatMessage))
+	require.Equal(t, FormatProblemDetails, NegotiateFormat("application/json, application/problem+json;q=0.5", FormatMessage))
+	require.Equal(t, FormatProblemDetails, NegotiateFormat("application/json", FormatProblemDetails))
+}
+
+func TestWithCode(t *testing.T) {
+	t.Parallel()
+
+	apiErr := WithCode(NewBadRequest("Bad request."), CodeValidationFailed)
+	require.Equal(t, CodeValidationFailed, apiErr.Code)
+	require.Equal(t, CodeValidationFailed, apiErr.GetCode())
+}
+
 func TestWithInternalError(t *testing.T) {
 	t.Parallel()
 
@@ -73,3 +361,11 @@ func mustMarshalJSON(t *testing.T, v any) []byte {
 
 	return data
 }
+
+type testEncoder struct {
+	contentType string
+	encode      func(v any) ([]byte, error)
+}
+
+func (e *testEncoder) ContentType() string          { return e.contentType }
+func (e *testEncoder) Encode(v any) ([]byte, error) { return e.encode(v) }
@@ -0,0 +1,169 @@
+package apierror
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Well-known codes assigned to API errors produced by the framework itself,
+// as opposed to those returned by endpoints.
+const (
+	// CodeAlreadyExists indicates that a resource conflicts with an existing
+	// one, like one with the same unique value.
+	CodeAlreadyExists = "already_exists"
+
+	// CodeConstraintViolation indicates that a value didn't satisfy a
+	// constraint, like a required value that was missing.
+	CodeConstraintViolation = "constraint_violation"
+
+	// CodeDatabasePermissionDenied indicates that the database refused an
+	// operation because of insufficient privileges.
+	CodeDatabasePermissionDenied = "database_permission_denied"
+
+	// CodeDatabaseUnavailable indicates that the database couldn't be
+	// connected to.
+	CodeDatabaseUnavailable = "database_unavailable"
+
+	// CodeDatabaseReadOnly indicates that the database is temporarily only
+	// accepting reads, like during a failover.
+	CodeDatabaseReadOnly = "database_read_only"
+
+	// CodeInternalError indicates an unexpected error whose details are only
+	// available in logs.
+	CodeInternalError = "internal_error"
+
+	// CodeInvalidBody indicates that a request body couldn't be decoded.
+	CodeInvalidBody = "invalid_body"
+
+	// CodeInvalidParameter indicates that a path, query, header, or cookie
+	// parameter couldn't be parsed.
+	CodeInvalidParameter = "invalid_parameter"
+
+	// CodeNotAcceptable indicates that none of the media types a request
+	// accepts in its Accept header can be produced by the endpoint.
+	CodeNotAcceptable = "not_acceptable"
+
+	// CodeNotFound indicates that a requested resource doesn't exist.
+	CodeNotFound = "not_found"
+
+	// CodeReferenceViolation indicates that an operation referenced a
+	// resource that doesn't exist, or removed one that's still referenced.
+	CodeReferenceViolation = "reference_violation"
+
+	// CodeRequestEntityTooLarge indicates that a request body was larger than
+	// allowed.
+	CodeRequestEntityTooLarge = "request_entity_too_large"
+
+	// CodeTimeout indicates that a request took longer than its timeout.
+	CodeTimeout = "timeout"
+
+	// CodeTransactionConflict indicates that an operation conflicted with a
+	// concurrent one and was aborted, and that it's likely to succeed if
+	// retried.
+	CodeTransactionConflict = "transaction_conflict"
+
+	// CodeUnsupportedMediaType indicates that a request body was sent with a
+	// Content-Type that isn't supported.
+	CodeUnsupportedMediaType = "unsupported_media_type"
+
+	// CodeValidationFailed indicates that a request failed validation.
+	CodeValidationFailed = "validation_failed"
+)
+
+// CodeDefinition describes a code that API errors may carry.
+type CodeDefinition struct {
+	// Code is the code, like `job_not_found`.
+	Code string
+
+	// Description describes when the code is returned, for documentation.
+	Description string
+
+	// StatusCode is the HTTP status code of errors carrying the code.
+	StatusCode int
+}
+
+// Catalog is a set of codes that API errors may carry, kept so that they can
+// be listed and documented, like in an OpenAPI document. Codes are usually
+// registered while initializing package variables:
+//
+//	var (
+//		catalog = apierror.NewCatalog()
+//
+//		codeJobNotFound = catalog.Register("job_not_found", http.StatusNotFound, "The requested job doesn't exist.")
+//	)
+type Catalog struct {
+	codes map[string]*CodeDefinition
+	mu    sync.RWMutex
+}
+
+// NewCatalog initializes a new catalog containing the well-known codes of
+// errors produced by the framework itself, like CodeValidationFailed.
+func NewCatalog() *Catalog {
+	catalog := &Catalog{codes: make(map[string]*CodeDefinition)}
+
+	catalog.Register(CodeAlreadyExists, http.StatusConflict, "A resource with the same unique value already exists.")
+	catalog.Register(CodeConstraintViolation, http.StatusUnprocessableEntity, "A value didn't satisfy a constraint.")
+	catalog.Register(CodeDatabasePermissionDenied, http.StatusBadRequest, "The database refused the operation because of insufficient privileges.")
+	catalog.Register(CodeDatabaseUnavailable, http.StatusBadRequest, "There was a problem connecting to the database.")
+	catalog.Register(CodeDatabaseReadOnly, http.StatusServiceUnavailable, "The database is temporarily read-only.")
+	catalog.Register(CodeInternalError, http.StatusInternalServerError, "An unexpected error occurred.")
+	catalog.Register(CodeInvalidBody, http.StatusBadRequest, "The request body couldn't be decoded.")
+	catalog.Register(CodeInvalidParameter, http.StatusBadRequest, "A path, query, header, or cookie parameter couldn't be parsed.")
+	catalog.Register(CodeNotAcceptable, http.StatusNotAcceptable, "None of the media types in the request's Accept header can be produced.")
+	catalog.Register(CodeNotFound, http.StatusNotFound, "The requested resource doesn't exist.")
+	catalog.Register(CodeReferenceViolation, http.StatusConflict, "The operation referenced a resource that doesn't exist, or removed one that's still referenced.")
+	catalog.Register(CodeRequestEntityTooLarge, http.StatusRequestEntityTooLarge, "The request body was too large.")
+	catalog.Register(CodeTimeout, http.StatusServiceUnavailable, "The request timed out.")
+	catalog.Register(CodeTransactionConflict, http.StatusServiceUnavailable, "The operation conflicted with a concurrent one. Retrying it might work.")
+	catalog.Register(CodeUnsupportedMediaType, http.StatusUnsupportedMediaType, "The request body's Content-Type isn't supported.")
+	catalog.Register(CodeValidationFailed, http.StatusBadRequest, "The request failed validation.")
+
+	return catalog
+}
+
+// Codes returns every code in the catalog, sorted by code.
+func (c *Catalog) Codes() []*CodeDefinition {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.SortedFunc(maps.Values(c.codes), func(a, b *CodeDefinition) int {
+		return cmp.Compare(a.Code, b.Code)
+	})
+}
+
+// Lookup returns the definition of the given code, or false if it's not in the
+// catalog.
+func (c *Catalog) Lookup(code string) (*CodeDefinition, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	definition, ok := c.codes[code]
+	return definition, ok
+}
+
+// Register adds a code to the catalog and returns it. Panics if the code is
+// empty or has already been registered, which are programming errors.
+func (c *Catalog) Register(code string, statusCode int, description string) string {
+	if code == "" {
+		panic("apierror: code must not be empty")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.codes[code]; ok {
+		panic(fmt.Sprintf("apierror: code %q already registered", code))
+	}
+
+	c.codes[code] = &CodeDefinition{
+		Code:        code,
+		Description: description,
+		StatusCode:  statusCode,
+	}
+
+	return code
+}
@@ -0,0 +1,87 @@
+package apierror
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("BuiltinCodes", func(t *testing.T) {
+		t.Parallel()
+
+		catalog := NewCatalog()
+
+		definition, ok := catalog.Lookup(CodeValidationFailed)
+		require.True(t, ok)
+		require.Equal(t, &CodeDefinition{
+			Code:        CodeValidationFailed,
+			Description: "The request failed validation.",
+			StatusCode:  http.StatusBadRequest,
+		}, definition)
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		t.Parallel()
+
+		catalog := NewCatalog()
+
+		code := catalog.Register("job_not_found", http.StatusNotFound, "The job doesn't exist.")
+		require.Equal(t, "job_not_found", code)
+
+		definition, ok := catalog.Lookup("job_not_found")
+		require.True(t, ok)
+		require.Equal(t, &CodeDefinition{Code: "job_not_found", Description: "The job doesn't exist.", StatusCode: http.StatusNotFound}, definition)
+
+		_, ok = catalog.Lookup("does_not_exist")
+		require.False(t, ok)
+	})
+
+	t.Run("RegisterPanics", func(t *testing.T) {
+		t.Parallel()
+
+		catalog := NewCatalog()
+
+		require.PanicsWithValue(t, "apierror: code must not be empty", func() {
+			catalog.Register("", http.StatusNotFound, "Empty.")
+		})
+		require.PanicsWithValue(t, `apierror: code "timeout" already registered`, func() {
+			catalog.Register(CodeTimeout, http.StatusServiceUnavailable, "Duplicate.")
+		})
+	})
+
+	t.Run("Codes", func(t *testing.T) {
+		t.Parallel()
+
+		catalog := NewCatalog()
+		catalog.Register("aaa_first", http.StatusBadRequest, "First.")
+
+		codes := make([]string, 0, len(catalog.Codes()))
+		for _, definition := range catalog.Codes() {
+			codes = append(codes, definition.Code)
+		}
+
+		require.Equal(t, []string{
+			"aaa_first",
+			CodeAlreadyExists,
+			CodeConstraintViolation,
+			CodeDatabasePermissionDenied,
+			CodeDatabaseReadOnly,
+			CodeDatabaseUnavailable,
+			CodeInternalError,
+			CodeInvalidBody,
+			CodeInvalidParameter,
+			CodeNotAcceptable,
+			CodeNotFound,
+			CodeReferenceViolation,
+			CodeRequestEntityTooLarge,
+			CodeTimeout,
+			CodeTransactionConflict,
+			CodeUnsupportedMediaType,
+			CodeValidationFailed,
+		}, codes)
+	})
+}
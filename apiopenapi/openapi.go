@@ -0,0 +1,630 @@
+// Package apiopenapi generates OpenAPI 3.1 documents from endpoints mounted with
+// apiendpoint.Mount. Endpoints are collected by assigning an
+// apiendpoint.Registry to MountOpts.Registry, and a document is built from
+// the registry's routes:
+//
+//	registry := apiendpoint.NewRegistry()
+//	opts := &apiendpoint.MountOpts{Registry: registry}
+//
+//	apiendpoint.Mount(mux, &jobGetEndpoint{}, opts)
+//	apiendpoint.Mount(mux, &jobListEndpoint{}, opts)
+//
+//	mux.Handle("GET /api/openapi.json", apiopenapi.NewHandler(registry, &apiopenapi.DocumentOpts{
+//		Title:   "River UI API",
+//		Version: "1.0.0",
+//	}))
+//
+// Request and response types are reflected into schemas using their `json`
+// tags, with constraints drawn from `validate` tags (`required`, `min`, `max`,
+// `oneof`, and a few others). Fields bound from path variables, query
+// parameters, headers, and cookies become operation parameters.
+package apiopenapi
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/internal/apireflect"
+)
+
+// Version is the version of the OpenAPI specification that generated documents
+// adhere to.
+const Version = "3.1.0"
+
+// Document is an OpenAPI document. Only the subset of the specification that's
+// needed to describe endpoints mounted with apiendpoint is supported.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       *Info                `json:"info"`
+	Servers    []*Server            `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+// Components holds reusable schemas referenced from elsewhere in a document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Example is an example value for a media type.
+type Example struct {
+	Summary string `json:"summary,omitempty"`
+	Value   any    `json:"value"`
+}
+
+// Info is metadata about an API.
+type Info struct {
+	Description string `json:"description,omitempty"`
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+}
+
+// MediaType describes the content of a request or response body.
+type MediaType struct {
+	Examples map[string]*Example `json:"examples,omitempty"`
+	Schema   *Schema             `json:"schema,omitempty"`
+}
+
+// Operation is a single API operation on a path.
+//
+//nolint:tagliatelle
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is an operation parameter that comes from somewhere other than the
+// request body, like a path variable or query parameter.
+type Parameter struct {
+	In       string  `json:"in"`
+	Name     string  `json:"name"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// PathItem holds the operations available on a single path.
+type PathItem struct {
+	Delete  *Operation `json:"delete,omitempty"`
+	Get     *Operation `json:"get,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Trace   *Operation `json:"trace,omitempty"`
+}
+
+// RequestBody describes an operation's request body.
+type RequestBody struct {
+	Content  map[string]*MediaType `json:"content"`
+	Required bool                  `json:"required,omitempty"`
+}
+
+// Response describes a single response from an operation.
+type Response struct {
+	Content     map[string]*MediaType `json:"content,omitempty"`
+	Description string                `json:"description"`
+}
+
+// Server is a server hosting the API.
+type Server struct {
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+}
+
+// DocumentOpts are options for building a document.
+type DocumentOpts struct {
+	// Catalog is a catalog of the codes that API errors may carry, which are
+	// listed in the schema of error responses. If not specified, error codes
+	// are documented as arbitrary strings.
+	Catalog *apierror.Catalog
+
+	// Description is a description of the API.
+	Description string
+
+	// ErrorFormat is the format that error responses are documented in, which
+	// should match MountOpts.ErrorFormat. Defaults to apierror.FormatMessage.
+	ErrorFormat apierror.Format
+
+	// Servers are servers hosting the API.
+	Servers []*Server
+
+	// Title is the API's title. Defaults to "API".
+	Title string
+
+	// Version is the API's version (not the OpenAPI version). Defaults to
+	// "0.0.0".
+	Version string
+}
+
+// YAML returns the document encoded as YAML.
+func (d *Document) YAML() ([]byte, error) {
+	return jsonToYAML(d)
+}
+
+// contentTypeJSON is the media type of request and response bodies.
+const contentTypeJSON = "application/json"
+
+// errorComponentName is the name of the component schema describing the body
+// of an API error.
+const errorComponentName = "APIError"
+
+// problemDetailsComponentName is the name of the component schema describing
+// the body of an API error written as problem details.
+const problemDetailsComponentName = "ProblemDetails"
+
+// NewDocument builds an OpenAPI document from the routes in the given
+// registry.
+func NewDocument(registry *apiendpoint.Registry, opts *DocumentOpts) *Document {
+	if opts == nil {
+		opts = &DocumentOpts{}
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info: &Info{
+			Description: opts.Description,
+			Title:       cmp.Or(opts.Title, "API"),
+			Version:     cmp.Or(opts.Version, "0.0.0"),
+		},
+		Servers: opts.Servers,
+		Paths:   make(map[string]*PathItem),
+	}
+
+	var (
+		generator    = newSchemaGenerator()
+		operationIDs = make(map[string]struct{})
+	)
+
+	if opts.ErrorFormat == apierror.FormatProblemDetails {
+		generator.components[problemDetailsComponentName] = problemDetailsSchema(opts.Catalog)
+	} else {
+		generator.components[errorComponentName] = errorSchema(opts.Catalog)
+	}
+
+	for _, route := range registry.Routes() {
+		methods, path := splitPattern(route.Meta.Pattern)
+
+		pathItem, ok := doc.Paths[path]
+		if !ok {
+			pathItem = &PathItem{}
+			doc.Paths[path] = pathItem
+		}
+
+		for _, method := range methods {
+			operation := buildOperation(generator, route, method, opts.ErrorFormat)
+
+			// Operation IDs must be unique across the document.
+			operation.OperationID = apireflect.UniqueName(operationIDs, operationID(route, method, len(methods) > 1))
+
+			pathItem.setOperation(method, operation)
+		}
+	}
+
+	doc.Components = &Components{Schemas: generator.components}
+
+	return doc
+}
+
+// NewHandler returns an HTTP handler that serves an OpenAPI document built from
+// the given registry. The document is served as JSON by default, or as YAML if
+// the request path ends in `.yaml` or `.yml`, or the request's Accept header
+// asks for YAML.
+//
+// The document is rebuilt on each request so that it always reflects every
+// endpoint mounted with the registry.
+func NewHandler(registry *apiendpoint.Registry, opts *DocumentOpts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc := NewDocument(registry, opts)
+
+		var (
+			contentType string
+			data        []byte
+			err         error
+		)
+
+		if wantsYAML(r) {
+			contentType = "application/yaml; charset=utf-8"
+			data, err = doc.YAML()
+		} else {
+			contentType = "application/json; charset=utf-8"
+			data, err = json.Marshal(doc)
+		}
+		if err != nil {
+			apierror.NewInternalServerErrorf("Error encoding OpenAPI document: %s.", err).Write(r.Context(), slog.Default(), w)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	})
+}
+
+func buildOperation(generator *schemaGenerator, route *apiendpoint.Route, method string, errorFormat apierror.Format) *Operation {
+	operation := &Operation{Responses: make(map[string]*Response)}
+
+	for _, param := range route.Parameters {
+		schema, required := generator.fieldSchema(param.Field)
+
+		operation.Parameters = append(operation.Parameters, &Parameter{
+			In:       param.In,
+			Name:     param.Name,
+			Required: required || param.In == "path",
+			Schema:   schema,
+		})
+	}
+
+	// Mirrors apiendpoint, which only reads request bodies for methods other
+	// than GET.
+	if method != http.MethodGet && route.RequestType.Kind() == reflect.Struct {
+		mediaTypes := route.Meta.RequestMediaTypes
+		if len(mediaTypes) < 1 {
+			mediaTypes = []string{apiendpoint.MediaTypeJSON}
+		}
+
+		var (
+			content  = make(map[string]*MediaType, len(mediaTypes))
+			required bool
+		)
+		for _, mediaType := range mediaTypes {
+			// Forms are bound from `form` tags rather than JSON fields, and
+			// are described inline because their schemas differ from the
+			// request struct's JSON one.
+			var bodySchema, schema *Schema
+			switch {
+			case strings.EqualFold(mediaType, apiendpoint.MediaTypeForm):
+				bodySchema = generator.formSchema(route.RequestType, false)
+				schema = bodySchema
+			case strings.EqualFold(mediaType, apiendpoint.MediaTypeMultipartForm):
+				bodySchema = generator.formSchema(route.RequestType, true)
+				schema = bodySchema
+			default:
+				bodySchema = generator.structSchema(route.RequestType)
+			}
+
+			if len(bodySchema.Properties) < 1 {
+				continue
+			}
+
+			// Other bodies reference the request struct's component, which
+			// is only generated once it's known to have properties.
+			if schema == nil {
+				schema = generator.schemaFor(route.RequestType)
+			}
+
+			content[mediaType] = &MediaType{Schema: schema}
+			required = required || len(bodySchema.Required) > 0
+		}
+
+		if len(content) > 0 {
+			operation.RequestBody = &RequestBody{
+				Content:  content,
+				Required: required,
+			}
+		}
+	}
+
+	successResponse := &Response{Description: http.StatusText(route.Meta.StatusCode)}
+	if route.Meta.StatusCode != http.StatusNoContent && route.Meta.StatusCode != http.StatusSwitchingProtocols {
+		mediaTypes := route.Meta.ResponseMediaTypes
+		if len(mediaTypes) < 1 {
+			mediaTypes = []string{contentTypeJSON}
+		}
+
+		successResponse.Content = make(map[string]*MediaType, len(mediaTypes))
+		for _, mediaType := range mediaTypes {
+			successResponse.Content[mediaType] = &MediaType{Schema: generator.schemaFor(route.ResponseType)}
+		}
+	}
+	operation.Responses[strconv.Itoa(route.Meta.StatusCode)] = successResponse
+
+	// Errors that can be emitted by the framework itself for any endpoint.
+	if len(operation.Parameters) > 0 || operation.RequestBody != nil {
+		operation.addErrorResponse(http.StatusBadRequest, nil, errorFormat)
+	}
+	if operation.RequestBody != nil {
+		if route.Meta.MaxRequestBodyBytes >= 0 {
+			operation.addErrorResponse(http.StatusRequestEntityTooLarge, nil, errorFormat)
+		}
+		operation.addErrorResponse(http.StatusUnsupportedMediaType, nil, errorFormat)
+	}
+	// Endpoints that only produce JSON still return a 406 to a client that
+	// doesn't accept it, but it's only worth documenting when there's a
+	// choice of media types.
+	if len(route.Meta.ResponseMediaTypes) > 0 {
+		operation.addErrorResponse(http.StatusNotAcceptable, nil, errorFormat)
+	}
+	operation.addErrorResponse(http.StatusInternalServerError, nil, errorFormat)
+	operation.addErrorResponse(http.StatusServiceUnavailable, nil, errorFormat)
+
+	for _, apiErr := range route.Meta.Errors {
+		operation.addErrorResponse(apiErr.GetStatusCode(), apiErr, errorFormat)
+	}
+
+	return operation
+}
+
+// addErrorResponse adds an error response for the given status code in the
+// given format. If apiErr is non-nil, it's added as an example of the
+// response.
+func (o *Operation) addErrorResponse(statusCode int, apiErr apierror.Interface, errorFormat apierror.Format) {
+	key := strconv.Itoa(statusCode)
+
+	var (
+		componentName = errorComponentName
+		contentType   = contentTypeJSON
+	)
+	if errorFormat == apierror.FormatProblemDetails {
+		componentName = problemDetailsComponentName
+		contentType = apierror.ContentTypeProblemDetails
+	}
+
+	response, ok := o.Responses[key]
+	if !ok {
+		response = &Response{
+			Content: map[string]*MediaType{
+				contentType: {Schema: &Schema{Ref: "#/components/schemas/" + componentName}},
+			},
+			Description: http.StatusText(statusCode),
+		}
+		o.Responses[key] = response
+	}
+
+	if apiErr == nil {
+		return
+	}
+
+	mediaType := response.Content[contentType]
+	if mediaType.Examples == nil {
+		mediaType.Examples = make(map[string]*Example)
+	}
+
+	examples := make(map[string]struct{}, len(mediaType.Examples))
+	for name := range mediaType.Examples {
+		examples[name] = struct{}{}
+	}
+
+	var value any = apiErr
+	if problemDetailer, ok := apiErr.(interface{ ProblemDetails() map[string]any }); ok && errorFormat == apierror.FormatProblemDetails {
+		value = problemDetailer.ProblemDetails()
+	}
+
+	mediaType.Examples[apireflect.UniqueName(examples, exampleName(apiErr))] = &Example{
+		Summary: apiErr.Error(),
+		Value:   value,
+	}
+}
+
+func (p *PathItem) setOperation(method string, operation *Operation) {
+	switch method {
+	case http.MethodDelete:
+		p.Delete = operation
+	case http.MethodGet:
+		p.Get = operation
+	case http.MethodHead:
+		p.Head = operation
+	case http.MethodOptions:
+		p.Options = operation
+	case http.MethodPatch:
+		p.Patch = operation
+	case http.MethodPost:
+		p.Post = operation
+	case http.MethodPut:
+		p.Put = operation
+	case http.MethodTrace:
+		p.Trace = operation
+	}
+}
+
+// errorSchema is a schema for the body of an API error as written by
+// apierror.APIError.
+func errorSchema(catalog *apierror.Catalog) *Schema {
+	return &Schema{
+		Type: SchemaType{"object"},
+		Properties: map[string]*Schema{
+			"code":   errorCodeSchema(catalog),
+			"errors": fieldErrorsSchema(),
+			"message": {
+				Type:        SchemaType{"string"},
+				Description: "A human-friendly message describing what went wrong.",
+			},
+		},
+		Required: []string{"message"},
+	}
+}
+
+// exampleName produces a name for an error example from its Go type, like
+// `notFound` for *apierror.NotFound.
+func exampleName(apiErr apierror.Interface) string {
+	return apireflect.LowerFirst(indirectType(reflect.TypeOf(apiErr)).Name())
+}
+
+// operationID produces an operation ID for a route from the Go type of its
+// endpoint, like `jobGet` for *jobGetEndpoint. If the route was registered for
+// multiple methods (because its pattern didn't have one), the method is
+// included to disambiguate.
+func operationID(route *apiendpoint.Route, method string, includeMethod bool) string {
+	id := apireflect.LowerFirst(strings.TrimSuffix(indirectType(reflect.TypeOf(route.Endpoint)).Name(), "Endpoint"))
+	if id == "" {
+		id = "operation"
+	}
+
+	if includeMethod {
+		id += strings.ToUpper(method[:1]) + strings.ToLower(method[1:])
+	}
+
+	return id
+}
+
+// splitPattern splits an http.ServeMux pattern into the methods it applies to
+// and an OpenAPI path. A pattern without a method applies to every method. Any
+// host is removed, `{name...}` wildcards are converted to `{name}`, and the
+// special `{$}` wildcard is removed.
+func splitPattern(pattern string) ([]string, string) {
+	methods := []string{http.MethodDelete, http.MethodGet, http.MethodPatch, http.MethodPost, http.MethodPut}
+
+	method, patternPath := apireflect.SplitPattern(pattern)
+	if method != "" {
+		methods = []string{method}
+	}
+
+	var path strings.Builder
+	for _, segment := range apireflect.PathSegments(patternPath) {
+		if segment.Wildcard == "" {
+			path.WriteString(segment.Literal)
+		} else {
+			path.WriteString("{" + segment.Wildcard + "}")
+		}
+	}
+
+	return methods, path.String()
+}
+
+func wantsYAML(r *http.Request) bool {
+	if strings.HasSuffix(r.URL.Path, ".yaml") || strings.HasSuffix(r.URL.Path, ".yml") {
+		return true
+	}
+
+	return slices.ContainsFunc(strings.Split(r.Header.Get("Accept"), ","), func(mediaType string) bool {
+		mediaType, _, _ = strings.Cut(mediaType, ";")
+		return strings.Contains(strings.TrimSpace(mediaType), "yaml")
+	})
+}
+
+// jsonToYAML converts a value to YAML by way of JSON so that JSON struct tags
+// and custom marshaling are respected. The JSON is parsed into a YAML node tree
+// (JSON being a subset of YAML), which preserves key order, then reformatted
+// into YAML's more readable block style.
+func jsonToYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("error parsing JSON as YAML: %w", err)
+	}
+
+	var clearStyle func(node *yaml.Node)
+	clearStyle = func(node *yaml.Node) {
+		node.Style = 0
+		for _, child := range node.Content {
+			clearStyle(child)
+		}
+	}
+	clearStyle(&node)
+
+	return yaml.Marshal(&node)
+}
+
+// problemDetailsSchema is a schema for the body of an API error written as RFC
+// 9457 problem details by apierror.APIError.
+func problemDetailsSchema(catalog *apierror.Catalog) *Schema {
+	return &Schema{
+		Type: SchemaType{"object"},
+		Properties: map[string]*Schema{
+			"code": errorCodeSchema(catalog),
+			"detail": {
+				Type:        SchemaType{"string"},
+				Description: "A human-friendly message describing what went wrong.",
+			},
+			"errors": fieldErrorsSchema(),
+			"instance": {
+				Type:        SchemaType{"string"},
+				Format:      "uri-reference",
+				Description: "A URI reference identifying the specific occurrence of the problem.",
+			},
+			"status": {
+				Type:        SchemaType{"integer"},
+				Description: "The HTTP status code of the response.",
+			},
+			"title": {
+				Type:        SchemaType{"string"},
+				Description: "A short summary of the problem type.",
+			},
+			"type": {
+				Type:        SchemaType{"string"},
+				Format:      "uri-reference",
+				Description: "A URI reference identifying the problem type.",
+				Default:     "about:blank",
+			},
+		},
+		Required: []string{"detail", "status", "title", "type"},
+	}
+}
+
+// errorCodeSchema is a schema for the code of an API error. If a catalog is
+// given, its codes are listed as the schema's possible values and described in
+// its description.
+func errorCodeSchema(catalog *apierror.Catalog) *Schema {
+	schema := &Schema{
+		Type:        SchemaType{"string"},
+		Description: "A machine-readable code identifying the kind of error.",
+	}
+
+	if catalog == nil {
+		return schema
+	}
+
+	var description strings.Builder
+	description.WriteString(schema.Description + "\n")
+
+	for _, definition := range catalog.Codes() {
+		schema.Enum = append(schema.Enum, definition.Code)
+		fmt.Fprintf(&description, "\n- `%s` (%d): %s", definition.Code, definition.StatusCode, definition.Description)
+	}
+
+	schema.Description = description.String()
+
+	return schema
+}
+
+// fieldErrorsSchema is a schema for the errors specific to individual fields of
+// a request that an API error may carry, like validation failures.
+func fieldErrorsSchema() *Schema {
+	return &Schema{
+		Type:        SchemaType{"array"},
+		Description: "Errors specific to individual fields of the request, like validation failures.",
+		Items: &Schema{
+			Type: SchemaType{"object"},
+			Properties: map[string]*Schema{
+				"field": {
+					Type:        SchemaType{"string"},
+					Description: "The public name of the field.",
+				},
+				"json_path": {
+					Type:        SchemaType{"string"},
+					Description: "The full path to the field, like `items[3].name`.",
+				},
+				"message": {
+					Type:        SchemaType{"string"},
+					Description: "A human-friendly message describing what's wrong with the field.",
+				},
+				"param": {
+					Type:        SchemaType{"string"},
+					Description: "The parameter of the rule that failed, like `100` for `max=100`.",
+				},
+				"tag": {
+					Type:        SchemaType{"string"},
+					Description: "The name of the rule that failed, like `required`.",
+				},
+			},
+			Required: []string{"field", "json_path", "message", "param", "tag"},
+		},
+	}
+}
@@ -0,0 +1,508 @@
+package apiopenapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+func TestNewDocument(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T) *apiendpoint.Registry {
+		t.Helper()
+
+		var (
+			mux      = http.NewServeMux()
+			registry = apiendpoint.NewRegistry()
+			opts     = &apiendpoint.MountOpts{Registry: registry}
+		)
+
+		apiendpoint.Mount(mux, &jobGetEndpoint{}, opts)
+		apiendpoint.Mount(mux, &jobUpdateEndpoint{}, opts)
+
+		return registry
+	}
+
+	t.Run("Document", func(t *testing.T) {
+		t.Parallel()
+
+		registry := setup(t)
+
+		doc := NewDocument(registry, &DocumentOpts{
+			Servers: []*Server{{URL: "https://example.com"}},
+			Title:   "Test API",
+			Version: "1.2.3",
+		})
+
+		data, err := json.Marshal(doc)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"openapi": "3.1.0",
+			"info": {"title": "Test API", "version": "1.2.3"},
+			"servers": [{"url": "https://example.com"}],
+			"paths": {
+				"/api/jobs/{id}": {
+					"get": {
+						"operationId": "jobGet",
+						"parameters": [
+							{"in": "path", "name": "id", "required": true, "schema": {"type": "integer", "format": "int64", "minimum": 1}}
+						],
+						"responses": {
+							"200": {
+								"description": "OK",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/job"}}}
+							},
+							"400": {
+								"description": "Bad Request",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIError"}}}
+							},
+							"404": {
+								"description": "Not Found",
+								"content": {"application/json": {
+									"schema": {"$ref": "#/components/schemas/APIError"},
+									"examples": {"notFound": {"summary": "Job not found.", "value": {"message": "Job not found."}}}
+								}}
+							},
+							"500": {
+								"description": "Internal Server Error",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIError"}}}
+							},
+							"503": {
+								"description": "Service Unavailable",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIError"}}}
+							}
+						}
+					},
+					"patch": {
+						"operationId": "jobUpdate",
+						"parameters": [
+							{"in": "path", "name": "id", "required": true, "schema": {"type": "integer", "format": "int64"}},
+							{"in": "header", "name": "If-Match", "schema": {"type": "string"}}
+						],
+						"requestBody": {
+							"content": {"application/json": {"schema": {"$ref": "#/components/schemas/jobUpdateRequest"}}},
+							"required": true
+						},
+						"responses": {
+							"200": {
+								"description": "OK",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/job"}}}
+							},
+							"400": {
+								"description": "Bad Request",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIError"}}}
+							},
+							"413": {
+								"description": "Request Entity Too Large",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIError"}}}
+							},
+							"415": {
+								"description": "Unsupported Media Type",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIError"}}}
+							},
+							"500": {
+								"description": "Internal Server Error",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIError"}}}
+							},
+							"503": {
+								"description": "Service Unavailable",
+								"content": {"application/json": {"schema": {"$ref": "#/components/schemas/APIError"}}}
+							}
+						}
+					}
+				}
+			},
+			"components": {
+				"schemas": {
+					"APIError": {
+						"type": "object",
+						"properties": {
+							"code": {"type": "string", "description": "A machine-readable code identifying the kind of error."},
+							"errors": {
+								"type": "array",
+								"description": "Errors specific to individual fields of the request, like validation failures.",
+								"items": {
+									"type": "object",
+									"properties": {
+										"field": {"type": "string", "description": "The public name of the field."},
+										"json_path": {"type": "string", "description": "The full path to the field, like `+"`items[3].name`"+`."},
+										"message": {"type": "string", "description": "A human-friendly message describing what's wrong with the field."},
+										"param": {"type": "string", "description": "The parameter of the rule that failed, like `+"`100` for `max=100`"+`."},
+										"tag": {"type": "string", "description": "The name of the rule that failed, like `+"`required`"+`."}
+									},
+									"required": ["field", "json_path", "message", "param", "tag"]
+								}
+							},
+							"message": {"type": "string", "description": "A human-friendly message describing what went wrong."}
+						},
+						"required": ["message"]
+					},
+					"job": {
+						"type": "object",
+						"properties": {
+							"id": {"type": "integer", "format": "int64"},
+							"queue": {"type": "string"}
+						}
+					},
+					"jobUpdateRequest": {
+						"type": "object",
+						"properties": {
+							"queue": {"type": "string", "maxLength": 100}
+						},
+						"required": ["queue"]
+					}
+				}
+			}
+		}`, string(data))
+	})
+
+	t.Run("ErrorFormatProblemDetails", func(t *testing.T) {
+		t.Parallel()
+
+		doc := NewDocument(setup(t), &DocumentOpts{ErrorFormat: apierror.FormatProblemDetails})
+
+		require.NotContains(t, doc.Components.Schemas, errorComponentName)
+		require.Equal(t, problemDetailsSchema(nil), doc.Components.Schemas[problemDetailsComponentName])
+
+		data, err := json.Marshal(doc.Paths["/api/jobs/{id}"].Get.Responses["404"])
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"description": "Not Found",
+			"content": {"application/problem+json": {
+				"schema": {"$ref": "#/components/schemas/ProblemDetails"},
+				"examples": {"notFound": {
+					"summary": "Job not found.",
+					"value": {"detail": "Job not found.", "status": 404, "title": "Not Found", "type": "about:blank"}
+				}}
+			}}
+		}`, string(data))
+	})
+
+	t.Run("ErrorCodeCatalog", func(t *testing.T) {
+		t.Parallel()
+
+		catalog := apierror.NewCatalog()
+		catalog.Register("job_not_found", http.StatusNotFound, "The job doesn't exist.")
+
+		doc := NewDocument(setup(t), &DocumentOpts{Catalog: catalog})
+
+		codeSchema := doc.Components.Schemas[errorComponentName].Properties["code"]
+		require.Contains(t, codeSchema.Enum, "job_not_found")
+		require.Contains(t, codeSchema.Enum, apierror.CodeValidationFailed)
+		require.Contains(t, codeSchema.Description, "\n- `job_not_found` (404): The job doesn't exist.")
+	})
+
+	t.Run("RequestMediaTypes", func(t *testing.T) {
+		t.Parallel()
+
+		decoders := apiendpoint.NewDecoderRegistry()
+		decoders.Register("application/msgpack", apiendpoint.DecoderFunc(func(_ *http.Request, _ []byte, _ any) error { return nil }))
+
+		var (
+			mux      = http.NewServeMux()
+			registry = apiendpoint.NewRegistry()
+		)
+
+		apiendpoint.Mount(mux, &jobUpdateEndpoint{requestMediaTypes: []string{"application/json", "application/msgpack"}}, &apiendpoint.MountOpts{Decoders: decoders, Registry: registry})
+
+		doc := NewDocument(registry, nil)
+
+		content := doc.Paths["/api/jobs/{id}"].Patch.RequestBody.Content
+		require.Len(t, content, 2)
+		require.Equal(t, "#/components/schemas/jobUpdateRequest", content["application/json"].Schema.Ref)
+		require.Equal(t, "#/components/schemas/jobUpdateRequest", content["application/msgpack"].Schema.Ref)
+	})
+
+	t.Run("FormRequestMediaTypes", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mux      = http.NewServeMux()
+			registry = apiendpoint.NewRegistry()
+		)
+
+		apiendpoint.Mount(mux, &jobAttachEndpoint{}, &apiendpoint.MountOpts{Registry: registry})
+
+		doc := NewDocument(registry, nil)
+
+		requestBody := doc.Paths["/api/jobs/{id}/attachments"].Post.RequestBody
+		require.True(t, requestBody.Required)
+		require.Len(t, requestBody.Content, 2)
+
+		schemaJSON := func(schema *Schema) string {
+			data, err := json.Marshal(schema)
+			require.NoError(t, err)
+			return string(data)
+		}
+
+		// Files can only be sent in multipart bodies.
+		require.JSONEq(t, `{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "maxLength": 100},
+				"tag":  {"type": "array", "items": {"type": "string"}}
+			},
+			"required": ["name"]
+		}`, schemaJSON(requestBody.Content[apiendpoint.MediaTypeForm].Schema))
+
+		require.JSONEq(t, `{
+			"type": "object",
+			"properties": {
+				"attachment": {"type": "array", "items": {"type": "string", "format": "binary"}, "minItems": 1},
+				"avatar":     {"type": "string", "format": "binary"},
+				"name":       {"type": "string", "maxLength": 100},
+				"tag":        {"type": "array", "items": {"type": "string"}}
+			},
+			"required": ["attachment", "name"]
+		}`, schemaJSON(requestBody.Content[apiendpoint.MediaTypeMultipartForm].Schema))
+
+		// The request struct has no JSON fields, so no component is needed.
+		require.NotContains(t, doc.Components.Schemas, "jobAttachRequest")
+	})
+
+	t.Run("ResponseMediaTypes", func(t *testing.T) {
+		t.Parallel()
+
+		encoders := apiendpoint.NewEncoderRegistry()
+		encoders.Register("application/msgpack", apiendpoint.NewEncoder("application/msgpack", func(_ any) ([]byte, error) { return nil, nil }))
+
+		var (
+			mux      = http.NewServeMux()
+			registry = apiendpoint.NewRegistry()
+		)
+
+		apiendpoint.Mount(mux, &jobUpdateEndpoint{responseMediaTypes: []string{"application/json", "application/msgpack"}}, &apiendpoint.MountOpts{Encoders: encoders, Registry: registry})
+
+		doc := NewDocument(registry, nil)
+
+		responses := doc.Paths["/api/jobs/{id}"].Patch.Responses
+
+		content := responses["200"].Content
+		require.Len(t, content, 2)
+		require.Equal(t, "#/components/schemas/job", content["application/json"].Schema.Ref)
+		require.Equal(t, "#/components/schemas/job", content["application/msgpack"].Schema.Ref)
+
+		require.Contains(t, responses, "406")
+	})
+
+	t.Run("WebSocket", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mux      = http.NewServeMux()
+			registry = apiendpoint.NewRegistry()
+		)
+
+		apiendpoint.MountWebSocket(mux, &jobWatchEndpoint{}, &apiendpoint.MountOpts{Registry: registry})
+
+		doc := NewDocument(registry, nil)
+
+		responses := doc.Paths["/api/jobs/{id}/watch"].Get.Responses
+		require.Equal(t, "Switching Protocols", responses["101"].Description)
+		require.Nil(t, responses["101"].Content)
+	})
+
+	t.Run("DefaultInfo", func(t *testing.T) {
+		t.Parallel()
+
+		doc := NewDocument(apiendpoint.NewRegistry(), nil)
+		require.Equal(t, &Info{Title: "API", Version: "0.0.0"}, doc.Info)
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		t.Parallel()
+
+		doc := NewDocument(setup(t), nil)
+
+		data, err := doc.YAML()
+		require.NoError(t, err)
+
+		var parsed map[string]any
+		require.NoError(t, yaml.Unmarshal(data, &parsed))
+		require.Equal(t, "3.1.0", parsed["openapi"])
+		require.Contains(t, string(data), "\n    /api/jobs/{id}:\n")
+		require.Contains(t, string(data), "\"200\":")
+	})
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux      = http.NewServeMux()
+		registry = apiendpoint.NewRegistry()
+	)
+
+	apiendpoint.Mount(mux, &jobGetEndpoint{}, &apiendpoint.MountOpts{Registry: registry})
+
+	handler := NewHandler(registry, nil)
+
+	t.Run("JSON", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+		var doc Document
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &doc))
+		require.Contains(t, doc.Paths, "/api/jobs/{id}")
+	})
+
+	t.Run("YAMLExtension", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "application/yaml; charset=utf-8", recorder.Header().Get("Content-Type"))
+		require.Contains(t, recorder.Body.String(), "openapi: 3.1.0\n")
+	})
+
+	t.Run("YAMLAccept", func(t *testing.T) {
+		t.Parallel()
+
+		req := httptest.NewRequest(http.MethodGet, "/openapi", nil)
+		req.Header.Set("Accept", "application/yaml")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, "application/yaml; charset=utf-8", recorder.Header().Get("Content-Type"))
+	})
+}
+
+func TestSplitPattern(t *testing.T) {
+	t.Parallel()
+
+	methods, path := splitPattern("GET /api/jobs/{id}")
+	require.Equal(t, []string{http.MethodGet}, methods)
+	require.Equal(t, "/api/jobs/{id}", path)
+
+	methods, path = splitPattern("POST example.com/api/files/{path...}")
+	require.Equal(t, []string{http.MethodPost}, methods)
+	require.Equal(t, "/api/files/{path}", path)
+
+	methods, path = splitPattern("/api/{$}")
+	require.Len(t, methods, 5)
+	require.Equal(t, "/api/", path)
+}
+
+type job struct {
+	ID    int64  `json:"id"`
+	Queue string `json:"queue"`
+}
+
+//
+// jobGetEndpoint
+//
+
+type jobGetEndpoint struct {
+	apiendpoint.Endpoint[jobGetRequest, job]
+}
+
+func (*jobGetEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Errors:     []apierror.Interface{apierror.NewNotFound("Job not found.")},
+		Pattern:    "GET /api/jobs/{id}",
+		StatusCode: http.StatusOK,
+	}
+}
+
+type jobGetRequest struct {
+	ID int64 `json:"-" path:"id" validate:"min=1"`
+}
+
+func (*jobGetEndpoint) Execute(_ context.Context, req *jobGetRequest) (*job, error) {
+	return &job{ID: req.ID}, nil
+}
+
+//
+// jobUpdateEndpoint
+//
+
+type jobUpdateEndpoint struct {
+	apiendpoint.Endpoint[jobUpdateRequest, job]
+
+	requestMediaTypes  []string
+	responseMediaTypes []string
+}
+
+func (e *jobUpdateEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:            "PATCH /api/jobs/{id}",
+		RequestMediaTypes:  e.requestMediaTypes,
+		ResponseMediaTypes: e.responseMediaTypes,
+		StatusCode:         http.StatusOK,
+	}
+}
+
+type jobUpdateRequest struct {
+	ID      int64  `json:"-"     path:"id"`
+	IfMatch string `header:"If-Match" json:"-"`
+	Queue   string `json:"queue" validate:"required,max=100"`
+}
+
+func (*jobUpdateEndpoint) Execute(_ context.Context, req *jobUpdateRequest) (*job, error) {
+	return &job{ID: req.ID, Queue: req.Queue}, nil
+}
+
+//
+// jobAttachEndpoint
+//
+
+type jobAttachEndpoint struct {
+	apiendpoint.Endpoint[jobAttachRequest, job]
+}
+
+func (*jobAttachEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:           "POST /api/jobs/{id}/attachments",
+		RequestMediaTypes: []string{apiendpoint.MediaTypeForm, apiendpoint.MediaTypeMultipartForm},
+		StatusCode:        http.StatusOK,
+	}
+}
+
+type jobAttachRequest struct {
+	Attachments []*apiendpoint.File `form:"attachment" json:"-"  validate:"required,min=1"`
+	Avatar      *apiendpoint.File   `form:"avatar"     json:"-"`
+	ID          int64               `json:"-"          path:"id"`
+	Name        string              `form:"name"       json:"-"  validate:"required,max=100"`
+	Tags        []string            `form:"tag"        json:"-"`
+}
+
+func (*jobAttachEndpoint) Execute(_ context.Context, req *jobAttachRequest) (*job, error) {
+	return &job{ID: req.ID}, nil
+}
+
+//
+// jobWatchEndpoint
+//
+
+type jobWatchEndpoint struct {
+	apiendpoint.Endpoint[jobGetRequest, job]
+}
+
+func (*jobWatchEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:    "GET /api/jobs/{id}/watch",
+		StatusCode: http.StatusSwitchingProtocols,
+	}
+}
+
+func (*jobWatchEndpoint) Execute(_ context.Context, _ *jobGetRequest, _ *apiendpoint.WebSocketConn[struct{}, job]) error {
+	return nil
+}
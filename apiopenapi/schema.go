@@ -0,0 +1,476 @@
+package apiopenapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/internal/apireflect"
+)
+
+// Schema is a JSON Schema (draft 2020-12) as used by OpenAPI 3.1. Only the
+// subset of keywords produced by reflecting over Go types is supported.
+//
+//nolint:tagliatelle
+type Schema struct {
+	Ref string `json:"$ref,omitempty"`
+
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MaxItems             *uint64            `json:"maxItems,omitempty"`
+	MaxLength            *uint64            `json:"maxLength,omitempty"`
+	MaxProperties        *uint64            `json:"maxProperties,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *uint64            `json:"minItems,omitempty"`
+	MinLength            *uint64            `json:"minLength,omitempty"`
+	MinProperties        *uint64            `json:"minProperties,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+}
+
+// SchemaType is the type (or types) of a schema, like `string` or `object`.
+// It's marshaled as a single string if there's only one type, and as an array
+// otherwise, like `["string", "null"]` for a nullable string.
+type SchemaType []string
+
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+
+	return json.Marshal([]string(t))
+}
+
+func (t *SchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = SchemaType{single}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()         //nolint:gochecknoglobals
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]() //nolint:gochecknoglobals
+	fileSliceType     = reflect.TypeFor[[]*apiendpoint.File]()    //nolint:gochecknoglobals
+	fileType          = reflect.TypeFor[*apiendpoint.File]()      //nolint:gochecknoglobals
+	timeType          = reflect.TypeFor[time.Time]()              //nolint:gochecknoglobals
+)
+
+// schemaGenerator reflects over Go types to produce schemas. Named struct
+// types are added to a set of component schemas and referenced by $ref so that
+// they're only described once, and so that recursive types work.
+type schemaGenerator struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// schemaFor returns a schema for the given type.
+func (g *schemaGenerator) schemaFor(typ reflect.Type) *Schema {
+	if valueType, ok := apireflect.ExplicitNullableValueType(typ); ok {
+		return nullable(g.schemaFor(valueType))
+	}
+
+	switch {
+	case typ == timeType:
+		return &Schema{Type: SchemaType{"string"}, Format: "date-time"}
+
+	// Types with custom JSON marshaling could look like anything, so they're
+	// described with an empty schema that accepts any value.
+	case typ.Implements(jsonMarshalerType) || reflect.PointerTo(typ).Implements(jsonMarshalerType):
+		return &Schema{}
+
+	case typ.Implements(textMarshalerType) || reflect.PointerTo(typ).Implements(textMarshalerType):
+		return &Schema{Type: SchemaType{"string"}}
+	}
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+
+	case reflect.Float32:
+		return &Schema{Type: SchemaType{"number"}, Format: "float"}
+
+	case reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}, Format: "double"}
+
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int32"}
+
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: SchemaType{"integer"}, Format: "int64"}
+
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}, Minimum: ptr(0.0)}
+
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}
+
+	case reflect.Array, reflect.Slice:
+		// Byte slices are marshaled as base64 strings by encoding/json.
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: SchemaType{"string"}, ContentEncoding: "base64"}
+		}
+
+		schema := &Schema{Type: SchemaType{"array"}, Items: g.schemaFor(typ.Elem())}
+		if typ.Kind() == reflect.Array {
+			schema.MinItems = ptr(uint64(typ.Len()))
+			schema.MaxItems = ptr(uint64(typ.Len()))
+		}
+
+		// A nil slice is marshaled as null.
+		if typ.Kind() == reflect.Slice {
+			return nullable(schema)
+		}
+
+		return schema
+
+	case reflect.Map:
+		// A nil map is marshaled as null.
+		return nullable(&Schema{Type: SchemaType{"object"}, AdditionalProperties: g.schemaFor(typ.Elem())})
+
+	case reflect.Pointer:
+		return nullable(g.schemaFor(typ.Elem()))
+
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.structSchema(typ)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + g.componentName(typ)}
+	}
+
+	// Interfaces and anything else that can't be described more precisely.
+	return &Schema{}
+}
+
+// componentName returns the name of the component schema for a named struct
+// type, generating the component if it hasn't been already.
+func (g *schemaGenerator) componentName(typ reflect.Type) string {
+	if name, ok := g.names[typ]; ok {
+		return name
+	}
+
+	name := typeName(typ)
+	for i := 2; ; i++ {
+		if _, ok := g.components[name]; !ok {
+			break
+		}
+		name = typeName(typ) + strconv.Itoa(i)
+	}
+
+	// Register the name before generating the schema so that recursive types
+	// can reference themselves.
+	g.names[typ] = name
+	g.components[name] = nil
+	g.components[name] = g.structSchema(typ)
+
+	return name
+}
+
+func (g *schemaGenerator) structSchema(typ reflect.Type) *Schema {
+	schema := &Schema{Type: SchemaType{"object"}, Properties: make(map[string]*Schema)}
+
+	for _, field := range apireflect.JSONFields(typ) {
+		fieldSchema, required := g.fieldSchema(field.StructField)
+		schema.Properties[field.Name] = fieldSchema
+
+		if required {
+			schema.Required = append(schema.Required, field.Name)
+		}
+	}
+
+	return schema
+}
+
+// formSchema returns a schema for the fields of a struct type that are bound
+// from a form body with `form` tags. Files are described as binary strings,
+// and are only included if withFiles is true, because only multipart bodies
+// can carry them.
+func (g *schemaGenerator) formSchema(typ reflect.Type, withFiles bool) *Schema {
+	schema := &Schema{Type: SchemaType{"object"}, Properties: make(map[string]*Schema)}
+
+	for _, field := range apireflect.FormFields(typ) {
+		var (
+			fieldSchema *Schema
+			required    bool
+		)
+
+		switch field.StructField.Type {
+		case fileType, fileSliceType:
+			if !withFiles {
+				continue
+			}
+
+			fieldSchema = &Schema{Type: SchemaType{"string"}, Format: "binary"}
+			if field.StructField.Type == fileSliceType {
+				fieldSchema = &Schema{Type: SchemaType{"array"}, Items: fieldSchema}
+			}
+
+			fieldRules, _ := apireflect.ValidateRules(field.StructField.Tag.Get("validate"))
+			required = applyValidateRules(fieldSchema, field.StructField.Type, fieldRules)
+
+		default:
+			fieldSchema, required = g.fieldSchema(field.StructField)
+
+			// Form fields are either present or not, and can't be null.
+			fieldSchema = nonNullSchema(fieldSchema)
+			fieldSchema.Type = slices.DeleteFunc(fieldSchema.Type, func(typ string) bool { return typ == "null" })
+		}
+
+		schema.Properties[field.Name] = fieldSchema
+
+		if required {
+			schema.Required = append(schema.Required, field.Name)
+		}
+	}
+
+	return schema
+}
+
+// fieldSchema returns a schema for a struct field, with constraints from its
+// `validate` tag applied, and whether the field is required.
+func (g *schemaGenerator) fieldSchema(field reflect.StructField) (*Schema, bool) {
+	schema := g.schemaFor(field.Type)
+
+	fieldRules, elemRules := apireflect.ValidateRules(field.Tag.Get("validate"))
+
+	required := applyValidateRules(schema, field.Type, fieldRules)
+
+	if len(elemRules) > 0 {
+		elemType := indirectType(field.Type)
+		if elemType.Kind() == reflect.Array || elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Map {
+			if elemSchema := nonNullSchema(schema).Items; elemSchema != nil {
+				applyValidateRules(elemSchema, elemType.Elem(), elemRules)
+			} else if elemSchema := nonNullSchema(schema).AdditionalProperties; elemSchema != nil {
+				applyValidateRules(elemSchema, elemType.Elem(), elemRules)
+			}
+		}
+	}
+
+	return schema, required
+}
+
+// applyValidateRules adds constraints described by validate rules to a schema
+// and returns true if one of the rules makes the value required.
+func applyValidateRules(schema *Schema, typ reflect.Type, rules []*apireflect.ValidateRule) bool {
+	var required bool
+
+	if valueType, ok := apireflect.ExplicitNullableValueType(typ); ok {
+		typ = valueType
+	}
+	typ = indirectType(typ)
+
+	// Constraints go on the non-null branch of a nullable reference.
+	target := nonNullSchema(schema)
+
+	for _, rule := range rules {
+		switch rule.Tag {
+		case "required":
+			required = true
+
+		case "email":
+			target.Format = "email"
+
+		case "uri", "url":
+			target.Format = "uri"
+
+		case "uuid", "uuid4":
+			target.Format = "uuid"
+
+		case "oneof":
+			for value := range strings.FieldsSeq(rule.Param) {
+				target.Enum = append(target.Enum, enumValue(typ, strings.Trim(value, "'")))
+			}
+
+		case "len":
+			applyBound(target, typ, rule.Param, true, false)
+			applyBound(target, typ, rule.Param, false, false)
+
+		case "gte", "min":
+			applyBound(target, typ, rule.Param, true, false)
+
+		case "gt":
+			applyBound(target, typ, rule.Param, true, true)
+
+		case "lte", "max":
+			applyBound(target, typ, rule.Param, false, false)
+
+		case "lt":
+			applyBound(target, typ, rule.Param, false, true)
+		}
+	}
+
+	return required
+}
+
+// applyBound applies a lower (if lower is true) or upper bound to a schema,
+// which depending on type is a length, item count, or numeric bound.
+func applyBound(schema *Schema, typ reflect.Type, param string, lower, exclusive bool) {
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		count, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return
+		}
+
+		// For counts, exclusive bounds are converted to inclusive ones.
+		if exclusive && lower {
+			count++
+		} else if exclusive && !lower {
+			if count == 0 {
+				return
+			}
+			count--
+		}
+
+		switch {
+		case typ.Kind() == reflect.String && lower:
+			schema.MinLength = &count
+		case typ.Kind() == reflect.String:
+			schema.MaxLength = &count
+		case typ.Kind() == reflect.Map && lower:
+			schema.MinProperties = &count
+		case typ.Kind() == reflect.Map:
+			schema.MaxProperties = &count
+		case lower:
+			schema.MinItems = &count
+		default:
+			schema.MaxItems = &count
+		}
+
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bound, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case lower && exclusive:
+			schema.ExclusiveMinimum = &bound
+		case lower:
+			schema.Minimum = &bound
+		case exclusive:
+			schema.ExclusiveMaximum = &bound
+		default:
+			schema.Maximum = &bound
+		}
+	}
+}
+
+// enumValue converts a `oneof` value to a number for numeric types so that it
+// appears in the schema with the right JSON type.
+func enumValue(typ reflect.Type, value string) any {
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	}
+
+	return value
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	return typ
+}
+
+// nonNullSchema returns the non-null branch of a schema produced by nullable,
+// or the schema itself if it wasn't wrapped.
+func nonNullSchema(schema *Schema) *Schema {
+	if len(schema.AnyOf) == 2 && schema.AnyOf[1].isNull() {
+		return schema.AnyOf[0]
+	}
+
+	return schema
+}
+
+// nullable returns a version of the given schema that also accepts null.
+func nullable(schema *Schema) *Schema {
+	switch {
+	// An empty schema already accepts anything, including null.
+	case schema.Ref == "" && len(schema.Type) < 1:
+		return schema
+
+	case schema.Ref != "":
+		return &Schema{AnyOf: []*Schema{schema, {Type: SchemaType{"null"}}}}
+
+	case !schema.isNullable():
+		schema.Type = append(schema.Type, "null")
+	}
+
+	return schema
+}
+
+func (s *Schema) isNull() bool {
+	return len(s.Type) == 1 && s.Type[0] == "null"
+}
+
+func (s *Schema) isNullable() bool {
+	for _, typ := range s.Type {
+		if typ == "null" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// typeNameInvalidCharsRE matches characters that aren't allowed in component
+// schema names.
+var typeNameInvalidCharsRE = regexp.MustCompile(`[^a-zA-Z0-9._-]+`) //nolint:gochecknoglobals
+
+// typeNamePackagePathRE matches package paths of type arguments in the names of
+// instantiated generic types, like `github.com/riverqueue/river.` in
+// `ListResponse[github.com/riverqueue/river.Job]`.
+var typeNamePackagePathRE = regexp.MustCompile(`[\w./-]*[/.]`) //nolint:gochecknoglobals
+
+// typeName returns a name for a type suitable for use as a component schema
+// name. Instantiated generic types like `ListResponse[pkg.Job]` become
+// `ListResponse_Job`.
+func typeName(typ reflect.Type) string {
+	name, typeArgs, ok := strings.Cut(typ.Name(), "[")
+	if !ok {
+		return name
+	}
+
+	typeArgs = typeNamePackagePathRE.ReplaceAllString(strings.TrimSuffix(typeArgs, "]"), "")
+	return name + "_" + strings.Trim(typeNameInvalidCharsRE.ReplaceAllString(typeArgs, "_"), "_")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,134 @@
+package apiopenapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apitype"
+)
+
+func TestSchemaGenerator(t *testing.T) {
+	t.Parallel()
+
+	requireSchemaJSON := func(t *testing.T, expected string, schema *Schema) {
+		t.Helper()
+
+		data, err := json.Marshal(schema)
+		require.NoError(t, err)
+		require.JSONEq(t, expected, string(data))
+	}
+
+	t.Run("Primitives", func(t *testing.T) {
+		t.Parallel()
+
+		generator := newSchemaGenerator()
+
+		requireSchemaJSON(t, `{"type":"boolean"}`, generator.schemaFor(reflect.TypeFor[bool]()))
+		requireSchemaJSON(t, `{"type":"integer","format":"int64"}`, generator.schemaFor(reflect.TypeFor[int64]()))
+		requireSchemaJSON(t, `{"type":"integer","format":"int32"}`, generator.schemaFor(reflect.TypeFor[int32]()))
+		requireSchemaJSON(t, `{"type":"integer","minimum":0}`, generator.schemaFor(reflect.TypeFor[uint]()))
+		requireSchemaJSON(t, `{"type":"number","format":"double"}`, generator.schemaFor(reflect.TypeFor[float64]()))
+		requireSchemaJSON(t, `{"type":"string"}`, generator.schemaFor(reflect.TypeFor[string]()))
+		requireSchemaJSON(t, `{"type":"string","format":"date-time"}`, generator.schemaFor(reflect.TypeFor[time.Time]()))
+		requireSchemaJSON(t, `{"type":"string","contentEncoding":"base64"}`, generator.schemaFor(reflect.TypeFor[[]byte]()))
+		requireSchemaJSON(t, `{}`, generator.schemaFor(reflect.TypeFor[json.RawMessage]()))
+		requireSchemaJSON(t, `{}`, generator.schemaFor(reflect.TypeFor[any]()))
+	})
+
+	t.Run("Nullable", func(t *testing.T) {
+		t.Parallel()
+
+		type named struct{}
+
+		generator := newSchemaGenerator()
+
+		requireSchemaJSON(t, `{"type":["string","null"]}`, generator.schemaFor(reflect.TypeFor[*string]()))
+		requireSchemaJSON(t, `{"type":["array","null"],"items":{"type":"string"}}`, generator.schemaFor(reflect.TypeFor[[]string]()))
+		requireSchemaJSON(t, `{"type":["object","null"],"additionalProperties":{"type":"boolean"}}`, generator.schemaFor(reflect.TypeFor[map[string]bool]()))
+		requireSchemaJSON(t, `{"anyOf":[{"$ref":"#/components/schemas/named"},{"type":"null"}]}`, generator.schemaFor(reflect.TypeFor[*named]()))
+		requireSchemaJSON(t, `{"type":["string","null"]}`, generator.schemaFor(reflect.TypeFor[apitype.ExplicitNullable[string]]()))
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		t.Parallel()
+
+		type embedded struct {
+			CreatedAt time.Time `json:"created_at"`
+		}
+
+		type job struct {
+			embedded
+
+			ID       int64                            `json:"id"                 validate:"required,min=1"`
+			Kind     string                           `json:"kind"               validate:"required,max=100"`
+			Label    apitype.ExplicitNullable[string] `json:"label"              validate:"omitempty,min=1,max=100"`
+			Priority int                              `json:"priority,omitempty" validate:"oneof=1 2 3 4"`
+			State    string                           `json:"state"              validate:"oneof=available running"`
+			Tags     []string                         `json:"tags"               validate:"max=10,dive,max=20"`
+			Limit    int                              `json:"-"                  query:"limit"`
+			internal string
+		}
+
+		generator := newSchemaGenerator()
+
+		requireSchemaJSON(t, `{"$ref":"#/components/schemas/job"}`, generator.schemaFor(reflect.TypeFor[job]()))
+		requireSchemaJSON(t, `{
+			"type": "object",
+			"properties": {
+				"created_at": {"type":"string","format":"date-time"},
+				"id":         {"type":"integer","format":"int64","minimum":1},
+				"kind":       {"type":"string","maxLength":100},
+				"label":      {"type":["string","null"],"minLength":1,"maxLength":100},
+				"priority":   {"type":"integer","format":"int64","enum":[1,2,3,4]},
+				"state":      {"type":"string","enum":["available","running"]},
+				"tags":       {"type":["array","null"],"items":{"type":"string","maxLength":20},"maxItems":10}
+			},
+			"required": ["id","kind"]
+		}`, generator.components["job"])
+	})
+
+	t.Run("Recursive", func(t *testing.T) {
+		t.Parallel()
+
+		type node struct {
+			Children []*node `json:"children"`
+		}
+
+		generator := newSchemaGenerator()
+
+		requireSchemaJSON(t, `{"$ref":"#/components/schemas/node"}`, generator.schemaFor(reflect.TypeFor[node]()))
+		requireSchemaJSON(t, `{
+			"type": "object",
+			"properties": {
+				"children": {"type":["array","null"],"items":{"anyOf":[{"$ref":"#/components/schemas/node"},{"type":"null"}]}}
+			}
+		}`, generator.components["node"])
+	})
+
+	t.Run("AnonymousStruct", func(t *testing.T) {
+		t.Parallel()
+
+		generator := newSchemaGenerator()
+
+		requireSchemaJSON(t, `{"type":"object","properties":{"name":{"type":"string"}}}`,
+			generator.schemaFor(reflect.TypeFor[struct {
+				Name string `json:"name"`
+			}]()))
+	})
+}
+
+type genericPage[T any] struct {
+	Data []T `json:"data"`
+}
+
+func TestTypeName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Schema", typeName(reflect.TypeFor[Schema]()))
+	require.Equal(t, "genericPage_Schema", typeName(reflect.TypeFor[genericPage[Schema]]()))
+	require.Equal(t, "genericPage_string", typeName(reflect.TypeFor[genericPage[string]]()))
+}
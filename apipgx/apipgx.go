@@ -0,0 +1,235 @@
+// Package apipgx interprets errors from the pgx Postgres driver as API errors.
+// It's opt-in, and enabled by adding an ErrorInterpreter to
+// apiendpoint.MountOpts:
+//
+//	apiendpoint.Mount(mux, endpoint, &apiendpoint.MountOpts{
+//		ErrorInterpreters: []apiendpoint.ErrorInterpreter{
+//			&apipgx.ErrorInterpreter{
+//				Constraints: map[string]*apipgx.Constraint{
+//					"users_email_key": {Field: "email", Message: "A user with this email already exists."},
+//				},
+//			},
+//		},
+//	})
+package apipgx
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+// DefaultRetryAfter is how long clients are told to wait before retrying after
+// a transient database error when ErrorInterpreter.RetryAfter isn't set.
+const DefaultRetryAfter = 1 * time.Second
+
+// Constraint describes how violations of a named database constraint are
+// reported to users.
+type Constraint struct {
+	// Field is the public name of the request field that the constraint
+	// applies to, like `email`. If set, the error includes a field error for
+	// it (see apierror.APIError.Errors). Optional.
+	Field string
+
+	// Message is a user-facing message describing the violation, like "A user
+	// with this email already exists." If empty, a generic message for the
+	// type of violation is used.
+	Message string
+}
+
+// ErrorInterpreter is an apiendpoint.ErrorInterpreter that makes Postgres
+// errors that are likely caused by a request or by transient conditions back
+// into something public facing, instead of letting them leak out as internal
+// server errors:
+//
+//   - unique_violation and exclusion_violation are a 409 Conflict with code
+//     apierror.CodeAlreadyExists.
+//   - foreign_key_violation is a 409 Conflict with code
+//     apierror.CodeReferenceViolation.
+//   - check_violation and not_null_violation are a 422 UnprocessableEntity
+//     with code apierror.CodeConstraintViolation.
+//   - serialization_failure and deadlock_detected are a 503
+//     ServiceUnavailable with code apierror.CodeTransactionConflict.
+//   - query_canceled (which includes statement_timeout) and
+//     lock_not_available (lock_timeout) are a 503 ServiceUnavailable with
+//     code apierror.CodeTimeout.
+//   - read_only_sql_transaction is a 503 ServiceUnavailable with code
+//     apierror.CodeDatabaseReadOnly.
+//   - insufficient_privilege is a 400 BadRequest with code
+//     apierror.CodeDatabasePermissionDenied.
+//   - A failure to connect to the database is a 400 BadRequest with code
+//     apierror.CodeDatabaseUnavailable.
+//   - pgx.ErrNoRows is a 404 NotFound with code apierror.CodeNotFound, but
+//     only if NotFoundOnNoRows is set.
+//
+// 503s include a Retry-After header. Messages of constraint violations can be
+// customized by constraint (or for not-null violations, column) name using
+// Constraints. The original *pgconn.PgError is kept as the API error's
+// internal error so that it's logged. Other errors aren't interpreted.
+type ErrorInterpreter struct {
+	// Constraints maps the names of database constraints, like
+	// `users_email_key`, to how their violations are reported. Violations of
+	// constraints that aren't listed get a generic message.
+	//
+	// Postgres doesn't name a constraint for a not_null_violation, so those
+	// are looked up by column instead, first as `table.column` (like
+	// `users.email`), then as just `column`.
+	Constraints map[string]*Constraint
+
+	// NotFoundOnNoRows maps pgx.ErrNoRows, which is returned when a query
+	// expecting a row doesn't find one, to a NotFound. This saves endpoints
+	// that look up a resource from checking for it themselves. The message
+	// names the endpoint's apiendpoint.EndpointMeta.ResourceName if it's set,
+	// like "Job not found.", and is "Resource not found." otherwise.
+	//
+	// Take care when enabling this for endpoints that run queries other than
+	// the lookup of the requested resource, because a missing row in any of
+	// them will produce a NotFound.
+	NotFoundOnNoRows bool
+
+	// RetryAfter is how long clients are told to wait before retrying after a
+	// transient error like a serialization failure. If zero,
+	// DefaultRetryAfter is used.
+	RetryAfter time.Duration
+}
+
+var _ apiendpoint.ErrorInterpreter = &ErrorInterpreter{}
+
+func (i *ErrorInterpreter) InterpretError(ctx context.Context, err error) apierror.Interface {
+	if i.NotFoundOnNoRows && errors.Is(err, pgx.ErrNoRows) {
+		return apierror.WithCode(apierror.NewNotFound(notFoundMessage(apiendpoint.MetaFromContext(ctx))), apierror.CodeNotFound)
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return apierror.WithCode(apierror.NewBadRequest("There was a problem connecting to the configured database. Check logs for details."), apierror.CodeDatabaseUnavailable)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	apiErr := i.interpretPgError(pgErr)
+	if apiErr == nil {
+		return nil
+	}
+
+	return apierror.WithInternalError(apiErr, pgErr)
+}
+
+func (i *ErrorInterpreter) interpretPgError(pgErr *pgconn.PgError) apierror.Interface {
+	switch pgErr.Code {
+	case pgerrcode.UniqueViolation, pgerrcode.ExclusionViolation:
+		message, fieldErrs := i.constraintDetails(pgErr, "A resource with the same unique value already exists.", "unique")
+		apiErr := apierror.WithCode(apierror.NewConflict(message), apierror.CodeAlreadyExists)
+		apiErr.Errors = fieldErrs
+		return apiErr
+
+	case pgerrcode.ForeignKeyViolation:
+		message, fieldErrs := i.constraintDetails(pgErr, "The operation references a resource that doesn't exist, or removes one that's still referenced.", "foreign_key")
+		apiErr := apierror.WithCode(apierror.NewConflict(message), apierror.CodeReferenceViolation)
+		apiErr.Errors = fieldErrs
+		return apiErr
+
+	case pgerrcode.CheckViolation:
+		message, fieldErrs := i.constraintDetails(pgErr, "A value doesn't satisfy a constraint.", "check")
+		apiErr := apierror.WithCode(apierror.NewUnprocessableEntity(message), apierror.CodeConstraintViolation)
+		apiErr.Errors = fieldErrs
+		return apiErr
+
+	case pgerrcode.NotNullViolation:
+		message, fieldErrs := i.constraintDetails(pgErr, "A required value is missing.", "not_null")
+		apiErr := apierror.WithCode(apierror.NewUnprocessableEntity(message), apierror.CodeConstraintViolation)
+		apiErr.Errors = fieldErrs
+		return apiErr
+
+	case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected:
+		return apierror.WithCode(apierror.NewServiceUnavailable("The operation conflicted with a concurrent one. Retrying the request might work.").WithRetryAfter(i.retryAfter()), apierror.CodeTransactionConflict)
+
+	case pgerrcode.QueryCanceled, pgerrcode.LockNotAvailable:
+		return apierror.WithCode(apierror.NewServiceUnavailable("Database operation timed out. Retrying the request might work.").WithRetryAfter(i.retryAfter()), apierror.CodeTimeout)
+
+	case pgerrcode.ReadOnlySQLTransaction:
+		return apierror.WithCode(apierror.NewServiceUnavailable("The database is temporarily read-only. Retrying the request might work.").WithRetryAfter(i.retryAfter()), apierror.CodeDatabaseReadOnly)
+
+	case pgerrcode.InsufficientPrivilege:
+		return apierror.WithCode(apierror.NewBadRequest("Insufficient database privilege to perform this operation."), apierror.CodeDatabasePermissionDenied)
+	}
+
+	return nil
+}
+
+// constraintDetails returns a message and field errors for a constraint
+// violation, using the violated constraint's entry in Constraints if there is
+// one, and falling back to a default message otherwise.
+func (i *ErrorInterpreter) constraintDetails(pgErr *pgconn.PgError, defaultMessage, tag string) (string, []*apierror.FieldError) {
+	constraint, ok := i.lookupConstraint(pgErr)
+	if !ok {
+		return defaultMessage, nil
+	}
+
+	message := constraint.Message
+	if message == "" {
+		message = defaultMessage
+	}
+
+	if constraint.Field == "" {
+		return message, nil
+	}
+
+	return message, []*apierror.FieldError{
+		{Field: constraint.Field, JSONPath: constraint.Field, Message: message, Tag: tag},
+	}
+}
+
+// lookupConstraint finds the entry in Constraints for a constraint violation.
+// Errors without a constraint name, like a not_null_violation, are looked up
+// by their column, qualified by table first.
+func (i *ErrorInterpreter) lookupConstraint(pgErr *pgconn.PgError) (*Constraint, bool) {
+	if pgErr.ConstraintName != "" {
+		constraint, ok := i.Constraints[pgErr.ConstraintName]
+		return constraint, ok
+	}
+
+	if pgErr.ColumnName == "" {
+		return nil, false
+	}
+
+	if pgErr.TableName != "" {
+		if constraint, ok := i.Constraints[pgErr.TableName+"."+pgErr.ColumnName]; ok {
+			return constraint, true
+		}
+	}
+
+	constraint, ok := i.Constraints[pgErr.ColumnName]
+	return constraint, ok
+}
+
+// notFoundMessage returns the message of a NotFound for an endpoint, naming
+// its resource if it has one.
+func notFoundMessage(meta *apiendpoint.EndpointMeta) string {
+	if meta == nil || meta.ResourceName == "" {
+		return "Resource not found."
+	}
+
+	first, size := utf8.DecodeRuneInString(meta.ResourceName)
+	return string(unicode.ToUpper(first)) + meta.ResourceName[size:] + " not found."
+}
+
+func (i *ErrorInterpreter) retryAfter() time.Duration {
+	if i.RetryAfter == 0 {
+		return DefaultRetryAfter
+	}
+
+	return i.RetryAfter
+}
@@ -0,0 +1,264 @@
+package apipgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/river/rivershared/riversharedtest"
+)
+
+func TestErrorInterpreter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx         = context.Background()
+		interpreter = &ErrorInterpreter{
+			Constraints: map[string]*Constraint{
+				"users_email_key":    {Field: "email", Message: "A user with this email already exists."},
+				"users_age_check":    {Field: "age"},
+				"users_team_id_fkey": {Message: "Team doesn't exist."},
+				"users.name":         {Field: "name", Message: "A name is required."},
+				"email":              {Field: "email"},
+			},
+		}
+	)
+
+	t.Run("ConnectError", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := pgconn.Connect(ctx, "postgres://user@127.0.0.1:37283/does_not_exist")
+
+		require.Equal(t, apierror.WithCode(apierror.NewBadRequest("There was a problem connecting to the configured database. Check logs for details."), apierror.CodeDatabaseUnavailable), interpreter.InterpretError(ctx, err))
+	})
+
+	t.Run("InternalErrorIsPgError", func(t *testing.T) {
+		t.Parallel()
+
+		// Wrap the error to make it more realistic.
+		pgErr := &pgconn.PgError{Code: pgerrcode.InsufficientPrivilege}
+		err := fmt.Errorf("error running Postgres query: %w", pgErr)
+
+		require.Equal(t, apierror.WithInternalError(apierror.WithCode(apierror.NewBadRequest("Insufficient database privilege to perform this operation."), apierror.CodeDatabasePermissionDenied), pgErr), interpreter.InterpretError(ctx, err))
+	})
+
+	t.Run("Codes", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tt := range []struct {
+			pgCode     string
+			code       string
+			message    string
+			statusCode int
+		}{
+			{pgerrcode.UniqueViolation, apierror.CodeAlreadyExists, "A resource with the same unique value already exists.", 409},
+			{pgerrcode.ExclusionViolation, apierror.CodeAlreadyExists, "A resource with the same unique value already exists.", 409},
+			{pgerrcode.ForeignKeyViolation, apierror.CodeReferenceViolation, "The operation references a resource that doesn't exist, or removes one that's still referenced.", 409},
+			{pgerrcode.CheckViolation, apierror.CodeConstraintViolation, "A value doesn't satisfy a constraint.", 422},
+			{pgerrcode.NotNullViolation, apierror.CodeConstraintViolation, "A required value is missing.", 422},
+			{pgerrcode.SerializationFailure, apierror.CodeTransactionConflict, "The operation conflicted with a concurrent one. Retrying the request might work.", 503},
+			{pgerrcode.DeadlockDetected, apierror.CodeTransactionConflict, "The operation conflicted with a concurrent one. Retrying the request might work.", 503},
+			{pgerrcode.QueryCanceled, apierror.CodeTimeout, "Database operation timed out. Retrying the request might work.", 503},
+			{pgerrcode.LockNotAvailable, apierror.CodeTimeout, "Database operation timed out. Retrying the request might work.", 503},
+			{pgerrcode.ReadOnlySQLTransaction, apierror.CodeDatabaseReadOnly, "The database is temporarily read-only. Retrying the request might work.", 503},
+			{pgerrcode.InsufficientPrivilege, apierror.CodeDatabasePermissionDenied, "Insufficient database privilege to perform this operation.", 400},
+		} {
+			pgErr := &pgconn.PgError{Code: tt.pgCode}
+
+			apiErr := interpreter.InterpretError(ctx, pgErr)
+			require.NotNil(t, apiErr, "pgCode: %s", tt.pgCode)
+			require.Equal(t, tt.code, apiErr.GetCode())
+			require.Equal(t, tt.message, apiErr.Error())
+			require.Equal(t, tt.statusCode, apiErr.GetStatusCode())
+			require.Equal(t, pgErr, apiErr.GetInternalError())
+		}
+	})
+
+	t.Run("RetryAfter", func(t *testing.T) {
+		t.Parallel()
+
+		apiErr := interpreter.InterpretError(ctx, &pgconn.PgError{Code: pgerrcode.SerializationFailure})
+		require.IsType(t, &apierror.ServiceUnavailable{}, apiErr)
+		require.Equal(t, "1", apiErr.(*apierror.ServiceUnavailable).Header.Get("Retry-After")) //nolint:forcetypeassert
+
+		interpreter := &ErrorInterpreter{RetryAfter: 5 * time.Second}
+		apiErr = interpreter.InterpretError(ctx, &pgconn.PgError{Code: pgerrcode.ReadOnlySQLTransaction})
+		require.Equal(t, "5", apiErr.(*apierror.ServiceUnavailable).Header.Get("Retry-After")) //nolint:forcetypeassert
+	})
+
+	t.Run("ConstraintWithFieldAndMessage", func(t *testing.T) {
+		t.Parallel()
+
+		pgErr := &pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "users_email_key"}
+
+		expectedErr := apierror.WithInternalError(apierror.WithCode(apierror.NewConflict("A user with this email already exists."), apierror.CodeAlreadyExists), pgErr)
+		expectedErr.Errors = []*apierror.FieldError{
+			{Field: "email", JSONPath: "email", Message: "A user with this email already exists.", Tag: "unique"},
+		}
+		require.Equal(t, expectedErr, interpreter.InterpretError(ctx, pgErr))
+	})
+
+	t.Run("ConstraintWithFieldOnly", func(t *testing.T) {
+		t.Parallel()
+
+		pgErr := &pgconn.PgError{Code: pgerrcode.CheckViolation, ConstraintName: "users_age_check"}
+
+		expectedErr := apierror.WithInternalError(apierror.WithCode(apierror.NewUnprocessableEntity("A value doesn't satisfy a constraint."), apierror.CodeConstraintViolation), pgErr)
+		expectedErr.Errors = []*apierror.FieldError{
+			{Field: "age", JSONPath: "age", Message: "A value doesn't satisfy a constraint.", Tag: "check"},
+		}
+		require.Equal(t, expectedErr, interpreter.InterpretError(ctx, pgErr))
+	})
+
+	t.Run("ConstraintWithMessageOnly", func(t *testing.T) {
+		t.Parallel()
+
+		pgErr := &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation, ConstraintName: "users_team_id_fkey"}
+
+		require.Equal(t, apierror.WithInternalError(apierror.WithCode(apierror.NewConflict("Team doesn't exist."), apierror.CodeReferenceViolation), pgErr), interpreter.InterpretError(ctx, pgErr))
+	})
+
+	t.Run("NotNullByTableAndColumn", func(t *testing.T) {
+		t.Parallel()
+
+		pgErr := &pgconn.PgError{Code: pgerrcode.NotNullViolation, ColumnName: "name", TableName: "users"}
+
+		expectedErr := apierror.WithInternalError(apierror.WithCode(apierror.NewUnprocessableEntity("A name is required."), apierror.CodeConstraintViolation), pgErr)
+		expectedErr.Errors = []*apierror.FieldError{
+			{Field: "name", JSONPath: "name", Message: "A name is required.", Tag: "not_null"},
+		}
+		require.Equal(t, expectedErr, interpreter.InterpretError(ctx, pgErr))
+	})
+
+	t.Run("NotNullByColumn", func(t *testing.T) {
+		t.Parallel()
+
+		pgErr := &pgconn.PgError{Code: pgerrcode.NotNullViolation, ColumnName: "email", TableName: "teams"}
+
+		expectedErr := apierror.WithInternalError(apierror.WithCode(apierror.NewUnprocessableEntity("A required value is missing."), apierror.CodeConstraintViolation), pgErr)
+		expectedErr.Errors = []*apierror.FieldError{
+			{Field: "email", JSONPath: "email", Message: "A required value is missing.", Tag: "not_null"},
+		}
+		require.Equal(t, expectedErr, interpreter.InterpretError(ctx, pgErr))
+	})
+
+	t.Run("NotNullUnknownColumn", func(t *testing.T) {
+		t.Parallel()
+
+		pgErr := &pgconn.PgError{Code: pgerrcode.NotNullViolation, ColumnName: "name", TableName: "teams"}
+
+		require.Equal(t, apierror.WithInternalError(apierror.WithCode(apierror.NewUnprocessableEntity("A required value is missing."), apierror.CodeConstraintViolation), pgErr), interpreter.InterpretError(ctx, pgErr))
+	})
+
+	t.Run("UnknownConstraint", func(t *testing.T) {
+		t.Parallel()
+
+		pgErr := &pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "jobs_name_key"}
+
+		require.Equal(t, apierror.WithInternalError(apierror.WithCode(apierror.NewConflict("A resource with the same unique value already exists."), apierror.CodeAlreadyExists), pgErr), interpreter.InterpretError(ctx, pgErr))
+	})
+
+	t.Run("NoRowsNotMappedByDefault", func(t *testing.T) {
+		t.Parallel()
+
+		require.Nil(t, interpreter.InterpretError(ctx, pgx.ErrNoRows))
+	})
+
+	t.Run("NoRowsWithoutEndpoint", func(t *testing.T) {
+		t.Parallel()
+
+		interpreter := &ErrorInterpreter{NotFoundOnNoRows: true}
+
+		require.Equal(t, apierror.WithCode(apierror.NewNotFound("Resource not found."), apierror.CodeNotFound),
+			interpreter.InterpretError(ctx, fmt.Errorf("error getting job: %w", pgx.ErrNoRows)))
+	})
+
+	t.Run("OtherPGError", func(t *testing.T) {
+		t.Parallel()
+
+		require.Nil(t, interpreter.InterpretError(ctx, &pgconn.PgError{Code: pgerrcode.CardinalityViolation}))
+	})
+
+	t.Run("OtherError", func(t *testing.T) {
+		t.Parallel()
+
+		require.Nil(t, interpreter.InterpretError(ctx, errors.New("other error")))
+	})
+}
+
+func TestErrorInterpreterNoRowsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	serve := func(t *testing.T, endpoint *noRowsEndpoint) *httptest.ResponseRecorder {
+		t.Helper()
+
+		mux := http.NewServeMux()
+		apiendpoint.Mount(mux, endpoint, &apiendpoint.MountOpts{
+			ErrorInterpreters: []apiendpoint.ErrorInterpreter{&ErrorInterpreter{NotFoundOnNoRows: true}},
+			Logger:            riversharedtest.Logger(t),
+		})
+
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/jobs/123", nil))
+		return recorder
+	}
+
+	t.Run("ResourceName", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(t, &noRowsEndpoint{resourceName: "job"})
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+		require.JSONEq(t, `{"code":"not_found","message":"Job not found."}`, recorder.Body.String())
+	})
+
+	t.Run("ResourceNameMultibyte", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(t, &noRowsEndpoint{resourceName: "équipe"})
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+		require.JSONEq(t, `{"code":"not_found","message":"Équipe not found."}`, recorder.Body.String())
+	})
+
+	t.Run("NoResourceName", func(t *testing.T) {
+		t.Parallel()
+
+		recorder := serve(t, &noRowsEndpoint{})
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+		require.JSONEq(t, `{"code":"not_found","message":"Resource not found."}`, recorder.Body.String())
+	})
+}
+
+type noRowsEndpoint struct {
+	apiendpoint.Endpoint[noRowsRequest, noRowsResponse]
+
+	resourceName string
+}
+
+func (e *noRowsEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:      "GET /jobs/{id}",
+		ResourceName: e.resourceName,
+		StatusCode:   http.StatusOK,
+	}
+}
+
+type noRowsRequest struct {
+	ID int64 `json:"-" path:"id"`
+}
+
+type noRowsResponse struct{}
+
+func (*noRowsEndpoint) Execute(_ context.Context, _ *noRowsRequest) (*noRowsResponse, error) {
+	return nil, fmt.Errorf("error getting job: %w", pgx.ErrNoRows)
+}
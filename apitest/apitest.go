@@ -2,6 +2,7 @@ package apitest
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/riverqueue/apiframe/apiendpoint"
@@ -16,7 +17,9 @@ import (
 // observed that are normally only available from the API framework:
 //
 //   - Incoming request structs are validated and an API error is emitted in case
-//     they're invalid (any `validate` tags are checked).
+//     they're invalid (any `validate` tags are checked). The error is a
+//     *apierror.BadRequest with the same message and field errors (see
+//     apierror.APIError.Errors) as would be returned by a mounted endpoint.
 //   - Outgoing response structs are validated.
 //
 // Sample invocation:
@@ -35,7 +38,7 @@ func InvokeHandler[TReq any, TResp any](ctx context.Context, handler func(contex
 	}
 
 	if err := validator.StructCtx(ctx, req); err != nil {
-		return nil, apierror.NewBadRequest(validate.PublicFacingMessage(validator, err))
+		return nil, validate.PublicFacingError(validator, err)
 	}
 
 	resp, err := handler(ctx, req)
@@ -49,3 +52,20 @@ func InvokeHandler[TReq any, TResp any](ctx context.Context, handler func(contex
 
 	return resp, nil
 }
+
+// FieldErrors returns the field errors of a bad request API error, like one
+// returned by InvokeHandler for a request that failed validation. Returns nil
+// if err isn't a bad request.
+//
+//	_, err := apitest.InvokeHandler(ctx, endpoint.Execute, nil, &testRequest{})
+//	require.Equal(t, []*apierror.FieldError{
+//		{Field: "name", JSONPath: "name", Message: "Field `name` is required.", Tag: "required"},
+//	}, apitest.FieldErrors(err))
+func FieldErrors(err error) []*apierror.FieldError {
+	var badRequest *apierror.BadRequest
+	if !errors.As(err, &badRequest) {
+		return nil
+	}
+
+	return badRequest.Errors
+}
@@ -2,6 +2,8 @@ package apitest
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/go-playground/validator/v10"
@@ -40,7 +42,13 @@ func TestInvokeHandler(t *testing.T) {
 		t.Parallel()
 
 		_, err := InvokeHandler(ctx, handler, nil, &testRequest{RequiredReqField: ""})
-		require.Equal(t, apierror.NewBadRequestf("Field `req_field` is required."), err)
+
+		expectedErr := apierror.WithCode(apierror.NewBadRequest("Field `req_field` is required."), apierror.CodeValidationFailed)
+		expectedErr.Errors = []*apierror.FieldError{
+			{Field: "req_field", JSONPath: "req_field", Message: "Field `req_field` is required.", Tag: "required"},
+		}
+		require.Equal(t, expectedErr, err)
+		require.Equal(t, expectedErr.Errors, FieldErrors(err))
 	})
 
 	t.Run("ValidatesResponse", func(t *testing.T) {
@@ -65,3 +73,17 @@ func TestInvokeHandler(t *testing.T) {
 		require.Equal(t, &testResponse{RequiredRespField: "response value"}, resp)
 	})
 }
+
+func TestFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	fieldErrs := []*apierror.FieldError{{Field: "name", JSONPath: "name", Message: "Field `name` is required.", Tag: "required"}}
+
+	badRequest := apierror.NewBadRequest("Field `name` is required.")
+	badRequest.Errors = fieldErrs
+
+	require.Equal(t, fieldErrs, FieldErrors(badRequest))
+	require.Equal(t, fieldErrs, FieldErrors(fmt.Errorf("wrapped: %w", badRequest)))
+	require.Nil(t, FieldErrors(apierror.NewNotFound("Not found.")))
+	require.Nil(t, FieldErrors(errors.New("not an API error")))
+}
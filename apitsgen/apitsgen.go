@@ -0,0 +1,674 @@
+// Package apitsgen generates TypeScript types and a typed fetch client for
+// endpoints mounted with apiendpoint.Mount, so that frontends don't need to
+// repeat request and response shapes by hand. Endpoints are collected by
+// assigning an apiendpoint.Registry to MountOpts.Registry, then TypeScript is
+// generated from the registry's routes with an interface for every request
+// and response struct and a function for every endpoint.
+//
+// Generation is meant to be invoked with go:generate through a small program
+// that mounts the API's endpoints:
+//
+//	//go:generate go run ./internal/cmd/generatets
+//
+//	func main() {
+//		registry := apiendpoint.NewRegistry()
+//		api.MountEndpoints(http.NewServeMux(), &apiendpoint.MountOpts{Registry: registry})
+//
+//		if err := apitsgen.WriteFile("../frontend/src/api.ts", registry, nil); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// Interfaces follow encoding/json: properties are named after json tags,
+// fields with `omitempty` are optional, and pointers may be null. An
+// apitype.ExplicitNullable[T] field becomes an optional `T | null | undefined`
+// property, where leaving it undefined omits it from a request entirely.
+//
+// Request fields bound with `path`, `query`, or `header` tags are included in
+// request interfaces under their parameter name, and are sent as such by
+// endpoint functions. Fields bound with `cookie` tags are left out because
+// cookies are managed by the browser. Endpoint functions throw an APIError
+// for error responses, carrying the response's status code and its parsed
+// error body.
+package apitsgen
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/internal/apireflect"
+)
+
+// GenerateOpts are options for Generate.
+type GenerateOpts struct {
+	// TypesOnly generates only interfaces for request and response types,
+	// leaving out endpoint functions and their runtime. Useful for frontends
+	// that have their own way of making requests.
+	TypesOnly bool
+}
+
+// Generate generates TypeScript source for the routes in the given registry.
+// Routes of endpoints that don't both accept and produce JSON are skipped.
+func Generate(registry *apiendpoint.Registry, opts *GenerateOpts) ([]byte, error) {
+	if opts == nil {
+		opts = &GenerateOpts{}
+	}
+
+	gen := &generator{
+		functionNames: make(map[string]struct{}),
+		typeNames:     make(map[reflect.Type]string),
+		typesOnly:     opts.TypesOnly,
+		usedNames:     make(map[string]struct{}),
+	}
+
+	// Reserve names used by the runtime so generated names don't collide
+	// with them.
+	for _, name := range runtimeFunctionNames {
+		gen.functionNames[name] = struct{}{}
+	}
+	for _, name := range runtimeTypeNames {
+		gen.usedNames[name] = struct{}{}
+	}
+
+	for _, route := range registry.Routes() {
+		// Endpoints that only consume something like a form, or only produce
+		// something like an event stream, can't be called like a regular
+		// JSON endpoint.
+		if !route.ConsumesJSON() || !route.ProducesJSON() {
+			continue
+		}
+
+		if err := gen.addRoute(route); err != nil {
+			return nil, err
+		}
+	}
+
+	return gen.source(), nil
+}
+
+// WriteFile generates TypeScript with Generate and writes it to the given
+// path.
+func WriteFile(path string, registry *apiendpoint.Registry, opts *GenerateOpts) error {
+	src, err := Generate(registry, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, src, 0o600); err != nil {
+		return fmt.Errorf("error writing TypeScript: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	durationType      = reflect.TypeFor[time.Duration]()          //nolint:gochecknoglobals
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()         //nolint:gochecknoglobals
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]() //nolint:gochecknoglobals
+
+	identifierRE = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`) //nolint:gochecknoglobals
+
+	runtimeFunctionNames = []string{"appendQuery", "parseErrorBody", "pathValue", "request", "setHeader"}        //nolint:gochecknoglobals
+	runtimeTypeNames     = []string{"APIError", "APIErrorBody", "APIFieldError", "ParamValue", "RequestOptions"} //nolint:gochecknoglobals
+)
+
+type generator struct {
+	functionNames map[string]struct{}
+	functions     strings.Builder
+	typeDecls     strings.Builder
+	typeNames     map[reflect.Type]string // generated interfaces -> names
+	typesOnly     bool
+	usedNames     map[string]struct{}
+}
+
+func (g *generator) addRoute(route *apiendpoint.Route) error {
+	method, routePath := apireflect.SplitPattern(route.Meta.Pattern)
+	if method == "" {
+		return fmt.Errorf("pattern %q must start with a method to generate an endpoint function", route.Meta.Pattern)
+	}
+
+	reqTypeExpr := g.typeExpr(route.RequestType)
+	respTypeExpr := g.typeExpr(route.ResponseType)
+
+	if g.typesOnly {
+		return nil
+	}
+
+	var (
+		headers []*apiendpoint.RouteParameter
+		queries []*apiendpoint.RouteParameter
+		paths   = make(map[string]*apiendpoint.RouteParameter)
+	)
+	for _, param := range route.Parameters {
+		switch param.In {
+		case "header":
+			headers = append(headers, param)
+		case "path":
+			paths[param.Name] = param
+		case "query":
+			queries = append(queries, param)
+		}
+	}
+
+	pathExpr, err := pathExpr(routePath, paths)
+	if err != nil {
+		return fmt.Errorf("error generating path for %q: %w", route.Meta.Pattern, err)
+	}
+
+	functionName := apireflect.UniqueName(g.functionNames, functionName(route))
+
+	f := &g.functions
+	fmt.Fprintf(f, "\n/** Invokes `%s`. */\n", route.Meta.Pattern)
+	fmt.Fprintf(f, "export function %s(req: %s, opts?: RequestOptions): Promise<%s> {\n", functionName, reqTypeExpr, respTypeExpr)
+
+	if len(headers) > 0 {
+		f.WriteString("  const headers: Record<string, string> = {};\n")
+		for _, param := range headers {
+			fmt.Fprintf(f, "  setHeader(headers, %s, %s);\n", strconv.Quote(param.Name), propertyAccess("req", param.Name))
+		}
+	}
+
+	if len(queries) > 0 {
+		f.WriteString("  const query = new URLSearchParams();\n")
+		for _, param := range queries {
+			fmt.Fprintf(f, "  appendQuery(query, %s, %s);\n", strconv.Quote(param.Name), propertyAccess("req", param.Name))
+		}
+	}
+
+	var params []string
+
+	// Mirrors apiendpoint, which only reads request bodies for methods other
+	// than GET.
+	if method != http.MethodGet && route.RequestType.Kind() == reflect.Struct {
+		if fields := apireflect.JSONFields(route.RequestType); len(fields) > 0 {
+			if len(route.Parameters) > 0 {
+				// Pick out JSON fields so that parameters sent elsewhere aren't
+				// also sent in the body.
+				var body strings.Builder
+				body.WriteString("body: {\n")
+				for _, field := range fields {
+					fmt.Fprintf(&body, "      %s: %s,\n", propertyName(field.Name), propertyAccess("req", field.Name))
+				}
+				body.WriteString("    }")
+				params = append(params, body.String())
+			} else {
+				params = append(params, "body: req")
+			}
+		}
+	}
+	if len(headers) > 0 {
+		params = append(params, "headers")
+	}
+	if len(queries) > 0 {
+		params = append(params, "query")
+	}
+
+	if len(params) > 0 {
+		fmt.Fprintf(f, "  return request<%s>(%q, %s, {\n", respTypeExpr, method, pathExpr)
+		for _, param := range params {
+			fmt.Fprintf(f, "    %s,\n", param)
+		}
+		f.WriteString("  }, opts);\n}\n")
+	} else {
+		fmt.Fprintf(f, "  return request<%s>(%q, %s, {}, opts);\n}\n", respTypeExpr, method, pathExpr)
+	}
+
+	return nil
+}
+
+// pathExpr produces a TypeScript expression that builds a request path from a
+// pattern path like `/jobs/{id}` by substituting in path parameters from the
+// request.
+func pathExpr(routePath string, params map[string]*apiendpoint.RouteParameter) (string, error) {
+	var parts []string
+	for _, segment := range apireflect.PathSegments(routePath) {
+		if segment.Wildcard == "" {
+			parts = append(parts, strconv.Quote(segment.Literal))
+			continue
+		}
+
+		param, ok := params[segment.Wildcard]
+		if !ok {
+			return "", fmt.Errorf("wildcard {%s} isn't bound to a request struct field with a `path` tag", segment.Wildcard)
+		}
+
+		if segment.MultiSegment {
+			parts = append(parts, fmt.Sprintf("pathValue(%s, true)", propertyAccess("req", param.Name)))
+		} else {
+			parts = append(parts, fmt.Sprintf("pathValue(%s)", propertyAccess("req", param.Name)))
+		}
+	}
+
+	if len(parts) < 1 {
+		parts = append(parts, `""`)
+	}
+
+	return strings.Join(parts, " + "), nil
+}
+
+// typeExpr returns a TypeScript type expression describing how the given type
+// looks in JSON, generating an interface for any named struct types it
+// references along the way.
+func (g *generator) typeExpr(typ reflect.Type) string {
+	if valueType, ok := apireflect.ExplicitNullableValueType(typ); ok {
+		return nullableExpr(g.typeExpr(valueType))
+	}
+
+	if typ.Name() != "" && typ.PkgPath() != "" {
+		// Types like time.Time marshal themselves as JSON strings.
+		if typ.Implements(textMarshalerType) || reflect.PointerTo(typ).Implements(textMarshalerType) {
+			return "string"
+		}
+
+		// Custom JSON marshaling could produce anything.
+		if typ.Implements(jsonMarshalerType) || reflect.PointerTo(typ).Implements(jsonMarshalerType) {
+			return "unknown"
+		}
+
+		if typ.Kind() == reflect.Struct {
+			return g.interfaceName(typ)
+		}
+	}
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Array, reflect.Slice:
+		// Byte slices are marshaled as base64 strings.
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+
+		// A nil slice is marshaled as null.
+		if typ.Kind() == reflect.Slice {
+			return nullableExpr(arrayExpr(g.typeExpr(typ.Elem())))
+		}
+
+		return arrayExpr(g.typeExpr(typ.Elem()))
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "number"
+	case reflect.Map:
+		// A nil map is marshaled as null.
+		return nullableExpr("Record<string, " + g.typeExpr(typ.Elem()) + ">")
+	case reflect.Pointer:
+		return nullableExpr(g.typeExpr(typ.Elem()))
+	case reflect.String:
+		return "string"
+	case reflect.Struct:
+		return g.interfaceBody(typ, nil)
+	}
+
+	return "unknown"
+}
+
+// paramTypeExpr returns a TypeScript type expression for a request field bound
+// from a path, query, or header parameter. Parameters are parsed from strings,
+// so their types are those that format back into the expected string.
+func paramTypeExpr(typ reflect.Type) string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	if typ == durationType || typ.Implements(textMarshalerType) || reflect.PointerTo(typ).Implements(textMarshalerType) {
+		return "string"
+	}
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice:
+		return arrayExpr(paramTypeExpr(typ.Elem()))
+	case reflect.String:
+		return "string"
+	}
+
+	return "number"
+}
+
+// interfaceName returns the name of the interface generated for a named struct
+// type, generating it if it hasn't been already.
+func (g *generator) interfaceName(typ reflect.Type) string {
+	if name, ok := g.typeNames[typ]; ok {
+		return name
+	}
+
+	name := apireflect.UniqueName(g.usedNames, apireflect.ExportedTypeName(typ))
+
+	// Register the name before generating the interface so that recursive
+	// types can reference themselves.
+	g.typeNames[typ] = name
+
+	body := g.interfaceBody(typ, boundFields(typ))
+	fmt.Fprintf(&g.typeDecls, "\nexport interface %s %s\n", name, body)
+
+	return name
+}
+
+// interfaceBody generates the body of an interface containing the JSON fields
+// of typ, and any fields bound from path, query, or header parameters.
+func (g *generator) interfaceBody(typ reflect.Type, bound []*boundField) string {
+	var sb strings.Builder
+	sb.WriteString("{\n")
+
+	for _, field := range bound {
+		optional := "?"
+		if field.required {
+			optional = ""
+		}
+
+		fmt.Fprintf(&sb, "  %s%s: %s;\n", propertyName(field.name), optional, paramTypeExpr(field.typ))
+	}
+
+	for _, field := range apireflect.JSONFields(typ) {
+		fieldType := field.StructField.Type
+
+		var (
+			optional string
+			typeExpr string
+		)
+		switch valueType, isExplicitNullable := apireflect.ExplicitNullableValueType(fieldType); {
+		case isExplicitNullable:
+			// Left undefined, the field is omitted, which is distinct from
+			// an explicit null.
+			optional = "?"
+			typeExpr = nullableExpr(g.typeExpr(valueType)) + " | undefined"
+		case field.OmitEmpty && fieldType.Kind() == reflect.Pointer:
+			// A nil pointer is omitted rather than marshaled as null.
+			optional = "?"
+			typeExpr = g.typeExpr(fieldType.Elem())
+		case field.OmitEmpty && (fieldType.Kind() == reflect.Map || fieldType.Kind() == reflect.Slice):
+			// Likewise, a nil or empty map or slice is omitted.
+			optional = "?"
+			typeExpr = strings.TrimSuffix(g.typeExpr(fieldType), " | null")
+		case field.OmitEmpty:
+			optional = "?"
+			typeExpr = g.typeExpr(fieldType)
+		default:
+			typeExpr = g.typeExpr(fieldType)
+		}
+
+		// Anonymous structs are rendered inline, one level further in.
+		if strings.HasPrefix(typeExpr, "{\n") {
+			typeExpr = strings.ReplaceAll(typeExpr, "\n", "\n  ")
+		}
+
+		fmt.Fprintf(&sb, "  %s%s: %s;\n", propertyName(field.Name), optional, typeExpr)
+	}
+
+	sb.WriteString("}")
+	return sb.String()
+}
+
+func (g *generator) source() []byte {
+	var sb strings.Builder
+
+	sb.WriteString("// Code generated by apitsgen. DO NOT EDIT.\n")
+	sb.WriteString("/* eslint-disable */\n")
+
+	if !g.typesOnly {
+		sb.WriteString(runtimeSource)
+		sb.WriteString(g.functions.String())
+	}
+
+	sb.WriteString(g.typeDecls.String())
+
+	return []byte(sb.String())
+}
+
+type boundField struct {
+	name     string
+	required bool
+	typ      reflect.Type
+}
+
+// boundFields returns the fields of a struct type bound from path, query, or
+// header parameters, including those promoted from embedded structs. Path
+// parameters are always required, and others are required if they're
+// validated as such.
+func boundFields(typ reflect.Type) []*boundField {
+	var fields []*boundField
+
+	for _, field := range reflect.VisibleFields(typ) {
+		if !field.IsExported() {
+			continue
+		}
+
+		for _, tag := range []string{"path", "query", "header"} {
+			name := field.Tag.Get(tag)
+			if name == "" || name == "-" {
+				continue
+			}
+
+			required := tag == "path"
+			fieldRules, _ := apireflect.ValidateRules(field.Tag.Get("validate"))
+			for _, rule := range fieldRules {
+				if rule.Tag == "required" {
+					required = true
+				}
+			}
+
+			fields = append(fields, &boundField{name: name, required: required, typ: field.Type})
+			break
+		}
+	}
+
+	return fields
+}
+
+// arrayExpr returns an array type of the given element type, parenthesizing
+// union types like `(string | null)[]`.
+func arrayExpr(elemExpr string) string {
+	if strings.Contains(elemExpr, " | ") {
+		return "(" + elemExpr + ")[]"
+	}
+
+	return elemExpr + "[]"
+}
+
+// nullableExpr returns a TypeScript type expression that also accepts null,
+// unless the given one already does.
+func nullableExpr(expr string) string {
+	if strings.HasSuffix(expr, " | null") {
+		return expr
+	}
+
+	return expr + " | null"
+}
+
+// functionName produces an endpoint function name from the Go type of a
+// route's endpoint, like `jobGet` for *jobGetEndpoint.
+func functionName(route *apiendpoint.Route) string {
+	endpointType := reflect.TypeOf(route.Endpoint)
+	for endpointType.Kind() == reflect.Pointer {
+		endpointType = endpointType.Elem()
+	}
+
+	name := apireflect.LowerFirst(strings.TrimSuffix(endpointType.Name(), "Endpoint"))
+	if name == "" {
+		name = "invoke"
+	}
+
+	return name
+}
+
+// propertyAccess returns an expression accessing the given property of an
+// object, like `req.id` or `req["Idempotency-Key"]`.
+func propertyAccess(object, name string) string {
+	if identifierRE.MatchString(name) {
+		return object + "." + name
+	}
+
+	return object + "[" + strconv.Quote(name) + "]"
+}
+
+// propertyName returns an interface property name, quoting it if it's not a
+// valid identifier.
+func propertyName(name string) string {
+	if identifierRE.MatchString(name) {
+		return name
+	}
+
+	return strconv.Quote(name)
+}
+
+// runtimeSource is TypeScript included in generated output ahead of endpoint
+// functions, which use it to make requests.
+const runtimeSource = `
+/** Error thrown by endpoint functions when the API responds with an error. */
+export class APIError extends Error {
+  /** Parsed body of the error response. */
+  readonly body: APIErrorBody;
+
+  /** HTTP status code of the error response, like 404. */
+  readonly status: number;
+
+  constructor(status: number, body: APIErrorBody) {
+    super(body.message);
+    this.name = "APIError";
+    this.body = body;
+    this.status = status;
+  }
+}
+
+/**
+ * Body of an error response from the API. Errors written as RFC 9457 problem
+ * details have their detail copied to message, and carry their other members
+ * like type and title.
+ */
+export interface APIErrorBody {
+  /** Machine-readable code identifying the kind of error, if it has one. */
+  code?: string;
+
+  /** Errors specific to individual fields of the request, like validation failures. */
+  errors?: APIFieldError[];
+
+  /** Human-readable description of the error. */
+  message: string;
+
+  [member: string]: unknown;
+}
+
+/** Error specific to a single field of a request. */
+export interface APIFieldError {
+  /** Public name of the field, like "name". */
+  field: string;
+
+  /** Full path to the field, like "items[3].name". Empty for fields outside the body. */
+  json_path: string;
+
+  /** Human-readable description of what's wrong with the field. */
+  message: string;
+
+  /** Parameter of the rule that failed, like "100" for max=100. */
+  param: string;
+
+  /** Name of the rule that failed, like "required". */
+  tag: string;
+}
+
+/** Options for a request made by an endpoint function. */
+export interface RequestOptions {
+  /**
+   * URL that request paths are resolved against, like
+   * "https://example.com". Defaults to the current origin.
+   */
+  baseURL?: string;
+
+  /** Fetch implementation to use. Defaults to the global fetch. */
+  fetch?: typeof fetch;
+
+  /** Additional options for fetch, like credentials or signal. */
+  init?: RequestInit;
+}
+
+type ParamValue = boolean | number | string | (boolean | number | string)[] | null | undefined;
+
+function appendQuery(query: URLSearchParams, name: string, value: ParamValue): void {
+  if (value === null || value === undefined) {
+    return;
+  }
+
+  for (const elem of Array.isArray(value) ? value : [value]) {
+    query.append(name, String(elem));
+  }
+}
+
+function setHeader(headers: Record<string, string>, name: string, value: ParamValue): void {
+  if (value === null || value === undefined) {
+    return;
+  }
+
+  headers[name] = Array.isArray(value) ? value.map(String).join(", ") : String(value);
+}
+
+function pathValue(value: boolean | number | string, multiSegment = false): string {
+  if (multiSegment) {
+    return String(value).split("/").map(encodeURIComponent).join("/");
+  }
+
+  return encodeURIComponent(String(value));
+}
+
+async function request<TResp>(
+  method: string,
+  path: string,
+  params: { body?: unknown; headers?: Record<string, string>; query?: URLSearchParams },
+  opts: RequestOptions = {},
+): Promise<TResp> {
+  const headers = new Headers(opts.init?.headers);
+  headers.set("Accept", "application/json");
+  for (const [name, value] of Object.entries(params.headers ?? {})) {
+    headers.set(name, value);
+  }
+  if (params.body !== undefined) {
+    headers.set("Content-Type", "application/json");
+  }
+
+  let url = (opts.baseURL ?? "").replace(/\/$/, "") + path;
+  const query = params.query?.toString();
+  if (query) {
+    url += "?" + query;
+  }
+
+  const resp = await (opts.fetch ?? fetch)(url, {
+    ...opts.init,
+    body: params.body === undefined ? undefined : JSON.stringify(params.body),
+    headers,
+    method,
+  });
+
+  const text = await resp.text();
+  if (!resp.ok) {
+    throw new APIError(resp.status, parseErrorBody(resp, text));
+  }
+
+  return (text.trim() === "" ? {} : JSON.parse(text)) as TResp;
+}
+
+function parseErrorBody(resp: Response, text: string): APIErrorBody {
+  try {
+    const body = JSON.parse(text);
+    if (typeof body?.message === "string") {
+      return body as APIErrorBody;
+    }
+    if (typeof body?.detail === "string") {
+      return { ...body, message: body.detail };
+    }
+  } catch {
+    // Not JSON, like an error from a proxy. Fall back to the raw body.
+  }
+
+  return { message: text.trim() || resp.statusText };
+}
+`
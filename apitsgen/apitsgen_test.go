@@ -0,0 +1,208 @@
+package apitsgen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/apitype"
+	"github.com/riverqueue/apiframe/internal/testapi"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("TestClientUpToDate", func(t *testing.T) {
+		t.Parallel()
+
+		registry := apiendpoint.NewRegistry()
+		testapi.MountEndpoints(http.NewServeMux(), &apiendpoint.MountOpts{Registry: registry})
+
+		src, err := Generate(registry, nil)
+		require.NoError(t, err)
+
+		existingSrc, err := os.ReadFile(filepath.Join("internal", "testclient", "client.ts"))
+		require.NoError(t, err)
+
+		require.Equal(t, string(existingSrc), string(src), "Generated test client is out of date; run `go generate ./...`")
+	})
+
+	t.Run("PatternWithoutMethod", func(t *testing.T) {
+		t.Parallel()
+
+		registry := apiendpoint.NewRegistry()
+		apiendpoint.Mount(http.NewServeMux(), &noMethodEndpoint{}, &apiendpoint.MountOpts{Registry: registry})
+
+		_, err := Generate(registry, nil)
+		require.EqualError(t, err, `pattern "/api/no-method" must start with a method to generate an endpoint function`)
+	})
+
+	t.Run("SkipsFormOnlyEndpoints", func(t *testing.T) {
+		t.Parallel()
+
+		registry := apiendpoint.NewRegistry()
+		apiendpoint.Mount(http.NewServeMux(), &formEndpoint{}, &apiendpoint.MountOpts{Registry: registry})
+
+		src, err := Generate(registry, nil)
+		require.NoError(t, err)
+		require.NotContains(t, string(src), "POST /api/form")
+	})
+
+	t.Run("TypesOnly", func(t *testing.T) {
+		t.Parallel()
+
+		registry := apiendpoint.NewRegistry()
+		testapi.MountEndpoints(http.NewServeMux(), &apiendpoint.MountOpts{Registry: registry})
+
+		src, err := Generate(registry, &GenerateOpts{TypesOnly: true})
+		require.NoError(t, err)
+		require.Contains(t, string(src), "export interface JobGetRequest {")
+		require.NotContains(t, string(src), "APIError")
+		require.NotContains(t, string(src), "export function")
+	})
+}
+
+func TestTypeExpr(t *testing.T) {
+	t.Parallel()
+
+	typeExpr := func(t *testing.T, typ reflect.Type) (string, string) {
+		t.Helper()
+
+		gen := &generator{typeNames: make(map[reflect.Type]string), usedNames: make(map[string]struct{})}
+		return gen.typeExpr(typ), gen.typeDecls.String()
+	}
+
+	t.Run("Basic", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tt := range []struct {
+			typ      reflect.Type
+			expected string
+		}{
+			{reflect.TypeFor[any](), "unknown"},
+			{reflect.TypeFor[bool](), "boolean"},
+			{reflect.TypeFor[[]byte](), "string"},
+			{reflect.TypeFor[float64](), "number"},
+			{reflect.TypeFor[int64](), "number"},
+			{reflect.TypeFor[json.RawMessage](), "unknown"},
+			{reflect.TypeFor[[3]int](), "number[]"},
+			{reflect.TypeFor[map[string][]int](), "Record<string, number[] | null> | null"},
+			{reflect.TypeFor[*[]int](), "number[] | null"},
+			{reflect.TypeFor[*string](), "string | null"},
+			{reflect.TypeFor[[]*string](), "(string | null)[] | null"},
+			{reflect.TypeFor[string](), "string"},
+			{reflect.TypeFor[time.Duration](), "number"},
+			{reflect.TypeFor[time.Time](), "string"},
+			{reflect.TypeFor[uint8](), "number"},
+		} {
+			expr, _ := typeExpr(t, tt.typ)
+			require.Equal(t, tt.expected, expr, "for type %s", tt.typ)
+		}
+	})
+
+	t.Run("Struct", func(t *testing.T) {
+		t.Parallel()
+
+		expr, decls := typeExpr(t, reflect.TypeFor[testStruct]())
+		require.Equal(t, "TestStruct", expr)
+		require.Equal(t, `
+export interface TestStruct {
+  "Idempotency-Key": string;
+  id: number;
+  limit?: number;
+  children: TestStruct[] | null;
+  explicit_nullable?: string | null | undefined;
+  inline: {
+    name: string;
+  };
+  "kebab-case": string;
+  omit_empty?: string;
+  omit_empty_map?: Record<string, string>;
+  omit_empty_slice?: string[];
+  optional_pointer?: number;
+  pointer: number | null;
+}
+`, decls)
+	})
+}
+
+func TestPathExpr(t *testing.T) {
+	t.Parallel()
+
+	params := map[string]*apiendpoint.RouteParameter{
+		"id":   {In: "path", Name: "id"},
+		"path": {In: "path", Name: "path"},
+	}
+
+	expr, err := pathExpr("/api/jobs", params)
+	require.NoError(t, err)
+	require.Equal(t, `"/api/jobs"`, expr)
+
+	expr, err = pathExpr("/api/jobs/{id}/files/{path...}", params)
+	require.NoError(t, err)
+	require.Equal(t, `"/api/jobs/" + pathValue(req.id) + "/files/" + pathValue(req.path, true)`, expr)
+
+	_, err = pathExpr("/api/queues/{queue}", params)
+	require.EqualError(t, err, "wildcard {queue} isn't bound to a request struct field with a `path` tag")
+}
+
+type formEndpoint struct {
+	apiendpoint.Endpoint[formRequest, struct{}]
+}
+
+func (*formEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:           "POST /api/form",
+		RequestMediaTypes: []string{apiendpoint.MediaTypeForm},
+		StatusCode:        http.StatusOK,
+	}
+}
+
+type formRequest struct {
+	Name string `form:"name" json:"-"`
+}
+
+func (*formEndpoint) Execute(_ context.Context, _ *formRequest) (*struct{}, error) {
+	return &struct{}{}, nil
+}
+
+type noMethodEndpoint struct {
+	apiendpoint.Endpoint[struct{}, struct{}]
+}
+
+func (*noMethodEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:    "/api/no-method",
+		StatusCode: http.StatusOK,
+	}
+}
+
+func (*noMethodEndpoint) Execute(_ context.Context, _ *struct{}) (*struct{}, error) {
+	return &struct{}{}, nil
+}
+
+type testStruct struct {
+	IdempotencyKey   string                           `header:"Idempotency-Key" json:"-"            validate:"required"`
+	ID               int64                            `json:"-"                 path:"id"`
+	Limit            int                              `json:"-"                 query:"limit"`
+	Session          string                           `cookie:"session"         json:"-"`
+	Children         []testStruct                     `json:"children"`
+	ExplicitNullable apitype.ExplicitNullable[string] `json:"explicit_nullable"`
+	Inline           struct {
+		Name string `json:"name"`
+	} `json:"inline"`
+	KebabCase       string            `json:"kebab-case"`
+	OmitEmpty       string            `json:"omit_empty,omitempty"`
+	OmitEmptyMap    map[string]string `json:"omit_empty_map,omitempty"`
+	OmitEmptySlice  []string          `json:"omit_empty_slice,omitempty"`
+	OptionalPointer *int              `json:"optional_pointer,omitempty"`
+	Pointer         *int              `json:"pointer"`
+}
@@ -0,0 +1,217 @@
+// Code generated by apitsgen. DO NOT EDIT.
+/* eslint-disable */
+
+/** Error thrown by endpoint functions when the API responds with an error. */
+export class APIError extends Error {
+  /** Parsed body of the error response. */
+  readonly body: APIErrorBody;
+
+  /** HTTP status code of the error response, like 404. */
+  readonly status: number;
+
+  constructor(status: number, body: APIErrorBody) {
+    super(body.message);
+    this.name = "APIError";
+    this.body = body;
+    this.status = status;
+  }
+}
+
+/**
+ * Body of an error response from the API. Errors written as RFC 9457 problem
+ * details have their detail copied to message, and carry their other members
+ * like type and title.
+ */
+export interface APIErrorBody {
+  /** Machine-readable code identifying the kind of error, if it has one. */
+  code?: string;
+
+  /** Errors specific to individual fields of the request, like validation failures. */
+  errors?: APIFieldError[];
+
+  /** Human-readable description of the error. */
+  message: string;
+
+  [member: string]: unknown;
+}
+
+/** Error specific to a single field of a request. */
+export interface APIFieldError {
+  /** Public name of the field, like "name". */
+  field: string;
+
+  /** Full path to the field, like "items[3].name". Empty for fields outside the body. */
+  json_path: string;
+
+  /** Human-readable description of what's wrong with the field. */
+  message: string;
+
+  /** Parameter of the rule that failed, like "100" for max=100. */
+  param: string;
+
+  /** Name of the rule that failed, like "required". */
+  tag: string;
+}
+
+/** Options for a request made by an endpoint function. */
+export interface RequestOptions {
+  /**
+   * URL that request paths are resolved against, like
+   * "https://example.com". Defaults to the current origin.
+   */
+  baseURL?: string;
+
+  /** Fetch implementation to use. Defaults to the global fetch. */
+  fetch?: typeof fetch;
+
+  /** Additional options for fetch, like credentials or signal. */
+  init?: RequestInit;
+}
+
+type ParamValue = boolean | number | string | (boolean | number | string)[] | null | undefined;
+
+function appendQuery(query: URLSearchParams, name: string, value: ParamValue): void {
+  if (value === null || value === undefined) {
+    return;
+  }
+
+  for (const elem of Array.isArray(value) ? value : [value]) {
+    query.append(name, String(elem));
+  }
+}
+
+function setHeader(headers: Record<string, string>, name: string, value: ParamValue): void {
+  if (value === null || value === undefined) {
+    return;
+  }
+
+  headers[name] = Array.isArray(value) ? value.map(String).join(", ") : String(value);
+}
+
+function pathValue(value: boolean | number | string, multiSegment = false): string {
+  if (multiSegment) {
+    return String(value).split("/").map(encodeURIComponent).join("/");
+  }
+
+  return encodeURIComponent(String(value));
+}
+
+async function request<TResp>(
+  method: string,
+  path: string,
+  params: { body?: unknown; headers?: Record<string, string>; query?: URLSearchParams },
+  opts: RequestOptions = {},
+): Promise<TResp> {
+  const headers = new Headers(opts.init?.headers);
+  headers.set("Accept", "application/json");
+  for (const [name, value] of Object.entries(params.headers ?? {})) {
+    headers.set(name, value);
+  }
+  if (params.body !== undefined) {
+    headers.set("Content-Type", "application/json");
+  }
+
+  let url = (opts.baseURL ?? "").replace(/\/$/, "") + path;
+  const query = params.query?.toString();
+  if (query) {
+    url += "?" + query;
+  }
+
+  const resp = await (opts.fetch ?? fetch)(url, {
+    ...opts.init,
+    body: params.body === undefined ? undefined : JSON.stringify(params.body),
+    headers,
+    method,
+  });
+
+  const text = await resp.text();
+  if (!resp.ok) {
+    throw new APIError(resp.status, parseErrorBody(resp, text));
+  }
+
+  return (text.trim() === "" ? {} : JSON.parse(text)) as TResp;
+}
+
+function parseErrorBody(resp: Response, text: string): APIErrorBody {
+  try {
+    const body = JSON.parse(text);
+    if (typeof body?.message === "string") {
+      return body as APIErrorBody;
+    }
+    if (typeof body?.detail === "string") {
+      return { ...body, message: body.detail };
+    }
+  } catch {
+    // Not JSON, like an error from a proxy. Fall back to the raw body.
+  }
+
+  return { message: text.trim() || resp.statusText };
+}
+
+/** Invokes `POST /api/jobs`. */
+export function jobCreate(req: JobCreateRequest, opts?: RequestOptions): Promise<Job> {
+  const headers: Record<string, string> = {};
+  setHeader(headers, "Idempotency-Key", req["Idempotency-Key"]);
+  return request<Job>("POST", "/api/jobs", {
+    body: {
+      args: req.args,
+      label: req.label,
+      queue: req.queue,
+      scheduled_at: req.scheduled_at,
+    },
+    headers,
+  }, opts);
+}
+
+/** Invokes `DELETE /api/jobs/{id}`. */
+export function jobDelete(req: JobDeleteRequest, opts?: RequestOptions): Promise<JobDeleteResponse> {
+  return request<JobDeleteResponse>("DELETE", "/api/jobs/" + pathValue(req.id), {}, opts);
+}
+
+/** Invokes `GET /api/queues/{queue}/jobs/{id}`. */
+export function jobGet(req: JobGetRequest, opts?: RequestOptions): Promise<Job> {
+  const query = new URLSearchParams();
+  appendQuery(query, "attempt", req.attempt);
+  appendQuery(query, "wait", req.wait);
+  return request<Job>("GET", "/api/queues/" + pathValue(req.queue) + "/jobs/" + pathValue(req.id), {
+    query,
+  }, opts);
+}
+
+export interface JobCreateRequest {
+  "Idempotency-Key"?: string;
+  args: Record<string, unknown> | null;
+  label?: string | null | undefined;
+  queue: string;
+  scheduled_at: string;
+}
+
+export interface JobAttempt {
+  error: string;
+  num: number;
+}
+
+export interface Job {
+  id: number;
+  args: Record<string, unknown> | null;
+  attempts: JobAttempt[] | null;
+  label?: string;
+  queue: string;
+  scheduled_at: string;
+  state: string;
+}
+
+export interface JobDeleteRequest {
+  id: number;
+}
+
+export interface JobDeleteResponse {
+  deleted: boolean;
+}
+
+export interface JobGetRequest {
+  id: number;
+  queue: string;
+  attempt?: number[];
+  wait?: string;
+}
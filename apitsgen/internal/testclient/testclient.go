@@ -0,0 +1,5 @@
+// Package testclient contains TypeScript generated from the endpoints in
+// testapi that's used to verify generated output.
+package testclient
+
+//go:generate go run ./gen
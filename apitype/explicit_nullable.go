@@ -17,6 +17,23 @@ type ExplicitNullable[T any] struct {
 	Value *T
 }
 
+// IsZero returns true if the field wasn't set. Combined with an `omitzero` JSON
+// tag option, this lets an unset field be omitted when marshaling.
+func (ps ExplicitNullable[T]) IsZero() bool {
+	return !ps.Set
+}
+
+// MarshalJSON implements json.Marshaler so that an ExplicitNullable[T] field is
+// marshaled as its value, or as null if it was explicitly set to null. Use an
+// `omitzero` JSON tag option to omit the field entirely when it's not set.
+func (ps ExplicitNullable[T]) MarshalJSON() ([]byte, error) {
+	if !ps.Set || ps.Value == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(ps.Value)
+}
+
 // UnmarshalJSON implements json.Unmarshaler to handle the three possible states
 // of an ExplicitNullable[T] field in a JSON payload.
 func (ps *ExplicitNullable[T]) UnmarshalJSON(data []byte) error {
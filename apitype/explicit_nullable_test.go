@@ -178,3 +178,48 @@ func TestExplicitNullable_UnmarshalJSON(t *testing.T) {
 func ptr[T any](v T) *T {
 	return &v
 }
+
+func TestExplicitNullable_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	type omitZeroPayload struct {
+		Label ExplicitNullable[string] `json:"label,omitzero"`
+	}
+
+	tests := []struct {
+		name  string
+		input ExplicitNullable[string]
+		want  string
+	}{
+		{
+			name:  "FieldOmitted",
+			input: ExplicitNullable[string]{Set: false},
+			want:  `{}`,
+		},
+		{
+			name:  "ExplicitNull",
+			input: ExplicitNullable[string]{Set: true, Value: nil},
+			want:  `{"label":null}`,
+		},
+		{
+			name:  "NonEmptyString",
+			input: ExplicitNullable[string]{Set: true, Value: ptr("test")},
+			want:  `{"label":"test"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := json.Marshal(omitZeroPayload{Label: tt.input})
+			require.NoError(t, err)
+			require.JSONEq(t, tt.want, string(data))
+
+			// Round trips back to the same value.
+			var got omitZeroPayload
+			require.NoError(t, json.Unmarshal(data, &got))
+			require.Equal(t, tt.input, got.Label)
+		})
+	}
+}
@@ -0,0 +1,288 @@
+// Package apireflect contains reflection and naming helpers shared by packages
+// that generate artifacts like API documentation from endpoint request and
+// response types.
+package apireflect
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// BindingTags are struct tags that bind request struct fields from parts of a
+// request other than its body. Fields carrying one of these tags are assumed
+// not to be part of a request's JSON body.
+//
+// `form` isn't one of them, because form fields are part of the body, and a
+// field of an endpoint accepting both forms and JSON may be bound from either.
+// See FormFields.
+var BindingTags = []string{"cookie", "header", "path", "query"} //nolint:gochecknoglobals
+
+// Field is a struct field as it's seen by encoding/json.
+type Field struct {
+	// Name is the field's name in JSON.
+	Name string
+
+	// OmitEmpty is true if the field's JSON tag has an `omitempty` or
+	// `omitzero` option.
+	OmitEmpty bool
+
+	// StructField is the underlying struct field.
+	StructField reflect.StructField
+}
+
+// ExplicitNullableValueType returns the type wrapped by an
+// apitype.ExplicitNullable type, or false if typ isn't an ExplicitNullable.
+func ExplicitNullableValueType(typ reflect.Type) (reflect.Type, bool) {
+	if typ.Kind() != reflect.Struct ||
+		typ.PkgPath() != "github.com/riverqueue/apiframe/apitype" ||
+		!strings.HasPrefix(typ.Name(), "ExplicitNullable[") {
+		return nil, false
+	}
+
+	valueField, ok := typ.FieldByName("Value")
+	if !ok || valueField.Type.Kind() != reflect.Pointer {
+		return nil, false
+	}
+
+	return valueField.Type.Elem(), true
+}
+
+// ExportedTypeName returns an exported name for a generated copy of a type,
+// like `JobGetRequest` for `jobGetRequest`. Instantiated generic types like
+// `ListResponse[pkg.Job]` become `ListResponseJob`.
+func ExportedTypeName(typ reflect.Type) string {
+	name, typeArgs, _ := strings.Cut(typ.Name(), "[")
+
+	for typeArg := range strings.SplitSeq(strings.TrimSuffix(typeArgs, "]"), ",") {
+		if lastDot := strings.LastIndexAny(typeArg, "./"); lastDot >= 0 {
+			typeArg = typeArg[lastDot+1:]
+		}
+		name += UpperFirst(strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, typeArg))
+	}
+
+	return UpperFirst(name)
+}
+
+// FormFields returns the fields of a struct type that are bound from form
+// fields of a request body with a `form` tag, in order, with fields of
+// embedded structs promoted. A field's Name is its form field name.
+func FormFields(typ reflect.Type) []*Field {
+	var fields []*Field
+	appendFormFields(&fields, typ)
+	return fields
+}
+
+// IsBound returns true if the given struct field carries one of BindingTags.
+func IsBound(field reflect.StructField) bool {
+	for _, tag := range BindingTags {
+		if name := field.Tag.Get(tag); name != "" && name != "-" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JSONFields returns the fields of a struct type that are marshaled to and
+// unmarshaled from JSON, in order, with fields of embedded structs promoted
+// like encoding/json does. Fields bound from other parts of a request (see
+// BindingTags) are excluded.
+func JSONFields(typ reflect.Type) []*Field {
+	var (
+		fields []*Field
+		seen   = make(map[string]struct{})
+	)
+
+	appendJSONFields(&fields, seen, typ)
+
+	return fields
+}
+
+// LookupJSONField finds the field that a JSON object key is unmarshaled into,
+// preferring an exact match, but falling back to a case-insensitive one like
+// encoding/json does. Returns nil if there's no such field.
+func LookupJSONField(fields []*Field, key string) *Field {
+	for _, field := range fields {
+		if field.Name == key {
+			return field
+		}
+	}
+
+	for _, field := range fields {
+		if strings.EqualFold(field.Name, key) {
+			return field
+		}
+	}
+
+	return nil
+}
+
+func appendJSONFields(fields *[]*Field, seen map[string]struct{}, typ reflect.Type) {
+	// Embedded structs are processed after direct fields so that direct fields
+	// take precedence in case of a name conflict, which approximates
+	// encoding/json's rule that shallower fields win.
+	var embedded []reflect.Type
+
+	for i := range typ.NumField() {
+		structField := typ.Field(i)
+
+		jsonTag := structField.Tag.Get("json")
+		if jsonTag == "-" || IsBound(structField) {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(jsonTag, ",")
+
+		if structField.Anonymous && name == "" {
+			embeddedType := structField.Type
+			if embeddedType.Kind() == reflect.Pointer {
+				embeddedType = embeddedType.Elem()
+			}
+
+			if embeddedType.Kind() == reflect.Struct {
+				embedded = append(embedded, embeddedType)
+				continue
+			}
+		}
+
+		if !structField.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = structField.Name
+		}
+
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		*fields = append(*fields, &Field{
+			Name:        name,
+			OmitEmpty:   hasTagOption(opts, "omitempty") || hasTagOption(opts, "omitzero"),
+			StructField: structField,
+		})
+	}
+
+	for _, embeddedType := range embedded {
+		appendJSONFields(fields, seen, embeddedType)
+	}
+}
+
+func appendFormFields(fields *[]*Field, typ reflect.Type) {
+	for i := range typ.NumField() {
+		structField := typ.Field(i)
+
+		formTag, ok := structField.Tag.Lookup("form")
+		if !ok || formTag == "-" {
+			if structField.Anonymous && structField.Type.Kind() == reflect.Struct {
+				appendFormFields(fields, structField.Type)
+			}
+			continue
+		}
+
+		name, _, _ := strings.Cut(formTag, ",")
+		if name == "" || !structField.IsExported() {
+			continue
+		}
+
+		*fields = append(*fields, &Field{Name: name, StructField: structField})
+	}
+}
+
+func hasTagOption(opts, opt string) bool {
+	for candidate := range strings.SplitSeq(opts, ",") {
+		if candidate == opt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateRule is a single rule from a `validate` struct tag, like `max=100`.
+type ValidateRule struct {
+	// Param is the rule's parameter, like `100` in `max=100`. Empty for rules
+	// that don't take one.
+	Param string
+
+	// Tag is the rule's name, like `max` in `max=100`.
+	Tag string
+}
+
+// ValidateRules parses a `validate` struct tag into rules that apply to the
+// field itself and rules that apply to the field's elements (those after a
+// `dive`). Rules with alternatives (`|`) are skipped because they can't be
+// described as a single constraint.
+func ValidateRules(tag string) ([]*ValidateRule, []*ValidateRule) {
+	var (
+		elemRules  []*ValidateRule
+		fieldRules []*ValidateRule
+		rules      = &fieldRules
+	)
+
+	if tag == "" || tag == "-" {
+		return nil, nil
+	}
+
+	for rawRule := range strings.SplitSeq(tag, ",") {
+		if rawRule == "dive" {
+			rules = &elemRules
+			continue
+		}
+
+		if strings.Contains(rawRule, "|") {
+			continue
+		}
+
+		ruleTag, param, _ := strings.Cut(rawRule, "=")
+		*rules = append(*rules, &ValidateRule{Param: param, Tag: ruleTag})
+	}
+
+	return fieldRules, elemRules
+}
+
+// LowerFirst returns s with its first character lowercased.
+func LowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	runes := []rune(s)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// UniqueName returns name if it's not already in names, or name with a numeric
+// suffix if it is. The returned name is added to names.
+func UniqueName(names map[string]struct{}, name string) string {
+	uniqueName := name
+	for i := 2; ; i++ {
+		if _, ok := names[uniqueName]; !ok {
+			break
+		}
+		uniqueName = name + strconv.Itoa(i)
+	}
+
+	names[uniqueName] = struct{}{}
+	return uniqueName
+}
+
+// UpperFirst returns s with its first character uppercased.
+func UpperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	runes := []rune(s)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
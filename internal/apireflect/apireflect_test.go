@@ -0,0 +1,148 @@
+package apireflect
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apitype"
+)
+
+func TestExplicitNullableValueType(t *testing.T) {
+	t.Parallel()
+
+	valueType, ok := ExplicitNullableValueType(reflect.TypeFor[apitype.ExplicitNullable[int]]())
+	require.True(t, ok)
+	require.Equal(t, reflect.TypeFor[int](), valueType)
+
+	_, ok = ExplicitNullableValueType(reflect.TypeFor[struct{ Value *int }]())
+	require.False(t, ok)
+}
+
+func TestExportedTypeName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "JobRow", ExportedTypeName(reflect.TypeFor[jobRow]()))
+	require.Equal(t, "ListResponseJobRow", ExportedTypeName(reflect.TypeFor[listResponse[jobRow]]()))
+	require.Equal(t, "ListResponseString", ExportedTypeName(reflect.TypeFor[listResponse[string]]()))
+}
+
+func TestFormFields(t *testing.T) {
+	t.Parallel()
+
+	type Embedded struct {
+		Note string `form:"note"`
+	}
+
+	type testStruct struct {
+		Embedded
+
+		Avatar   string `form:"avatar,maxsize=1KB" json:"-"`
+		Name     string `form:"name"               json:"name"`
+		NoTag    string
+		Ignored  string `form:"-"`
+		internal string `form:"internal"`
+	}
+
+	fields := FormFields(reflect.TypeFor[testStruct]())
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	require.Equal(t, []string{"note", "avatar", "name"}, names)
+	require.Equal(t, "Avatar", fields[1].StructField.Name)
+}
+
+func TestJSONFields(t *testing.T) {
+	t.Parallel()
+
+	type Embedded struct {
+		Name   string `json:"name"`
+		Shadow string `json:"shadow"`
+	}
+
+	type testStruct struct {
+		Embedded
+
+		ID       int64  `json:"id"`
+		Label    string `json:"label,omitempty"`
+		NoTag    string
+		Shadow   string `json:"shadow"`
+		Ignored  string `json:"-"`
+		Queried  string `query:"queried"`
+		internal string
+	}
+
+	fields := JSONFields(reflect.TypeFor[testStruct]())
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	require.Equal(t, []string{"id", "label", "NoTag", "shadow", "name"}, names)
+
+	require.False(t, fields[0].OmitEmpty)
+	require.True(t, fields[1].OmitEmpty)
+	require.Equal(t, "Shadow", fields[3].StructField.Name)
+}
+
+func TestLookupJSONField(t *testing.T) {
+	t.Parallel()
+
+	type testStruct struct {
+		Name      string `json:"name"`
+		NameUpper string `json:"NAME"`
+		Title     string `json:"title"`
+	}
+
+	fields := JSONFields(reflect.TypeFor[testStruct]())
+
+	require.Equal(t, "Name", LookupJSONField(fields, "name").StructField.Name)
+	require.Equal(t, "NameUpper", LookupJSONField(fields, "NAME").StructField.Name)
+	require.Equal(t, "Title", LookupJSONField(fields, "Title").StructField.Name)
+	require.Nil(t, LookupJSONField(fields, "other"))
+}
+
+func TestLowerFirst(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "jobGet", LowerFirst("JobGet"))
+	require.Equal(t, "émile", LowerFirst("Émile"))
+	require.Empty(t, LowerFirst(""))
+}
+
+func TestUniqueName(t *testing.T) {
+	t.Parallel()
+
+	names := make(map[string]struct{})
+	require.Equal(t, "jobGet", UniqueName(names, "jobGet"))
+	require.Equal(t, "jobGet2", UniqueName(names, "jobGet"))
+	require.Equal(t, "jobGet3", UniqueName(names, "jobGet"))
+	require.Equal(t, "jobList", UniqueName(names, "jobList"))
+}
+
+func TestUpperFirst(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "JobGet", UpperFirst("jobGet"))
+	require.Equal(t, "Émile", UpperFirst("émile"))
+	require.Empty(t, UpperFirst(""))
+}
+
+func TestValidateRules(t *testing.T) {
+	t.Parallel()
+
+	fieldRules, elemRules := ValidateRules("required,max=10,dive,oneof=a b,email|url")
+	require.Equal(t, []*ValidateRule{{Tag: "required"}, {Tag: "max", Param: "10"}}, fieldRules)
+	require.Equal(t, []*ValidateRule{{Tag: "oneof", Param: "a b"}}, elemRules)
+
+	fieldRules, elemRules = ValidateRules("-")
+	require.Nil(t, fieldRules)
+	require.Nil(t, elemRules)
+}
+
+type jobRow struct{}
+
+type listResponse[T any] struct{}
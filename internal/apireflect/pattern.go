@@ -0,0 +1,70 @@
+package apireflect
+
+import "strings"
+
+// PathSegment is a piece of an http.ServeMux pattern path, either literal
+// text or a wildcard.
+type PathSegment struct {
+	// Literal is the segment's text if it's not a wildcard.
+	Literal string
+
+	// MultiSegment is true for a wildcard like `{path...}` that matches the
+	// remainder of a path, including slashes.
+	MultiSegment bool
+
+	// Wildcard is the name of the segment's wildcard, like `id` for `{id}` or
+	// `path` for `{path...}`. Empty for literal text.
+	Wildcard string
+}
+
+// PathSegments splits an http.ServeMux pattern path like `/jobs/{id}` into
+// literal text and wildcards. The special `{$}` wildcard only affects
+// matching, so it's removed.
+func PathSegments(path string) []*PathSegment {
+	path = strings.ReplaceAll(path, "{$}", "")
+
+	var segments []*PathSegment
+	for {
+		before, after, ok := strings.Cut(path, "{")
+		if !ok {
+			break
+		}
+
+		wildcard, rest, ok := strings.Cut(after, "}")
+		if !ok {
+			break
+		}
+
+		if before != "" {
+			segments = append(segments, &PathSegment{Literal: before})
+		}
+
+		name, multiSegment := strings.CutSuffix(wildcard, "...")
+		segments = append(segments, &PathSegment{MultiSegment: multiSegment, Wildcard: name})
+		path = rest
+	}
+
+	if path != "" {
+		segments = append(segments, &PathSegment{Literal: path})
+	}
+
+	return segments
+}
+
+// SplitPattern splits an http.ServeMux pattern like `GET /jobs/{id}` into its
+// method, which is empty for a pattern that applies to every method, and its
+// path. Any host in the pattern is removed.
+func SplitPattern(pattern string) (string, string) {
+	var method string
+	if patternMethod, path, ok := strings.Cut(pattern, " "); ok {
+		method = patternMethod
+		pattern = strings.TrimSpace(path)
+	}
+
+	// Remove any host.
+	if slashIndex := strings.Index(pattern, "/"); slashIndex > 0 {
+		pattern = pattern[slashIndex:]
+	}
+
+	return method, pattern
+}
@@ -0,0 +1,38 @@
+package apireflect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathSegments(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []*PathSegment{
+		{Literal: "/api/jobs/"},
+		{Wildcard: "id"},
+		{Literal: "/files/"},
+		{MultiSegment: true, Wildcard: "path"},
+	}, PathSegments("/api/jobs/{id}/files/{path...}"))
+
+	require.Equal(t, []*PathSegment{{Literal: "/api/"}}, PathSegments("/api/{$}"))
+	require.Equal(t, []*PathSegment{{Wildcard: "id"}}, PathSegments("{id}"))
+	require.Empty(t, PathSegments(""))
+}
+
+func TestSplitPattern(t *testing.T) {
+	t.Parallel()
+
+	method, path := SplitPattern("GET /api/jobs/{id}")
+	require.Equal(t, "GET", method)
+	require.Equal(t, "/api/jobs/{id}", path)
+
+	method, path = SplitPattern("POST  example.com/api/files/{path...}")
+	require.Equal(t, "POST", method)
+	require.Equal(t, "/api/files/{path...}", path)
+
+	method, path = SplitPattern("/api/{$}")
+	require.Empty(t, method)
+	require.Equal(t, "/api/{$}", path)
+}
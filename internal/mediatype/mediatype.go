@@ -0,0 +1,111 @@
+// Package mediatype parses Accept headers and negotiates media types, which is
+// shared by the negotiation of response and error formats.
+package mediatype
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Range is a media range from an Accept header, like `application/json` or
+// `text/*`, along with its quality value.
+type Range struct {
+	// MediaType is the media range's type and subtype in lowercase, either of
+	// which may be a `*` wildcard.
+	MediaType string
+
+	// Q is the range's quality value between 0 and 1, which is 1 if it didn't
+	// specify one.
+	Q float64
+}
+
+// ParseAccept parses the media ranges of an Accept header. Malformed ranges
+// are skipped.
+func ParseAccept(accept string) []*Range {
+	var ranges []*Range
+
+	for mediaRange := range strings.SplitSeq(accept, ",") {
+		if strings.TrimSpace(mediaRange) == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			continue
+		}
+
+		q := 1.0
+		if qParam, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qParam, 64); err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, &Range{MediaType: mediaType, Q: q})
+	}
+
+	return ranges
+}
+
+// Negotiate picks the media type from offers that's most preferred by an
+// Accept header, with ties broken by the order of offers. Each offer's quality
+// comes from the most specific range that matches it, so `application/json`
+// takes precedence over `application/*`, which takes precedence over `*/*`.
+// An empty Accept header accepts anything, so the first offer is returned.
+// Returns false if no offer is acceptable.
+func Negotiate(accept string, offers []string) (string, bool) {
+	if len(offers) < 1 {
+		return "", false
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+
+	var (
+		ranges    = ParseAccept(accept)
+		bestOffer string
+		bestQ     float64
+	)
+
+	for _, offer := range offers {
+		if q := quality(ranges, strings.ToLower(offer)); q > bestQ {
+			bestOffer, bestQ = offer, q
+		}
+	}
+
+	return bestOffer, bestQ > 0
+}
+
+// quality returns the quality value of a media type according to the most
+// specific of the ranges that matches it, or 0 if none does.
+func quality(ranges []*Range, mediaType string) float64 {
+	var (
+		bestSpecificity = -1
+		q               float64
+	)
+
+	typ, _, _ := strings.Cut(mediaType, "/")
+
+	for _, mediaRange := range ranges {
+		var specificity int
+
+		switch {
+		case mediaRange.MediaType == mediaType:
+			specificity = 2
+		case mediaRange.MediaType == typ+"/*":
+			specificity = 1
+		case mediaRange.MediaType == "*/*":
+			specificity = 0
+		default:
+			continue
+		}
+
+		if specificity > bestSpecificity {
+			bestSpecificity, q = specificity, mediaRange.Q
+		}
+	}
+
+	return q
+}
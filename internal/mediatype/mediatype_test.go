@@ -0,0 +1,78 @@
+package mediatype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	t.Parallel()
+
+	negotiate := func(accept string, offers ...string) string {
+		mediaType, ok := Negotiate(accept, offers)
+		if !ok {
+			return "<none>"
+		}
+		return mediaType
+	}
+
+	t.Run("EmptyAccept", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, "application/json", negotiate("", "application/json", "text/csv"))
+		require.Equal(t, "<none>", negotiate(""))
+	})
+
+	t.Run("Exact", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, "text/csv", negotiate("text/csv", "application/json", "text/csv"))
+		require.Equal(t, "text/csv", negotiate("Text/CSV", "application/json", "text/csv"))
+		require.Equal(t, "<none>", negotiate("application/xml", "application/json", "text/csv"))
+	})
+
+	t.Run("QualityValues", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, "text/csv", negotiate("application/json;q=0.5, text/csv", "application/json", "text/csv"))
+		require.Equal(t, "application/json", negotiate("application/json, text/csv", "application/json", "text/csv"))
+		require.Equal(t, "text/csv", negotiate("application/json, text/csv", "text/csv", "application/json"))
+		require.Equal(t, "<none>", negotiate("application/json;q=0", "application/json"))
+	})
+
+	t.Run("Wildcards", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, "application/json", negotiate("*/*", "application/json", "text/csv"))
+		require.Equal(t, "text/csv", negotiate("text/*", "application/json", "text/csv"))
+		require.Equal(t, "text/csv", negotiate("application/*;q=0.5, text/*", "application/json", "text/csv"))
+	})
+
+	t.Run("MostSpecificRangeWins", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, "text/csv", negotiate("*/*, application/json;q=0", "application/json", "text/csv"))
+		require.Equal(t, "application/json", negotiate("application/json, application/*;q=0", "application/json", "application/msgpack"))
+	})
+
+	t.Run("MalformedRangesSkipped", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, "text/csv", negotiate("not a media type, text/csv", "application/json", "text/csv"))
+		require.Equal(t, "text/csv", negotiate("application/json;q=high, text/csv", "application/json", "text/csv"))
+		require.Equal(t, "<none>", negotiate("not a media type", "application/json"))
+	})
+}
+
+func TestParseAccept(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []*Range{
+		{MediaType: "application/json", Q: 1},
+		{MediaType: "text/*", Q: 0.5},
+		{MediaType: "*/*", Q: 0.1},
+	}, ParseAccept("Application/JSON, text/*;q=0.5,, */*;q=0.1, invalid"))
+
+	require.Nil(t, ParseAccept(""))
+}
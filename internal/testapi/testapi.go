@@ -0,0 +1,146 @@
+// Package testapi contains endpoints used to test client generation.
+package testapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/riverqueue/apiframe/apiendpoint"
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/apitype"
+)
+
+// MountEndpoints mounts every test endpoint.
+func MountEndpoints(mux *http.ServeMux, opts *apiendpoint.MountOpts) {
+	apiendpoint.Mount(mux, &jobCreateEndpoint{}, opts)
+	apiendpoint.Mount(mux, &jobDeleteEndpoint{}, opts)
+	apiendpoint.Mount(mux, &jobGetEndpoint{}, opts)
+}
+
+type job struct {
+	ID          int64          `json:"id"`
+	Args        map[string]any `json:"args"`
+	Attempts    []jobAttempt   `json:"attempts"`
+	Label       *string        `json:"label,omitempty"`
+	Queue       string         `json:"queue"`
+	ScheduledAt time.Time      `json:"scheduled_at"`
+	State       jobState       `json:"state"`
+}
+
+type jobAttempt struct {
+	Error string `json:"error"`
+	Num   int    `json:"num"`
+}
+
+type jobState string
+
+//
+// jobCreateEndpoint
+//
+
+type jobCreateEndpoint struct {
+	apiendpoint.Endpoint[jobCreateRequest, job]
+}
+
+func (*jobCreateEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:    "POST /api/jobs",
+		StatusCode: http.StatusCreated,
+	}
+}
+
+type jobCreateRequest struct {
+	IdempotencyKey string                           `header:"Idempotency-Key" json:"-"`
+	Args           map[string]any                   `json:"args"`
+	Label          apitype.ExplicitNullable[string] `json:"label"`
+	Queue          string                           `json:"queue"        validate:"required"`
+	ScheduledAt    time.Time                        `json:"scheduled_at"`
+}
+
+func (*jobCreateEndpoint) Execute(_ context.Context, req *jobCreateRequest) (*job, error) {
+	label := "<unset>"
+	switch {
+	case req.Label.Set && req.Label.Value == nil:
+		label = "<null>"
+	case req.Label.Set:
+		label = *req.Label.Value
+	}
+
+	return &job{
+		ID:          123,
+		Args:        req.Args,
+		Label:       &label,
+		Queue:       req.Queue + ":" + req.IdempotencyKey,
+		ScheduledAt: req.ScheduledAt,
+		State:       "available",
+	}, nil
+}
+
+//
+// jobDeleteEndpoint
+//
+
+type jobDeleteEndpoint struct {
+	apiendpoint.Endpoint[jobDeleteRequest, jobDeleteResponse]
+}
+
+func (*jobDeleteEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:    "DELETE /api/jobs/{id}",
+		StatusCode: http.StatusOK,
+	}
+}
+
+type jobDeleteRequest struct {
+	ID int64 `json:"-" path:"id"`
+}
+
+type jobDeleteResponse struct {
+	Deleted bool `json:"deleted"`
+}
+
+func (*jobDeleteEndpoint) Execute(_ context.Context, req *jobDeleteRequest) (*jobDeleteResponse, error) {
+	if req.ID == 404 {
+		return nil, apierror.NewNotFoundf("Job not found: %d.", req.ID)
+	}
+
+	return &jobDeleteResponse{Deleted: true}, nil
+}
+
+//
+// jobGetEndpoint
+//
+
+type jobGetEndpoint struct {
+	apiendpoint.Endpoint[jobGetRequest, job]
+}
+
+func (*jobGetEndpoint) Meta() *apiendpoint.EndpointMeta {
+	return &apiendpoint.EndpointMeta{
+		Pattern:    "GET /api/queues/{queue}/jobs/{id}",
+		StatusCode: http.StatusOK,
+	}
+}
+
+type jobGetRequest struct {
+	ID      int64         `json:"-" path:"id"`
+	Queue   string        `json:"-" path:"queue"`
+	Attempt []int         `json:"-" query:"attempt"`
+	Session string        `cookie:"session" json:"-"`
+	Wait    time.Duration `json:"-" query:"wait"`
+}
+
+func (*jobGetEndpoint) Execute(_ context.Context, req *jobGetRequest) (*job, error) {
+	attempts := make([]jobAttempt, len(req.Attempt))
+	for i, num := range req.Attempt {
+		attempts[i] = jobAttempt{Error: req.Session + ":" + req.Wait.String(), Num: num}
+	}
+
+	return &job{
+		ID:       req.ID,
+		Attempts: attempts,
+		Queue:    req.Queue,
+		State:    "running",
+	}, nil
+}
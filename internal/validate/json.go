@@ -0,0 +1,216 @@
+package validate
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/riverqueue/apiframe/apierror"
+	"github.com/riverqueue/apiframe/internal/apireflect"
+)
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]() //nolint:gochecknoglobals
+
+// PublicFacingJSONError builds a bad request API error from an error returned
+// while unmarshaling a JSON request body into a value of type typ, that's
+// suitable for public-facing consumption. Unlike encoding/json's errors, its
+// message doesn't mention Go types, and it describes fields by their JSON
+// path like `items[3].name`. A type mismatch is also included as a field
+// error.
+func PublicFacingJSONError(unmarshalErr error, typ reflect.Type) *apierror.BadRequest {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.Is(unmarshalErr, io.ErrUnexpectedEOF),
+		errors.As(unmarshalErr, &syntaxErr) && syntaxErr.Error() == "unexpected end of JSON input":
+		return apierror.WithCode(apierror.NewBadRequest("Request body contains incomplete JSON."), apierror.CodeInvalidBody)
+
+	case errors.As(unmarshalErr, &syntaxErr):
+		return apierror.WithCode(apierror.NewBadRequestf("Request body contains invalid JSON at byte %d: %s.", syntaxErr.Offset, syntaxErr), apierror.CodeInvalidBody)
+
+	case errors.As(unmarshalErr, &typeErr):
+		path := jsonTypeErrorPath(typeErr.Field, typ)
+		if path == "" {
+			return apierror.WithCode(apierror.NewBadRequestf("Request body must be %s, but got %s.", jsonTypeName(typeErr.Type), jsonValueName(typeErr.Value)), apierror.CodeInvalidBody)
+		}
+
+		message := jsonTypeErrorMessage(typeErr, path)
+
+		name := path
+		if lastDot := strings.LastIndexAny(name, ".["); lastDot >= 0 {
+			name = strings.TrimSuffix(name[lastDot+1:], "]")
+		}
+
+		apiErr := apierror.WithCode(apierror.NewBadRequest(message), apierror.CodeInvalidBody)
+		apiErr.Errors = []*apierror.FieldError{
+			{Field: name, JSONPath: path, Message: message, Tag: "type"},
+		}
+		return apiErr
+	}
+
+	return apierror.WithCode(apierror.NewBadRequestf("Error unmarshaling request body: %s.", unmarshalErr), apierror.CodeInvalidBody)
+}
+
+// jsonTypeErrorMessage builds a message for a JSON value of the wrong type at
+// the given path, like "Field `name` must be a string, but got a number."
+func jsonTypeErrorMessage(typeErr *json.UnmarshalTypeError, path string) string {
+	// A number that's valid JSON but doesn't fit into an integer is reported
+	// with its value, like `number 300` for a uint8.
+	if number, ok := strings.CutPrefix(typeErr.Value, "number "); ok && isIntegerKind(typeErr.Type.Kind()) {
+		if strings.ContainsAny(number, ".eE") {
+			return fmt.Sprintf("Field `%s` must be an integer.", path)
+		}
+
+		minValue, maxValue := integerRange(typeErr.Type)
+		return fmt.Sprintf("Field `%s` must be an integer between %s and %s.", path, minValue, maxValue)
+	}
+
+	return fmt.Sprintf("Field `%s` must be %s, but got %s.", path, jsonTypeName(typeErr.Type), jsonValueName(typeErr.Value))
+}
+
+// jsonTypeErrorPath converts the field path of a json.UnmarshalTypeError like
+// `items.3.name` into a JSON path like `items[3].name` by following it
+// through typ to find which segments are array indexes.
+func jsonTypeErrorPath(field string, typ reflect.Type) string {
+	if field == "" {
+		return ""
+	}
+
+	var path strings.Builder
+
+	for i, segment := range strings.Split(field, ".") {
+		for typ != nil {
+			if valueType, ok := apireflect.ExplicitNullableValueType(typ); ok {
+				typ = valueType
+				continue
+			}
+
+			if typ.Kind() == reflect.Pointer {
+				typ = typ.Elem()
+				continue
+			}
+
+			break
+		}
+
+		var isIndex bool
+
+		if typ != nil {
+			switch typ.Kind() { //nolint:exhaustive
+			case reflect.Array, reflect.Slice:
+				isIndex = true
+				typ = typ.Elem()
+
+			case reflect.Map:
+				typ = typ.Elem()
+
+			case reflect.Struct:
+				if field := apireflect.LookupJSONField(apireflect.JSONFields(typ), segment); field != nil {
+					typ = field.StructField.Type
+				} else {
+					typ = nil
+				}
+
+			default:
+				typ = nil
+			}
+		}
+
+		switch {
+		case isIndex:
+			path.WriteString("[" + segment + "]")
+		case i > 0:
+			path.WriteString("." + segment)
+		default:
+			path.WriteString(segment)
+		}
+	}
+
+	return path.String()
+}
+
+// jsonTypeName describes the JSON type that a Go type is unmarshaled from,
+// like "a string" for a string or "an object" for a struct.
+func jsonTypeName(typ reflect.Type) string {
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return "a string"
+	}
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Bool:
+		return "a boolean"
+
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+
+	case reflect.Map, reflect.Struct:
+		return "an object"
+
+	case reflect.Pointer:
+		return jsonTypeName(typ.Elem())
+
+	case reflect.Array, reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "a base64-encoded string"
+		}
+		return "an array"
+
+	case reflect.String:
+		return "a string"
+	}
+
+	if isIntegerKind(typ.Kind()) {
+		return "an integer"
+	}
+
+	return "a different type"
+}
+
+// jsonValueName describes the kind of JSON value reported in a
+// json.UnmarshalTypeError's Value, like "a string" for `string`.
+func jsonValueName(value string) string {
+	switch {
+	case value == "array":
+		return "an array"
+	case value == "bool":
+		return "a boolean"
+	case value == "number", strings.HasPrefix(value, "number "):
+		return "a number"
+	case value == "object":
+		return "an object"
+	case value == "string":
+		return "a string"
+	}
+
+	return value
+}
+
+// integerRange returns the smallest and largest values of an integer type.
+func integerRange(typ reflect.Type) (string, string) {
+	bits := typ.Bits()
+
+	switch typ.Kind() { //nolint:exhaustive
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "0", strconv.FormatUint(uint64(1)<<bits-1, 10)
+	}
+
+	return strconv.FormatInt(int64(-1)<<(bits-1), 10), strconv.FormatInt(int64(1)<<(bits-1)-1, 10)
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+
+	return false
+}
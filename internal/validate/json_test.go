@@ -0,0 +1,127 @@
+package validate
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
+)
+
+func TestPublicFacingJSONError(t *testing.T) {
+	t.Parallel()
+
+	type item struct {
+		Count uint8  `json:"count"`
+		Name  string `json:"name"`
+	}
+
+	type request struct {
+		Attrs   map[string]int `json:"attrs"`
+		Enabled bool           `json:"enabled"`
+		Items   []*item        `json:"items"`
+		Offset  int64          `json:"offset"`
+		Payload []byte         `json:"payload"`
+		Time    time.Time      `json:"time"`
+	}
+
+	unmarshal := func(t *testing.T, data string) *apierror.BadRequest {
+		t.Helper()
+
+		var req request
+		err := json.Unmarshal([]byte(data), &req)
+		require.Error(t, err)
+
+		return PublicFacingJSONError(err, reflect.TypeFor[request]())
+	}
+
+	requireMessage := func(t *testing.T, expectedMessage string, apiErr *apierror.BadRequest) {
+		t.Helper()
+
+		require.Equal(t, expectedMessage, apiErr.Message)
+		require.Equal(t, apierror.CodeInvalidBody, apiErr.Code)
+	}
+
+	t.Run("SyntaxError", func(t *testing.T) {
+		t.Parallel()
+
+		apiErr := unmarshal(t, `{"items":]}`)
+		requireMessage(t, "Request body contains invalid JSON at byte 10: invalid character ']' looking for beginning of value.", apiErr)
+		require.Empty(t, apiErr.Errors)
+	})
+
+	t.Run("UnexpectedEnd", func(t *testing.T) {
+		t.Parallel()
+
+		requireMessage(t, "Request body contains incomplete JSON.", unmarshal(t, `{"items":`))
+	})
+
+	t.Run("UnexpectedEOF", func(t *testing.T) {
+		t.Parallel()
+
+		var req request
+		err := json.NewDecoder(strings.NewReader(`{"items":`)).Decode(&req)
+		requireMessage(t, "Request body contains incomplete JSON.", PublicFacingJSONError(err, reflect.TypeFor[request]()))
+	})
+
+	t.Run("TypeMismatch", func(t *testing.T) {
+		t.Parallel()
+
+		apiErr := unmarshal(t, `{"items":[{"name":"a"},{"name":1}]}`)
+		requireMessage(t, "Field `items[1].name` must be a string, but got a number.", apiErr)
+		require.Equal(t, []*apierror.FieldError{
+			{Field: "name", JSONPath: "items[1].name", Message: "Field `items[1].name` must be a string, but got a number.", Tag: "type"},
+		}, apiErr.Errors)
+	})
+
+	t.Run("TypeNames", func(t *testing.T) {
+		t.Parallel()
+
+		requireMessage(t, "Field `enabled` must be a boolean, but got a string.", unmarshal(t, `{"enabled":"yes"}`))
+		requireMessage(t, "Field `items` must be an array, but got an object.", unmarshal(t, `{"items":{}}`))
+		requireMessage(t, "Field `items[0]` must be an object, but got an array.", unmarshal(t, `{"items":[[]]}`))
+		requireMessage(t, "Field `offset` must be an integer, but got a boolean.", unmarshal(t, `{"offset":true}`))
+		requireMessage(t, "Field `payload` must be a base64-encoded string, but got a number.", unmarshal(t, `{"payload":1}`))
+		requireMessage(t, "Field `time` must be a string, but got a number.", unmarshal(t, `{"time":1}`))
+	})
+
+	t.Run("MapKeysNotIndexes", func(t *testing.T) {
+		t.Parallel()
+
+		apiErr := unmarshal(t, `{"attrs":{"0":"zero"}}`)
+		requireMessage(t, "Field `attrs.0` must be an integer, but got a string.", apiErr)
+		require.Equal(t, "0", apiErr.Errors[0].Field)
+	})
+
+	t.Run("IntegerOutOfRange", func(t *testing.T) {
+		t.Parallel()
+
+		requireMessage(t, "Field `items[0].count` must be an integer between 0 and 255.", unmarshal(t, `{"items":[{"count":300}]}`))
+		requireMessage(t, "Field `items[0].count` must be an integer between 0 and 255.", unmarshal(t, `{"items":[{"count":-1}]}`))
+		requireMessage(t, "Field `offset` must be an integer between -9223372036854775808 and 9223372036854775807.", unmarshal(t, `{"offset":9223372036854775808}`))
+	})
+
+	t.Run("IntegerWithFraction", func(t *testing.T) {
+		t.Parallel()
+
+		requireMessage(t, "Field `offset` must be an integer.", unmarshal(t, `{"offset":1.5}`))
+	})
+
+	t.Run("RootTypeMismatch", func(t *testing.T) {
+		t.Parallel()
+
+		apiErr := unmarshal(t, `[1]`)
+		requireMessage(t, "Request body must be an object, but got an array.", apiErr)
+		require.Empty(t, apiErr.Errors)
+	})
+
+	t.Run("OtherError", func(t *testing.T) {
+		t.Parallel()
+
+		requireMessage(t, `Error unmarshaling request body: parsing time "nope" as "2006-01-02T15:04:05Z07:00": cannot parse "nope" as "2006".`, unmarshal(t, `{"time":"nope"}`))
+	})
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+
+	"github.com/riverqueue/apiframe/apierror"
 )
 
 // Default is the package's default validator instance. WithRequiredStructEnabled
@@ -19,82 +21,180 @@ func init() { //nolint:gochecknoinits
 	Default.RegisterTagNameFunc(preferPublicName)
 }
 
+// PublicFacingError builds a bad request API error from a validator error
+// that's suitable for public-facing consumption. Its message describes every
+// failure, and each failure is also included as a field error so that
+// clients can point out which inputs were invalid.
+func PublicFacingError(v *validator.Validate, validatorErr error) *apierror.BadRequest {
+	apiErr := apierror.WithCode(apierror.NewBadRequest(PublicFacingMessage(v, validatorErr)), apierror.CodeValidationFailed)
+	apiErr.Errors = FieldErrors(validatorErr)
+	return apiErr
+}
+
 // PublicFacingMessage builds a complete error message from a validator error
 // that's suitable for public-facing consumption.
 //
 // I only added a few possible validations to start. We'll probably need to add
 // more as we go and expand our usage.
 func PublicFacingMessage(v *validator.Validate, validatorErr error) string {
-	var message string
+	var messages []string
 
 	//nolint:errorlint
 	if validationErrs, ok := validatorErr.(validator.ValidationErrors); ok {
 		for _, fieldErr := range validationErrs {
-			switch fieldErr.Tag() {
-			case "lte":
-				fallthrough // lte and max are synonyms
-			case "max":
-				kind := fieldErr.Kind()
-				if kind == reflect.Ptr {
-					kind = fieldErr.Type().Elem().Kind()
-				}
-
-				switch kind { //nolint:exhaustive
-				case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int32, reflect.Int64:
-					message += fmt.Sprintf(" Field `%s` must be less than or equal to %s.",
-						fieldErr.Field(), fieldErr.Param())
-
-				case reflect.Slice, reflect.Map:
-					message += fmt.Sprintf(" Field `%s` must contain at most %s element(s).",
-						fieldErr.Field(), fieldErr.Param())
-
-				case reflect.String:
-					message += fmt.Sprintf(" Field `%s` must be at most %s character(s) long.",
-						fieldErr.Field(), fieldErr.Param())
-
-				default:
-					message += fieldErr.Error()
-				}
-
-			case "gte":
-				fallthrough // gte and min are synonyms
-			case "min":
-				kind := fieldErr.Kind()
-				if kind == reflect.Ptr {
-					kind = fieldErr.Type().Elem().Kind()
-				}
-
-				switch kind { //nolint:exhaustive
-				case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int32, reflect.Int64:
-					message += fmt.Sprintf(" Field `%s` must be greater or equal to %s.",
-						fieldErr.Field(), fieldErr.Param())
-
-				case reflect.Slice, reflect.Map:
-					message += fmt.Sprintf(" Field `%s` must contain at least %s element(s).",
-						fieldErr.Field(), fieldErr.Param())
-
-				case reflect.String:
-					message += fmt.Sprintf(" Field `%s` must be at least %s character(s) long.",
-						fieldErr.Field(), fieldErr.Param())
-
-				default:
-					message += fieldErr.Error()
-				}
-
-			case "oneof":
-				message += fmt.Sprintf(" Field `%s` should be one of the following values: %s.",
-					fieldErr.Field(), fieldErr.Param())
-
-			case "required":
-				message += fmt.Sprintf(" Field `%s` is required.", fieldErr.Field())
-
-			default:
-				message += fmt.Sprintf(" Validation on field `%s` failed on the `%s` tag.", fieldErr.Field(), fieldErr.Tag())
-			}
+			messages = append(messages, fieldMessage(fieldErr))
+		}
+	}
+
+	return strings.Join(messages, " ")
+}
+
+// FieldErrors converts a validator error into a field error for each failure.
+func FieldErrors(validatorErr error) []*apierror.FieldError {
+	var fieldErrs []*apierror.FieldError
+
+	//nolint:errorlint
+	if validationErrs, ok := validatorErr.(validator.ValidationErrors); ok {
+		for _, fieldErr := range validationErrs {
+			_, name, _ := fieldKindAndName(fieldErr)
+
+			fieldErrs = append(fieldErrs, &apierror.FieldError{
+				Field:    name,
+				JSONPath: jsonPath(fieldErr),
+				Message:  fieldMessage(fieldErr),
+				Param:    fieldErr.Param(),
+				Tag:      fieldErr.Tag(),
+			})
+		}
+	}
+
+	return fieldErrs
+}
+
+// fieldMessage builds a public-facing message for a single validation failure.
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "lte":
+		fallthrough // lte and max are synonyms
+	case "max":
+		kind := fieldErr.Kind()
+		if kind == reflect.Ptr {
+			kind = fieldErr.Type().Elem().Kind()
+		}
+
+		switch kind { //nolint:exhaustive
+		case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int32, reflect.Int64:
+			return fmt.Sprintf("%s must be less than or equal to %s.",
+				describeField(fieldErr, true), fieldErr.Param())
+
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("%s must contain at most %s element(s).",
+				describeField(fieldErr, true), fieldErr.Param())
+
+		case reflect.String:
+			return fmt.Sprintf("%s must be at most %s character(s) long.",
+				describeField(fieldErr, true), fieldErr.Param())
+
+		default:
+			return fieldErr.Error()
 		}
+
+	case "gte":
+		fallthrough // gte and min are synonyms
+	case "min":
+		kind := fieldErr.Kind()
+		if kind == reflect.Ptr {
+			kind = fieldErr.Type().Elem().Kind()
+		}
+
+		switch kind { //nolint:exhaustive
+		case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int32, reflect.Int64:
+			return fmt.Sprintf("%s must be greater or equal to %s.",
+				describeField(fieldErr, true), fieldErr.Param())
+
+		case reflect.Slice, reflect.Map:
+			return fmt.Sprintf("%s must contain at least %s element(s).",
+				describeField(fieldErr, true), fieldErr.Param())
+
+		case reflect.String:
+			return fmt.Sprintf("%s must be at least %s character(s) long.",
+				describeField(fieldErr, true), fieldErr.Param())
+
+		default:
+			return fieldErr.Error()
+		}
+
+	case "oneof":
+		return fmt.Sprintf("%s should be one of the following values: %s.",
+			describeField(fieldErr, true), fieldErr.Param())
+
+	case "required":
+		return fmt.Sprintf("%s is required.", describeField(fieldErr, true))
+	}
+
+	return fmt.Sprintf("Validation on %s failed on the `%s` tag.", describeField(fieldErr, false), fieldErr.Tag())
+}
+
+// Prefixes added to the names of fields bound from outside the request body
+// by preferPublicName so that messages can describe headers and cookies as
+// such instead of as fields, and so that they don't get a JSON path.
+const (
+	cookieNamePrefix = "cookie:"
+	headerNamePrefix = "header:"
+	pathNamePrefix   = "path:"
+	queryNamePrefix  = "query:"
+)
+
+// describeField describes the field of a validation error for use in a message,
+// like "Field `name`", or "Header `X-Name`" for a field bound from a header.
+// The description is capitalized if it's going to start a sentence.
+func describeField(fieldErr validator.FieldError, capitalize bool) string {
+	kind, name, _ := fieldKindAndName(fieldErr)
+
+	if capitalize {
+		kind = strings.ToUpper(kind[:1]) + kind[1:]
+	}
+
+	return fmt.Sprintf("%s `%s`", kind, name)
+}
+
+// fieldKindAndName returns the kind of a validation error's field (`field`,
+// `cookie`, or `header`), its public name, and whether it's bound from the
+// request body.
+func fieldKindAndName(fieldErr validator.FieldError) (string, string, bool) {
+	name := fieldErr.Field()
+
+	if cookie, ok := strings.CutPrefix(name, cookieNamePrefix); ok {
+		return "cookie", cookie, false
+	}
+	if header, ok := strings.CutPrefix(name, headerNamePrefix); ok {
+		return "header", header, false
+	}
+	if path, ok := strings.CutPrefix(name, pathNamePrefix); ok {
+		return "field", path, false
+	}
+	if query, ok := strings.CutPrefix(name, queryNamePrefix); ok {
+		return "field", query, false
+	}
+
+	return "field", name, true
+}
+
+// jsonPath returns the full path to a validation error's field using public
+// names, like `items[3].name`. The validator's namespace includes the name of
+// the top-level struct, which isn't part of the path. Fields bound from
+// outside the request body have no JSON path, so it's empty for them.
+func jsonPath(fieldErr validator.FieldError) string {
+	if _, _, inBody := fieldKindAndName(fieldErr); !inBody {
+		return ""
+	}
+
+	_, path, ok := strings.Cut(fieldErr.Namespace(), ".")
+	if !ok {
+		path = fieldErr.Field()
 	}
 
-	return strings.TrimSpace(message)
+	return path
 }
 
 // preferPublicName is a validator tag naming function that uses public names
@@ -107,5 +207,30 @@ func preferPublicName(fld reflect.StructField) string {
 		return name
 	}
 
+	// Fields bound from a cookie or header get a prefix so they can be
+	// described as such by describeField.
+	if name := fld.Tag.Get("cookie"); name != "" && name != "-" {
+		return cookieNamePrefix + name
+	}
+	if name := fld.Tag.Get("header"); name != "" && name != "-" {
+		return headerNamePrefix + name
+	}
+
+	// Fields bound from a form field, path variable, or query string
+	// parameter usually have a `json:"-"` tag so that they're not also read
+	// from the body. Form tags may have options after the name, like
+	// `form:"avatar,maxsize=5MB"`. Form fields are part of the body, but
+	// path variables and query parameters get a prefix so that they're left
+	// without a JSON path.
+	if name, _, _ := strings.Cut(fld.Tag.Get("form"), ","); name != "" && name != "-" {
+		return name
+	}
+	if name := fld.Tag.Get("path"); name != "" && name != "-" {
+		return pathNamePrefix + name
+	}
+	if name := fld.Tag.Get("query"); name != "" && name != "-" {
+		return queryNamePrefix + name
+	}
+
 	return fld.Name
 }
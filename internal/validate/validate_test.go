@@ -1,11 +1,14 @@
 package validate
 
 import (
+	"net/http"
 	"reflect"
 	"testing"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/require"
+
+	"github.com/riverqueue/apiframe/apierror"
 )
 
 func TestFromValidator(t *testing.T) {
@@ -114,6 +117,20 @@ func TestFromValidator(t *testing.T) {
 		require.Equal(t, "Validation on field `unsupported` failed on the `e164` tag.", PublicFacingMessage(validator, validator.Struct(testStruct)))
 	})
 
+	t.Run("HeaderAndCookie", func(t *testing.T) {
+		t.Parallel()
+
+		type boundStruct struct {
+			IdempotencyKey string `header:"Idempotency-Key" json:"-" validate:"required"`
+			Session        string `cookie:"session"         json:"-" validate:"min=10"`
+			Phone          string `header:"X-Phone"         json:"-" validate:"e164"`
+		}
+
+		require.Equal(t,
+			"Header `Idempotency-Key` is required. Cookie `session` must be at least 10 character(s) long. Validation on header `X-Phone` failed on the `e164` tag.",
+			PublicFacingMessage(validator, validator.Struct(&boundStruct{Session: "short", Phone: "abc"})))
+	})
+
 	t.Run("MultipleErrors", func(t *testing.T) {
 		t.Parallel()
 
@@ -124,16 +141,81 @@ func TestFromValidator(t *testing.T) {
 	})
 }
 
+func TestFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	validator := validator.New(validator.WithRequiredStructEnabled())
+	validator.RegisterTagNameFunc(preferPublicName)
+
+	type Item struct {
+		Name string `json:"name" validate:"required"`
+	}
+
+	type TestStruct struct {
+		IdempotencyKey string   `header:"Idempotency-Key" json:"-"     validate:"required"`
+		Items          []Item   `json:"items"             validate:"dive"`
+		Limit          int      `json:"limit"             validate:"max=100"`
+		Nested         Item     `json:"nested"`
+		Page           int      `json:"-"                 query:"page" validate:"min=1"`
+		Session        string   `cookie:"session"         json:"-"     validate:"required"`
+		Tags           []string `json:"tags"              validate:"dive,max=3"`
+	}
+
+	err := validator.Struct(&TestStruct{
+		IdempotencyKey: "",
+		Items:          []Item{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: ""}},
+		Limit:          101,
+		Nested:         Item{Name: ""},
+		Page:           0,
+		Session:        "",
+		Tags:           []string{"ok", "too long"},
+	})
+
+	require.Equal(t, []*apierror.FieldError{
+		{Field: "Idempotency-Key", Message: "Header `Idempotency-Key` is required.", Tag: "required"},
+		{Field: "name", JSONPath: "items[3].name", Message: "Field `name` is required.", Tag: "required"},
+		{Field: "limit", JSONPath: "limit", Message: "Field `limit` must be less than or equal to 100.", Param: "100", Tag: "max"},
+		{Field: "name", JSONPath: "nested.name", Message: "Field `name` is required.", Tag: "required"},
+		{Field: "page", Message: "Field `page` must be greater or equal to 1.", Param: "1", Tag: "min"},
+		{Field: "session", Message: "Cookie `session` is required.", Tag: "required"},
+		{Field: "tags[1]", JSONPath: "tags[1]", Message: "Field `tags[1]` must be at most 3 character(s) long.", Param: "3", Tag: "max"},
+	}, FieldErrors(err))
+
+	publicErr := PublicFacingError(validator, err)
+	require.Equal(t, apierror.CodeValidationFailed, publicErr.Code)
+	require.Equal(t, FieldErrors(err), publicErr.Errors)
+	require.Equal(t, PublicFacingMessage(validator, err), publicErr.Message)
+	require.Equal(t, http.StatusBadRequest, publicErr.StatusCode)
+}
+
 func TestPreferPublicNames(t *testing.T) {
 	t.Parallel()
 
 	type testStruct struct {
 		JSONNameField   string `json:"json_name"`
 		StructNameField string `apiquery:"-"`
+		QueryNameField  string `json:"-"                  query:"query_name"`
+		PathNameField   string `json:"-"                  path:"path_name"`
+		HeaderField     string `header:"X-Name"           json:"-"`
+		CookieField     string `cookie:"name"             json:"-"`
+		FormField       string `form:"form_name"          json:"-"`
+		FormFileField   string `form:"avatar,maxsize=5MB" json:"-"`
 	}
 
 	require.Equal(t, "json_name",
 		preferPublicName(reflect.TypeOf(testStruct{}).Field(0)))
 	require.Equal(t, "StructNameField",
 		preferPublicName(reflect.TypeOf(testStruct{}).Field(1)))
+	require.Equal(t, "query:query_name",
+		preferPublicName(reflect.TypeOf(testStruct{}).Field(2)))
+	require.Equal(t, "path:path_name",
+		preferPublicName(reflect.TypeOf(testStruct{}).Field(3)))
+	require.Equal(t, "header:X-Name",
+		preferPublicName(reflect.TypeOf(testStruct{}).Field(4)))
+	require.Equal(t, "cookie:name",
+		preferPublicName(reflect.TypeOf(testStruct{}).Field(5)))
+	require.Equal(t, "form_name",
+		preferPublicName(reflect.TypeOf(testStruct{}).Field(6)))
+	require.Equal(t, "avatar",
+		preferPublicName(reflect.TypeOf(testStruct{}).Field(7)))
 }